@@ -38,11 +38,19 @@ func main() {
 		repository.NewPostgresHealthRepository(pool),
 		repository.NewRedisHealthRepository(redisClient),
 	)
-	healthService := service.NewHealthService(healthRepo)
+	mailer := service.NewMailerFromEnv()
+	healthService := service.NewHealthService(healthRepo, mailer)
 	repos := repository.NewRepository(pool, redisClient)
-	services := service.NewService(repos)
+	services := service.NewService(repos, mailer)
 	handlers := handler.NewHandler(healthService, services)
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go service.RunPeriodically(jobsCtx, "account purge", time.Hour, services.Authorization.PurgeDeletedUsers)
+	go service.RunPeriodically(jobsCtx, "email outbox", 5*time.Second, services.EmailOutbox.DeliverPendingEmails)
+	go service.RunPeriodically(jobsCtx, "email outbox cleanup", time.Hour, services.EmailOutbox.PurgeSentEmails)
+	go service.RunPeriodically(jobsCtx, "invitation expiry", 10*time.Minute, services.Company.ExpireInvitations)
+
 	srv := new(sovpalo.Server)
 	go func() {
 		log.Printf("server starting on :%s", cfg.Port)
@@ -54,6 +62,7 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
+	stopJobs()
 
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer shutdownCancel()
@@ -0,0 +1,30 @@
+-- +goose Up
+BEGIN;
+
+ALTER TABLE user_sessions
+    ADD COLUMN IF NOT EXISTS family_id VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS rotated_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMPTZ;
+
+UPDATE user_sessions SET family_id = id::text WHERE family_id IS NULL;
+
+ALTER TABLE user_sessions
+    ALTER COLUMN family_id SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_refresh_token_hash ON user_sessions(refresh_token_hash);
+CREATE INDEX IF NOT EXISTS idx_sessions_family ON user_sessions(family_id);
+
+COMMIT;
+
+-- +goose Down
+BEGIN;
+
+DROP INDEX IF EXISTS idx_sessions_family;
+DROP INDEX IF EXISTS idx_sessions_refresh_token_hash;
+
+ALTER TABLE user_sessions
+    DROP COLUMN IF EXISTS revoked_at,
+    DROP COLUMN IF EXISTS rotated_at,
+    DROP COLUMN IF EXISTS family_id;
+
+COMMIT;
@@ -0,0 +1,29 @@
+-- +goose Up
+BEGIN;
+
+-- accounts created through Telegram sign-in have no email until one is added
+ALTER TABLE users
+    ALTER COLUMN email DROP NOT NULL;
+
+COMMIT;
+
+-- +goose Down
+BEGIN;
+
+-- Accounts without an email are real users; refuse to roll back rather than
+-- delete them. Add an email to those accounts (or remove them by hand) first.
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM users WHERE email IS NULL) THEN
+        RAISE EXCEPTION 'cannot make users.email NOT NULL: % accounts have no email',
+            (SELECT COUNT(*) FROM users WHERE email IS NULL);
+    END IF;
+END;
+$$;
+-- +goose StatementEnd
+
+ALTER TABLE users
+    ALTER COLUMN email SET NOT NULL;
+
+COMMIT;
@@ -0,0 +1,17 @@
+-- +goose Up
+BEGIN;
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS first_name VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS second_name VARCHAR(100);
+
+COMMIT;
+
+-- +goose Down
+BEGIN;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS second_name,
+    DROP COLUMN IF EXISTS first_name;
+
+COMMIT;
@@ -0,0 +1,16 @@
+-- +goose Up
+BEGIN;
+
+-- access tokens issued before this moment are rejected (set on password change)
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS tokens_valid_after TIMESTAMPTZ;
+
+COMMIT;
+
+-- +goose Down
+BEGIN;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS tokens_valid_after;
+
+COMMIT;
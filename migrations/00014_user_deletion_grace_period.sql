@@ -0,0 +1,30 @@
+-- +goose Up
+BEGIN;
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS deletion_requested_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_users_deletion_requested_at
+    ON users(deletion_requested_at)
+    WHERE deletion_requested_at IS NOT NULL;
+
+-- company_id is NOT NULL since 00007, so SET NULL made companies with events impossible to delete
+ALTER TABLE events
+    DROP CONSTRAINT IF EXISTS events_company_id_fkey,
+    ADD CONSTRAINT events_company_id_fkey FOREIGN KEY (company_id) REFERENCES companies(id) ON DELETE CASCADE;
+
+COMMIT;
+
+-- +goose Down
+BEGIN;
+
+ALTER TABLE events
+    DROP CONSTRAINT IF EXISTS events_company_id_fkey,
+    ADD CONSTRAINT events_company_id_fkey FOREIGN KEY (company_id) REFERENCES companies(id) ON DELETE SET NULL;
+
+DROP INDEX IF EXISTS idx_users_deletion_requested_at;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS deletion_requested_at;
+
+COMMIT;
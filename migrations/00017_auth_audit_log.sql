@@ -0,0 +1,38 @@
+-- +goose Up
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS auth_audit_log (
+    id SERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    outcome VARCHAR(30) NOT NULL,
+    ip_address INET,
+    user_agent TEXT,
+    details TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_audit_log_user ON auth_audit_log(user_id, id DESC);
+
+-- Entries are never edited; they only go away together with the user.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION auth_audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'auth_audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER auth_audit_log_no_update
+    BEFORE UPDATE ON auth_audit_log
+    FOR EACH ROW EXECUTE FUNCTION auth_audit_log_append_only();
+
+COMMIT;
+
+-- +goose Down
+BEGIN;
+
+DROP TABLE IF EXISTS auth_audit_log;
+DROP FUNCTION IF EXISTS auth_audit_log_append_only();
+
+COMMIT;
@@ -0,0 +1,21 @@
+-- +goose Up
+BEGIN;
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS locale VARCHAR(10) NOT NULL DEFAULT 'ru';
+
+ALTER TABLE email_outbox
+    ADD COLUMN IF NOT EXISTS html_body TEXT;
+
+COMMIT;
+
+-- +goose Down
+BEGIN;
+
+ALTER TABLE email_outbox
+    DROP COLUMN IF EXISTS html_body;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS locale;
+
+COMMIT;
@@ -0,0 +1,24 @@
+-- +goose Up
+BEGIN;
+
+-- Until now the role was informational; the creator is the owner.
+UPDATE company_members cm
+SET role = CASE WHEN c.created_by = cm.user_id THEN 'owner' ELSE 'member' END
+FROM companies c
+WHERE c.id = cm.company_id;
+
+ALTER TABLE company_members
+    ADD CONSTRAINT company_members_role_check CHECK (role IN ('owner', 'admin', 'member'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_company_members_single_owner ON company_members(company_id) WHERE role = 'owner';
+
+COMMIT;
+
+-- +goose Down
+BEGIN;
+
+DROP INDEX IF EXISTS idx_company_members_single_owner;
+UPDATE company_members SET role = 'member' WHERE role = 'admin';
+ALTER TABLE company_members DROP CONSTRAINT IF EXISTS company_members_role_check;
+
+COMMIT;
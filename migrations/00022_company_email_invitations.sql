@@ -0,0 +1,26 @@
+-- +goose Up
+BEGIN;
+
+-- Invitations of people who have no account yet. They become regular
+-- company_invitations rows when the email is registered.
+CREATE TABLE IF NOT EXISTS company_email_invitations (
+    id SERIAL PRIMARY KEY,
+    company_id BIGINT NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    invited_by BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    last_sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_company_email_invitations_company_email ON company_email_invitations(company_id, lower(email));
+CREATE INDEX IF NOT EXISTS idx_company_email_invitations_email ON company_email_invitations(lower(email));
+
+COMMIT;
+
+-- +goose Down
+BEGIN;
+
+DROP TABLE IF EXISTS company_email_invitations;
+
+COMMIT;
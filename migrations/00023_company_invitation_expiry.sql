@@ -0,0 +1,29 @@
+-- +goose Up
+BEGIN;
+
+ALTER TABLE company_invitations ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+-- Invitations sent before expiry existed get the default lifetime.
+UPDATE company_invitations
+SET expires_at = created_at + INTERVAL '7 days'
+WHERE status = 'pending';
+
+ALTER TABLE company_invitations DROP CONSTRAINT IF EXISTS company_invitations_status_check;
+ALTER TABLE company_invitations
+    ADD CONSTRAINT company_invitations_status_check CHECK (status IN ('pending', 'accepted', 'declined', 'expired'));
+
+CREATE INDEX IF NOT EXISTS idx_company_invitations_pending_expiry ON company_invitations(expires_at) WHERE status = 'pending';
+
+COMMIT;
+
+-- +goose Down
+BEGIN;
+
+DROP INDEX IF EXISTS idx_company_invitations_pending_expiry;
+UPDATE company_invitations SET status = 'declined' WHERE status = 'expired';
+ALTER TABLE company_invitations DROP CONSTRAINT IF EXISTS company_invitations_status_check;
+ALTER TABLE company_invitations
+    ADD CONSTRAINT company_invitations_status_check CHECK (status IN ('pending', 'accepted', 'declined'));
+ALTER TABLE company_invitations DROP COLUMN IF EXISTS expires_at;
+
+COMMIT;
@@ -0,0 +1,37 @@
+-- +goose Up
+BEGIN;
+
+-- The owner is now stored separately; created_by only records who created
+-- the company and is kept when ownership changes hands.
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS owner_id BIGINT REFERENCES users(id);
+
+UPDATE companies c
+SET owner_id = COALESCE(
+    (SELECT cm.user_id FROM company_members cm WHERE cm.company_id = c.id AND cm.role = 'owner'),
+    c.created_by
+);
+
+ALTER TABLE companies ALTER COLUMN owner_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_companies_owner ON companies(owner_id);
+
+-- The creator may delete their account while the company lives on.
+ALTER TABLE companies ALTER COLUMN created_by DROP NOT NULL;
+ALTER TABLE companies DROP CONSTRAINT IF EXISTS companies_created_by_fkey;
+ALTER TABLE companies
+    ADD CONSTRAINT companies_created_by_fkey FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL;
+
+COMMIT;
+
+-- +goose Down
+BEGIN;
+
+UPDATE companies SET created_by = owner_id;
+ALTER TABLE companies DROP CONSTRAINT IF EXISTS companies_created_by_fkey;
+ALTER TABLE companies
+    ADD CONSTRAINT companies_created_by_fkey FOREIGN KEY (created_by) REFERENCES users(id);
+ALTER TABLE companies ALTER COLUMN created_by SET NOT NULL;
+
+DROP INDEX IF EXISTS idx_companies_owner;
+ALTER TABLE companies DROP COLUMN IF EXISTS owner_id;
+
+COMMIT;
@@ -0,0 +1,36 @@
+-- +goose Up
+BEGIN;
+
+-- Listed companies appear in discovery and accept join requests; private
+-- ones are invite-only.
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS visibility VARCHAR(20) NOT NULL DEFAULT 'private';
+ALTER TABLE companies
+    ADD CONSTRAINT companies_visibility_check CHECK (visibility IN ('private', 'listed'));
+CREATE INDEX IF NOT EXISTS idx_companies_listed ON companies(id) WHERE visibility = 'listed';
+
+CREATE TABLE IF NOT EXISTS company_join_requests (
+    id SERIAL PRIMARY KEY,
+    company_id BIGINT NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    message TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    responded_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    responded_at TIMESTAMPTZ,
+    UNIQUE(company_id, user_id),
+    CONSTRAINT company_join_requests_status_check CHECK (status IN ('pending', 'approved', 'rejected'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_company_join_requests_pending ON company_join_requests(company_id, created_at) WHERE status = 'pending';
+
+COMMIT;
+
+-- +goose Down
+BEGIN;
+
+DROP TABLE IF EXISTS company_join_requests;
+DROP INDEX IF EXISTS idx_companies_listed;
+ALTER TABLE companies DROP CONSTRAINT IF EXISTS companies_visibility_check;
+ALTER TABLE companies DROP COLUMN IF EXISTS visibility;
+
+COMMIT;
@@ -0,0 +1,32 @@
+-- +goose Up
+BEGIN;
+
+-- Companies without a row use the defaults below, which match
+-- model.DefaultCompanySettings.
+CREATE TABLE IF NOT EXISTS company_settings (
+    company_id BIGINT PRIMARY KEY REFERENCES companies(id) ON DELETE CASCADE,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    default_event_duration_minutes INT NOT NULL DEFAULT 120,
+    event_creation VARCHAR(20) NOT NULL DEFAULT 'everyone',
+    idea_creation VARCHAR(20) NOT NULL DEFAULT 'everyone',
+    members_can_invite BOOLEAN NOT NULL DEFAULT TRUE,
+    default_rsvp_deadline_minutes INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    CONSTRAINT company_settings_event_creation_check CHECK (event_creation IN ('everyone', 'admins')),
+    CONSTRAINT company_settings_idea_creation_check CHECK (idea_creation IN ('everyone', 'admins')),
+    CONSTRAINT company_settings_event_duration_check CHECK (default_event_duration_minutes > 0),
+    CONSTRAINT company_settings_rsvp_deadline_check CHECK (default_rsvp_deadline_minutes >= 0)
+);
+
+-- Attendance can no longer be changed after the deadline.
+ALTER TABLE events ADD COLUMN IF NOT EXISTS rsvp_deadline TIMESTAMPTZ;
+
+COMMIT;
+
+-- +goose Down
+BEGIN;
+
+ALTER TABLE events DROP COLUMN IF EXISTS rsvp_deadline;
+DROP TABLE IF EXISTS company_settings;
+
+COMMIT;
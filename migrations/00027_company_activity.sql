@@ -0,0 +1,28 @@
+-- +goose Up
+BEGIN;
+
+-- What happened in a company, written by the services after each change.
+-- subject_id points to the event, idea or user the entry is about; details
+-- keeps what is worth showing even after the subject is gone (a title, an
+-- RSVP status).
+CREATE TABLE IF NOT EXISTS activity (
+    id BIGSERIAL PRIMARY KEY,
+    company_id BIGINT NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    actor_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    type VARCHAR(50) NOT NULL,
+    subject_id BIGINT,
+    details TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_activity_company ON activity(company_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_activity_company_type ON activity(company_id, type, id DESC);
+
+COMMIT;
+
+-- +goose Down
+BEGIN;
+
+DROP TABLE IF EXISTS activity;
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- +goose Up
+BEGIN;
+
+-- delivered and dead emails no longer keep their bodies, which contain codes
+UPDATE email_outbox
+SET body = '', html_body = NULL
+WHERE status IN ('sent', 'dead');
+
+COMMIT;
+
+-- +goose Down
+-- the removed bodies cannot be restored
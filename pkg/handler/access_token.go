@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) createAccessToken(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var input model.PersonalAccessTokenCreateInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, bindingErrorMessage(err))
+		return
+	}
+
+	token, err := h.services.AccessToken.CreateAccessToken(int64(userID), input)
+	if err != nil {
+		mapRegistrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, token)
+}
+
+func (h *Handler) listAccessTokens(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	tokens, err := h.services.AccessToken.ListAccessTokens(int64(userID))
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if tokens == nil {
+		tokens = []model.PersonalAccessToken{}
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *Handler) revokeAccessToken(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	tokenID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid token id")
+		return
+	}
+
+	if err := h.services.AccessToken.RevokeAccessToken(int64(userID), tokenID); err != nil {
+		mapRegistrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{Status: "ok"})
+}
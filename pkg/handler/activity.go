@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/Sovpalo/sovpalo-backend/pkg/service"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) listCompanyActivity(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	companyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid company id")
+		return
+	}
+
+	var beforeID int64
+	if value := c.Query("before"); value != "" {
+		beforeID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || beforeID <= 0 {
+			newErrorResponse(c, http.StatusBadRequest, "invalid before parameter")
+			return
+		}
+	}
+
+	var limit int
+	if value := c.Query("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			newErrorResponse(c, http.StatusBadRequest, "invalid limit parameter")
+			return
+		}
+	}
+
+	// type can be repeated or hold a comma-separated list
+	var types []string
+	for _, value := range c.QueryArray("type") {
+		for _, activityType := range strings.Split(value, ",") {
+			if activityType = strings.TrimSpace(activityType); activityType != "" {
+				types = append(types, activityType)
+			}
+		}
+	}
+
+	entries, err := h.services.Activity.ListCompanyActivity(companyID, int64(userID), beforeID, types, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotCompanyMember):
+			newErrorResponse(c, http.StatusNotFound, err.Error())
+		case errors.Is(err, service.ErrInvalidActivityFilter):
+			newErrorResponse(c, http.StatusBadRequest, err.Error())
+		default:
+			newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	if entries == nil {
+		entries = []model.CompanyActivityView{}
+	}
+	c.JSON(http.StatusOK, entries)
+}
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
 	"github.com/Sovpalo/sovpalo-backend/pkg/service"
 	"github.com/gin-gonic/gin"
 )
@@ -12,6 +14,8 @@ import (
 const (
 	authorizationHeader = "Authorization"
 	userCtx             = "user_id"
+	scopesCtx           = "token_scopes"
+	authTimeCtx         = "auth_time"
 )
 
 func (h *Handler) userIdentity(c *gin.Context) {
@@ -27,13 +31,61 @@ func (h *Handler) userIdentity(c *gin.Context) {
 		newErrorResponse(c, http.StatusUnauthorized, "Invalid authorization header")
 		return
 	}
-	userId, err := h.services.Authorization.ParseToken(headerParts[1])
+
+	if service.IsPersonalAccessToken(headerParts[1]) {
+		identity, err := h.services.AccessToken.AuthenticateAccessToken(headerParts[1])
+		if err != nil {
+			newErrorResponse(c, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		c.Set(userCtx, int(identity.UserID))
+		c.Set(scopesCtx, identity.Scopes)
+		c.Next()
+		return
+	}
+
+	identity, err := h.services.Authorization.ParseToken(headerParts[1])
 	if err != nil {
 		newErrorResponse(c, http.StatusUnauthorized, err.Error())
 		return
 	}
 
-	c.Set(userCtx, userId)
+	c.Set(userCtx, identity.UserID)
+	if !identity.AuthTime.IsZero() {
+		c.Set(authTimeCtx, identity.AuthTime)
+	}
+	c.Next()
+}
+
+// requireScope checks that a personal access token has the read or write
+// scope of resource, depending on the request method. Requests signed in with
+// a JWT have full access.
+func (h *Handler) requireScope(resource string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scopes, ok := c.Get(scopesCtx)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		required := service.RequiredScope(resource, c.Request.Method)
+		granted, _ := scopes.([]string)
+		if !service.HasScope(granted, required) {
+			newErrorResponse(c, http.StatusForbidden, "token is missing scope "+required)
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireSession rejects personal access tokens on account management
+// endpoints: those need a JWT from a real sign-in.
+func (h *Handler) requireSession(c *gin.Context) {
+	if _, ok := c.Get(scopesCtx); ok {
+		newErrorResponse(c, http.StatusForbidden, "personal access tokens cannot be used here")
+		return
+	}
 	c.Next()
 }
 
@@ -50,12 +102,33 @@ func getUserId(c *gin.Context) (int, error) {
 	return idInt, nil
 }
 
+func sessionMeta(c *gin.Context) model.SessionMeta {
+	authTime, _ := c.Get(authTimeCtx)
+	signedInAt, _ := authTime.(time.Time)
+	return model.SessionMeta{
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+		Locale:    service.PreferredLocale(c.GetHeader("Accept-Language")),
+		AuthTime:  signedInAt,
+	}
+}
+
 func mapRegistrationError(c *gin.Context, err error) {
 	switch {
-	case errors.Is(err, service.ErrInvalidPassword):
+	case errors.Is(err, service.ErrInvalidPassword), errors.Is(err, service.ErrInvalidProfile), errors.Is(err, service.ErrNoProfileChanges):
 		newErrorResponse(c, http.StatusBadRequest, err.Error())
 	case errors.Is(err, service.ErrInvalidCredentials):
 		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, service.ErrIncorrectCurrentPassword):
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrRecentSignInRequired):
+		newErrorResponse(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrInvalidTwoFactorCode), errors.Is(err, service.ErrTwoFactorChallengeNotFound):
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, service.ErrTwoFactorAlreadyEnabled):
+		newErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrTwoFactorNotEnabled), errors.Is(err, service.ErrTwoFactorEnrollmentMissing):
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
 	case errors.Is(err, service.ErrUserAlreadyExists), errors.Is(err, service.ErrUsernameAlreadyExists):
 		newErrorResponse(c, http.StatusBadRequest, err.Error())
 	case errors.Is(err, service.ErrUserNotFound):
@@ -64,6 +137,30 @@ func mapRegistrationError(c *gin.Context, err error) {
 		newErrorResponse(c, http.StatusBadRequest, err.Error())
 	case errors.Is(err, service.ErrIncorrectVerificationCode):
 		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, service.ErrTooManyAttempts), errors.Is(err, service.ErrResendTooSoon):
+		newErrorResponse(c, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, service.ErrInvalidRefreshToken), errors.Is(err, service.ErrRefreshTokenReused):
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, service.ErrSessionNotFound):
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrInvalidTelegramAuth), errors.Is(err, service.ErrTelegramAuthExpired):
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, service.ErrTelegramAlreadyLinked):
+		newErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrTelegramNotLinked), errors.Is(err, service.ErrLastSignInMethod):
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrInvalidAccessTokenParams):
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrAccessTokenNotFound):
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrOIDCProviderNotFound):
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrInvalidOIDCState):
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrOIDCSignInFailed):
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, service.ErrOIDCProviderUnavailable):
+		newErrorResponse(c, http.StatusBadGateway, err.Error())
 	default:
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 	}
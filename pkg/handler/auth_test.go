@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/Sovpalo/sovpalo-backend/pkg/repository"
+	"github.com/Sovpalo/sovpalo-backend/pkg/service"
+	"github.com/gin-gonic/gin"
+)
+
+type accessTokenRepoStub struct {
+	repository.AccessToken
+	scopes []string
+}
+
+func (s *accessTokenRepoStub) UseAccessToken(tokenHash string) (model.AccessTokenIdentity, error) {
+	return model.AccessTokenIdentity{UserID: 1, Scopes: s.scopes}, nil
+}
+
+func accessTokenRouter(scopes []string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(nil, &service.Service{
+		AccessToken: service.NewAccessTokenService(&accessTokenRepoStub{scopes: scopes}),
+	})
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router := gin.New()
+	router.GET("/events", h.userIdentity, h.requireScope("events"), ok)
+	router.GET("/me/sessions", h.userIdentity, h.requireSession, ok)
+	return router
+}
+
+func serveWithAccessToken(router *gin.Engine, path string) int {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set(authorizationHeader, "Bearer spat_test")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestRequireScopeRejectsAccessTokenWithoutScope(t *testing.T) {
+	if code := serveWithAccessToken(accessTokenRouter([]string{"ideas:write"}), "/events"); code != http.StatusForbidden {
+		t.Fatalf("expected 403 without events scope, got %d", code)
+	}
+	if code := serveWithAccessToken(accessTokenRouter([]string{"events:read"}), "/events"); code != http.StatusOK {
+		t.Fatalf("expected 200 with events:read, got %d", code)
+	}
+}
+
+func TestRequireSessionRejectsAccessTokens(t *testing.T) {
+	allScopes := []string{"profile:write", "companies:write", "events:write", "ideas:write", "availability:write"}
+	if code := serveWithAccessToken(accessTokenRouter(allScopes), "/me/sessions"); code != http.StatusForbidden {
+		t.Fatalf("expected 403 for an access token on an account endpoint, got %d", code)
+	}
+}
+
+func TestRequireInternalToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	cases := []struct {
+		configured string
+		header     string
+		want       int
+	}{
+		{"", "Bearer anything", http.StatusNotFound},
+		{"secret", "", http.StatusUnauthorized},
+		{"secret", "Bearer wrong", http.StatusUnauthorized},
+		{"secret", "Bearer secret", http.StatusOK},
+	}
+	for _, tc := range cases {
+		router := gin.New()
+		router.GET("/internal/email-outbox", requireInternalToken(tc.configured), ok)
+
+		req := httptest.NewRequest(http.MethodGet, "/internal/email-outbox", nil)
+		if tc.header != "" {
+			req.Header.Set(authorizationHeader, tc.header)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("token %q, header %q: expected %d, got %d", tc.configured, tc.header, tc.want, rec.Code)
+		}
+	}
+}
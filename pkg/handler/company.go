@@ -99,7 +99,7 @@ func (h *Handler) updateCompany(c *gin.Context) {
 		case errors.Is(err, service.ErrAvatarTooLarge), errors.Is(err, service.ErrAvatarInvalidType):
 			newErrorResponse(c, http.StatusBadRequest, err.Error())
 		default:
-			newErrorResponse(c, http.StatusBadRequest, err.Error())
+			newErrorResponse(c, companyErrorStatus(err, http.StatusBadRequest), err.Error())
 		}
 		return
 	}
@@ -133,6 +133,10 @@ func parseCompanyUpdateInput(c *gin.Context) (model.CompanyUpdateInput, string,
 		value := c.PostForm("avatar_url")
 		input.AvatarURL = &value
 	}
+	if _, ok := c.Request.MultipartForm.Value["visibility"]; ok {
+		value := c.PostForm("visibility")
+		input.Visibility = &value
+	}
 
 	fileName, fileData, err := readMultipartImage(c, "avatar")
 	if err != nil {
@@ -156,7 +160,7 @@ func (h *Handler) deleteCompany(c *gin.Context) {
 	}
 
 	if err := h.services.Company.DeleteCompany(companyID, int64(userID)); err != nil {
-		newErrorResponse(c, http.StatusNotFound, err.Error())
+		newErrorResponse(c, companyErrorStatus(err, http.StatusNotFound), err.Error())
 		return
 	}
 
@@ -194,6 +198,37 @@ func (h *Handler) leaveCompany(c *gin.Context) {
 	c.JSON(http.StatusOK, statusResponse{Status: "ok"})
 }
 
+func (h *Handler) transferCompanyOwnership(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	companyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid company id")
+		return
+	}
+
+	var input model.CompanyOwnershipTransferInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, bindingErrorMessage(err))
+		return
+	}
+
+	if err := h.services.Company.TransferOwnership(companyID, int64(userID), input.NewOwnerID); err != nil {
+		if errors.Is(err, service.ErrNotCompanyMember) {
+			newErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		newErrorResponse(c, companyErrorStatus(err, http.StatusBadRequest), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{Status: "ok"})
+}
+
 func (h *Handler) inviteToCompany(c *gin.Context) {
 	userID, err := getUserId(c)
 	if err != nil {
@@ -217,7 +252,11 @@ func (h *Handler) inviteToCompany(c *gin.Context) {
 
 	invite, err := h.services.Company.InviteUser(companyID, int64(userID), input.Username)
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		if errors.Is(err, service.ErrInvitationDeclinedRecently) {
+			newErrorResponse(c, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		newErrorResponse(c, companyErrorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
@@ -240,6 +279,63 @@ func (h *Handler) listInvitations(c *gin.Context) {
 	c.JSON(http.StatusOK, invites)
 }
 
+func (h *Handler) listSentInvitations(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	companyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid company id")
+		return
+	}
+
+	invites, err := h.services.Company.ListSentInvitations(companyID, int64(userID))
+	if err != nil {
+		if errors.Is(err, service.ErrNotCompanyMember) {
+			newErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		newErrorResponse(c, companyErrorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, invites)
+}
+
+func (h *Handler) revokeInvitation(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	companyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid company id")
+		return
+	}
+
+	inviteID, err := strconv.ParseInt(c.Param("invite_id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid invitation id")
+		return
+	}
+
+	if err := h.services.Company.RevokeInvitation(companyID, int64(userID), inviteID); err != nil {
+		if errors.Is(err, service.ErrInvitationNotFound) || errors.Is(err, service.ErrNotCompanyMember) {
+			newErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+		newErrorResponse(c, companyErrorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{Status: "ok"})
+}
+
 func (h *Handler) acceptInvitation(c *gin.Context) {
 	userID, err := getUserId(c)
 	if err != nil {
@@ -254,6 +350,10 @@ func (h *Handler) acceptInvitation(c *gin.Context) {
 	}
 
 	if err := h.services.Company.AcceptInvitation(inviteID, int64(userID)); err != nil {
+		if errors.Is(err, service.ErrInvitationExpired) {
+			newErrorResponse(c, http.StatusGone, err.Error())
+			return
+		}
 		newErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
@@ -327,9 +427,51 @@ func (h *Handler) removeCompanyMember(c *gin.Context) {
 	}
 
 	if err := h.services.Company.RemoveCompanyMember(companyID, int64(userID), memberUserID); err != nil {
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		newErrorResponse(c, companyErrorStatus(err, http.StatusBadRequest), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{Status: "ok"})
+}
+
+func (h *Handler) updateCompanyMemberRole(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	companyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid company id")
+		return
+	}
+
+	memberUserID, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	var input model.CompanyMemberRoleInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, bindingErrorMessage(err))
+		return
+	}
+
+	if err := h.services.Company.UpdateCompanyMemberRole(companyID, int64(userID), memberUserID, input.Role); err != nil {
+		newErrorResponse(c, companyErrorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, statusResponse{Status: "ok"})
 }
+
+// companyErrorStatus reports a missing company permission as 403 and any
+// other error with the given status.
+func companyErrorStatus(err error, status int) int {
+	if errors.Is(err, service.ErrCompanyPermissionDenied) {
+		return http.StatusForbidden
+	}
+	return status
+}
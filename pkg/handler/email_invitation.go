@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/Sovpalo/sovpalo-backend/pkg/service"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) inviteByEmail(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	companyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid company id")
+		return
+	}
+
+	var input model.CompanyEmailInvitationInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, bindingErrorMessage(err))
+		return
+	}
+
+	invitation, err := h.services.Company.InviteByEmail(companyID, int64(userID), input.Email)
+	if err != nil {
+		mapEmailInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+func (h *Handler) resendEmailInvitation(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	companyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid company id")
+		return
+	}
+
+	inviteID, err := strconv.ParseInt(c.Param("invite_id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid email invitation id")
+		return
+	}
+
+	invitation, err := h.services.Company.ResendEmailInvitation(companyID, int64(userID), inviteID)
+	if err != nil {
+		mapEmailInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invitation)
+}
+
+func mapEmailInvitationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrEmailInvitationNotFound), errors.Is(err, service.ErrNotCompanyMember):
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrEmailInvitationSentRecently):
+		newErrorResponse(c, http.StatusTooManyRequests, err.Error())
+	default:
+		newErrorResponse(c, companyErrorStatus(err, http.StatusInternalServerError), err.Error())
+	}
+}
@@ -36,7 +36,7 @@ func (h *Handler) createEvent(c *gin.Context) {
 
 	eventID, err := h.services.Event.CreateEvent(int64(userID), createInput, photoFileName, photoFileData)
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		newErrorResponse(c, companyErrorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
@@ -183,7 +183,7 @@ func (h *Handler) updateEvent(c *gin.Context) {
 			newErrorResponse(c, http.StatusBadRequest, err.Error())
 			return
 		}
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		newErrorResponse(c, companyErrorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
@@ -306,7 +306,7 @@ func (h *Handler) deleteEvent(c *gin.Context) {
 	}
 
 	if err := h.services.Event.DeleteEvent(eventID, int64(userID)); err != nil {
-		newErrorResponse(c, http.StatusNotFound, err.Error())
+		newErrorResponse(c, companyErrorStatus(err, http.StatusNotFound), err.Error())
 		return
 	}
 
@@ -365,7 +365,7 @@ func (h *Handler) createCompanyEvent(c *gin.Context) {
 
 	eventID, err := h.services.Event.CreateEvent(int64(userID), createInput, photoFileName, photoFileData)
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		newErrorResponse(c, companyErrorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
@@ -450,7 +450,7 @@ func (h *Handler) updateCompanyEvent(c *gin.Context) {
 			newErrorResponse(c, http.StatusBadRequest, err.Error())
 			return
 		}
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		newErrorResponse(c, companyErrorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
@@ -487,7 +487,7 @@ func (h *Handler) deleteCompanyEvent(c *gin.Context) {
 	}
 
 	if err := h.services.Event.DeleteEvent(eventID, int64(userID)); err != nil {
-		newErrorResponse(c, http.StatusNotFound, err.Error())
+		newErrorResponse(c, companyErrorStatus(err, http.StatusNotFound), err.Error())
 		return
 	}
 
@@ -522,6 +522,10 @@ func (h *Handler) setCompanyEventAttendance(c *gin.Context) {
 	}
 
 	if err := h.services.Event.SetCompanyEventAttendance(companyID, eventID, int64(userID), input.Status); err != nil {
+		if errors.Is(err, service.ErrRSVPDeadlinePassed) {
+			newErrorResponse(c, http.StatusConflict, err.Error())
+			return
+		}
 		newErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
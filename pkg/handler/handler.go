@@ -4,9 +4,11 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/Sovpalo/sovpalo-backend/pkg/service"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 type Handler struct {
@@ -24,38 +26,106 @@ func NewHandler(health service.HealthService, services *service.Service) *Handle
 func (h *Handler) InitRoutes() *gin.Engine {
 	router := gin.New()
 	router.Use(gin.Recovery())
+	// X-Forwarded-For is honoured only from TRUSTED_PROXIES; otherwise any
+	// client could pick the IP that rate limits and cooldowns are keyed by.
+	if err := router.SetTrustedProxies(trustedProxiesFromEnv()); err != nil {
+		logrus.Errorf("invalid TRUSTED_PROXIES, trusting no proxies: %s", err.Error())
+		_ = router.SetTrustedProxies(nil)
+	}
 	router.Static("/uploads", avatarUploadsRootDir())
 
+	signInLimit := h.rateLimit(service.RateLimitPolicySignIn, clientIPSubject)
+	emailLimit := h.rateLimit(service.RateLimitPolicyEmail, clientIPSubject)
+	authLimit := h.rateLimit(service.RateLimitPolicyAuth, clientIPSubject)
+	verifyLimit := h.rateLimit(service.RateLimitPolicyVerify, clientIPSubject)
+	writeLimit := h.rateLimitWrites(service.RateLimitPolicyWrite, userSubject)
+	profileScope := h.requireScope("profile")
+
 	// проверка статуса сервиса, возвращает статус и ошибку, если сервис не работает
 	router.GET("/health", h.healthHandler)
 	router.GET("/health/smtp", h.smtpHealthHandler)
+	internal := router.Group("/internal", requireInternalToken(internalAPIToken()))
+	{
+		// метрики очереди писем: ожидающие, повторные и недоставленные письма, счётчики воркера
+		internal.GET("/email-outbox", h.emailOutboxHandler)
+	}
+	// публичные ключи для проверки access-токенов другими сервисами
+	router.GET("/.well-known/jwks.json", h.jwks)
 	auth := router.Group("/auth")
 	{
 		// старт регистрации и отправка кода на email
-		auth.POST("/sign-up", h.signUp)
+		auth.POST("/sign-up", emailLimit, h.signUp)
 		// подтверждение email-кода и завершение регистрации
-		auth.POST("/sign-up/verify", h.verifySignUp)
+		auth.POST("/sign-up/verify", verifyLimit, h.verifySignUp)
 		// повторная отправка кода подтверждения
-		auth.POST("/sign-up/resend", h.resendSignUpCode)
+		auth.POST("/sign-up/resend", emailLimit, h.resendSignUpCode)
 		// старт входа: проверка пароля и отправка кода на email
-		auth.POST("/sign-in", h.signIn)
+		auth.POST("/sign-in", signInLimit, h.signIn)
+		// вход без пароля: отправка одноразового кода на email
+		auth.POST("/sign-in/code", emailLimit, h.startCodeSignIn)
+		// вход без пароля: подтверждение кода из письма
+		auth.POST("/sign-in/code/verify", verifyLimit, h.verifyCodeSignIn)
+		// вход без пароля: повторная отправка кода
+		auth.POST("/sign-in/code/resend", emailLimit, h.resendCodeSignIn)
+		// второй шаг входа для пользователей с 2FA: обмен challenge_token и TOTP/резервного кода на токены
+		auth.POST("/sign-in/2fa", signInLimit, h.verifyTwoFactorSignIn)
 		// запуск восстановления пароля
-		auth.POST("/password/forgot", h.forgotPassword)
+		auth.POST("/password/forgot", emailLimit, h.forgotPassword)
 		// подтверждение кода и установка нового пароля
-		auth.POST("/password/verify", h.verifyForgotPassword)
+		auth.POST("/password/verify", verifyLimit, h.verifyForgotPassword)
 		// повторная отправка кода для восстановления пароля
-		auth.POST("/password/resend", h.resendForgotPasswordCode)
+		auth.POST("/password/resend", emailLimit, h.resendForgotPasswordCode)
+		// вход через Telegram Login Widget, создаёт пользователя при первом входе
+		auth.POST("/telegram", signInLimit, h.signInWithTelegram)
+		// вход через OpenID Connect провайдера из OIDC_PROVIDERS: редирект на страницу провайдера (authorization code + PKCE)
+		auth.GET("/oidc/:provider/start", signInLimit, h.startOIDCSignIn)
+		// возврат от OIDC провайдера: проверка ID-токена, привязка или создание пользователя, выдача токенов
+		auth.GET("/oidc/:provider/callback", authLimit, h.oidcCallback)
+		// обмен refresh-токена на новую пару токенов
+		auth.POST("/refresh", authLimit, h.refreshToken)
+		// завершение сессии по refresh-токену
+		auth.POST("/logout", authLimit, h.logout)
 		// информация о текущем пользователе
-		auth.GET("/me", h.userIdentity, h.getCurrentUser)
+		auth.GET("/me", h.userIdentity, profileScope, h.getCurrentUser)
+		// изменение username и имени текущего пользователя; новый email применяется после подтверждения кода
+		auth.PATCH("/me", h.userIdentity, profileScope, writeLimit, h.updateCurrentUser)
+		// подтверждение кода, отправленного на новый email
+		auth.POST("/me/email/verify", h.userIdentity, h.requireSession, verifyLimit, h.verifyCurrentUserEmail)
+		// смена пароля текущего пользователя; завершает все остальные сессии и возвращает новую пару токенов
+		auth.POST("/me/password", h.userIdentity, h.requireSession, signInLimit, h.changeCurrentUserPassword)
+		// начало подключения TOTP: возвращает секрет и otpauth URI (требует пароль, у аккаунтов без пароля — недавний вход)
+		auth.POST("/me/2fa/totp", h.userIdentity, h.requireSession, signInLimit, h.startCurrentUserTOTP)
+		// подтверждение TOTP кодом из приложения, возвращает резервные коды
+		auth.POST("/me/2fa/totp/confirm", h.userIdentity, h.requireSession, authLimit, h.confirmCurrentUserTOTP)
+		// отключение TOTP (требует пароль или недавний вход и код)
+		auth.DELETE("/me/2fa/totp", h.userIdentity, h.requireSession, signInLimit, h.disableCurrentUserTOTP)
 		// загрузка аватара текущего пользователя
-		auth.POST("/me/avatar", h.userIdentity, h.uploadCurrentUserAvatar)
+		auth.POST("/me/avatar", h.userIdentity, profileScope, writeLimit, h.uploadCurrentUserAvatar)
 		// удаление аватара текущего пользователя
-		auth.DELETE("/me/avatar", h.userIdentity, h.deleteCurrentUserAvatar)
-		// удаление текущего пользователя
-		auth.DELETE("/me", h.userIdentity, h.deleteCurrentUser)
+		auth.DELETE("/me/avatar", h.userIdentity, profileScope, writeLimit, h.deleteCurrentUserAvatar)
+		// удаление текущего пользователя: аккаунт деактивируется и удаляется после льготного периода, вход отменяет удаление
+		auth.DELETE("/me", h.userIdentity, h.requireSession, writeLimit, h.deleteCurrentUser)
+		// выгрузка всех данных текущего пользователя ZIP-архивом
+		auth.GET("/me/export", h.userIdentity, h.requireSession, authLimit, h.exportCurrentUser)
+		// привязка Telegram-аккаунта к текущему пользователю
+		auth.POST("/me/telegram", h.userIdentity, h.requireSession, writeLimit, h.linkCurrentUserTelegram)
+		// отвязка Telegram-аккаунта от текущего пользователя
+		auth.DELETE("/me/telegram", h.userIdentity, h.requireSession, writeLimit, h.unlinkCurrentUserTelegram)
+		// список активных сессий текущего пользователя
+		auth.GET("/me/sessions", h.userIdentity, h.requireSession, h.listCurrentUserSessions)
+		// завершение сессии текущего пользователя по id
+		auth.DELETE("/me/sessions/:id", h.userIdentity, h.requireSession, writeLimit, h.revokeCurrentUserSession)
+		// журнал безопасности текущего пользователя: входы, смена пароля, email, 2FA и т.д. (новые сверху, параметры limit и before)
+		auth.GET("/me/security-log", h.userIdentity, h.requireSession, h.listCurrentUserSecurityLog)
+		// создание персонального токена доступа с набором scope (например events:write), токен показывается один раз
+		auth.POST("/me/tokens", h.userIdentity, h.requireSession, writeLimit, h.createAccessToken)
+		// список персональных токенов текущего пользователя (без самих токенов)
+		auth.GET("/me/tokens", h.userIdentity, h.requireSession, h.listAccessTokens)
+		// отзыв персонального токена по id
+		auth.DELETE("/me/tokens/:id", h.userIdentity, h.requireSession, writeLimit, h.revokeAccessToken)
 	}
 
-	companies := router.Group("/companies", h.userIdentity)
+	companies := router.Group("/companies", h.userIdentity, h.requireScope("companies"), writeLimit)
 	{
 		// создание компании, возвращает id новой компании
 		companies.POST("", h.createCompany)
@@ -63,28 +133,68 @@ func (h *Handler) InitRoutes() *gin.Engine {
 		companies.GET("", h.listCompanies)
 		// получение информации о компании, если пользователь состоит в ней
 		companies.GET("/:id", h.getCompany)
-		// обновление информации о компании (только владелец может обновлять) - можно менять название, описание и аватар
+		// обновление информации о компании (владелец и администраторы) - можно менять название, описание и аватар
 		companies.PATCH("/:id", h.updateCompany)
 		// удаление компании (только владелец может удалять) - удаляет компанию и всех её членов
 		companies.DELETE("/:id", h.deleteCompany)
 		// выход из компании; владелец должен сначала назначить нового владельца
 		companies.POST("/:id/leave", h.leaveCompany)
+		// передача роли владельца другому участнику; прежний владелец остаётся в компании участником
+		companies.POST("/:id/transfer-ownership", h.transferCompanyOwnership)
+		// настройки компании: часовой пояс, длительность событий и дедлайн ответа по умолчанию, кто может создавать события, идеи и приглашать
+		companies.GET("/:id/settings", h.getCompanySettings)
+		// изменение настроек компании (только владелец), передаются только изменяемые поля
+		companies.PATCH("/:id/settings", h.updateCompanySettings)
+		// лента активности компании от новых записей к старым: before, limit и фильтр type
+		companies.GET("/:id/activity", h.listCompanyActivity)
 
-		// приглашение пользователя в компанию (может любой участник), возвращает id приглашения
+		// приглашение пользователя в компанию (участник с правом invite_members), возвращает id приглашения
 		companies.POST("/:id/invitations", h.inviteToCompany)
+		// приглашения, отправленные компанией: ожидающие, отклонённые и истёкшие
+		companies.GET("/:id/invitations/sent", h.listSentInvitations)
+		// отзыв ожидающего приглашения (автор приглашения, владелец или администратор)
+		companies.DELETE("/:id/invitations/:invite_id", h.revokeInvitation)
 		// получение списка приглашений в компании, которые получил пользователь - возвращает список компаний и id приглашения для каждой из них
 		companies.GET("/invitations", h.listInvitations)
 		// принять приглашение в компанию (по id приглашения) - добавляет пользователя в компанию и удаляет приглашение
 		companies.POST("/invitations/:id/accept", h.acceptInvitation)
 		// отклонить приглашение в компанию (по id приглашения) - удаляет приглашение
 		companies.POST("/invitations/:id/decline", h.declineInvitation)
+		// приглашение по email человека без аккаунта: письмо со ссылкой на регистрацию, после регистрации становится обычным приглашением
+		companies.POST("/:id/invitations/email", h.inviteByEmail)
+		// повторная отправка письма с приглашением по email, продлевает срок действия
+		companies.POST("/:id/invitations/email/:invite_id/resend", h.resendEmailInvitation)
 		// получить список участников компании
 		companies.GET("/:id/members", h.listCompanyMembers)
-		// удалить участника компании (только владелец)
+		// удалить участника компании (владелец или администратор; администратор может удалять только обычных участников)
 		companies.DELETE("/:id/members/:user_id", h.removeCompanyMember)
+		// изменить роль участника компании: admin или member (только владелец)
+		companies.PATCH("/:id/members/:user_id", h.updateCompanyMemberRole)
+
+		// создание ссылки-приглашения с необязательными сроком действия и лимитом использований
+		companies.POST("/:id/invite-links", h.createInviteLink)
+		// список действующих ссылок-приглашений компании
+		companies.GET("/:id/invite-links", h.listInviteLinks)
+		// отзыв ссылки-приглашения
+		companies.DELETE("/:id/invite-links/:link_id", h.revokeInviteLink)
+		// предпросмотр компании по коду ссылки: название, аватар и число участников
+		companies.GET("/join/:code", h.previewInviteLink)
+		// вступление в компанию по коду ссылки-приглашения
+		companies.POST("/join/:code", h.joinCompanyByInviteLink)
+
+		// поиск открытых (listed) компаний по названию и описанию: q, limit, offset
+		companies.GET("/discover", h.discoverCompanies)
+		// заявка на вступление в открытую компанию, владелец и администраторы получают уведомление
+		companies.POST("/:id/join-requests", h.createJoinRequest)
+		// ожидающие заявки на вступление (владелец или администратор)
+		companies.GET("/:id/join-requests", h.listJoinRequests)
+		// одобрить заявку: пользователь становится участником и получает уведомление
+		companies.POST("/:id/join-requests/:request_id/approve", h.approveJoinRequest)
+		// отклонить заявку, пользователь получает уведомление
+		companies.POST("/:id/join-requests/:request_id/reject", h.rejectJoinRequest)
 	}
 
-	events := router.Group("/events", h.userIdentity)
+	events := router.Group("/events", h.userIdentity, h.requireScope("events"), writeLimit)
 	{
 		// POST /events - create event (title, start_time, optional company_id)
 		events.POST("", h.createEvent)
@@ -98,7 +208,7 @@ func (h *Handler) InitRoutes() *gin.Engine {
 		events.DELETE("/:id", h.deleteEvent)
 	}
 
-	companyEvents := router.Group("/companies/:id/events", h.userIdentity)
+	companyEvents := router.Group("/companies/:id/events", h.userIdentity, h.requireScope("events"), writeLimit)
 	{
 		// POST /companies/:id/events - create event for company
 		companyEvents.POST("", h.createCompanyEvent)
@@ -118,7 +228,7 @@ func (h *Handler) InitRoutes() *gin.Engine {
 		companyEvents.GET("/:event_id/attendance/summary", h.listCompanyEventAttendanceSummary)
 	}
 
-	companyIdeas := router.Group("/companies/:id/ideas", h.userIdentity)
+	companyIdeas := router.Group("/companies/:id/ideas", h.userIdentity, h.requireScope("ideas"), writeLimit)
 	{
 		// POST /companies/:id/ideas - create idea for company
 		companyIdeas.POST("", h.createCompanyIdea)
@@ -134,7 +244,7 @@ func (h *Handler) InitRoutes() *gin.Engine {
 		companyIdeas.DELETE("/:idea_id/like", h.unlikeCompanyIdea)
 	}
 
-	availability := router.Group("/companies/:id/availability", h.userIdentity)
+	availability := router.Group("/companies/:id/availability", h.userIdentity, h.requireScope("availability"), writeLimit)
 	{
 		// POST /companies/:id/availability - add availability interval for current user
 		availability.POST("", h.createAvailability)
@@ -153,6 +263,18 @@ func (h *Handler) InitRoutes() *gin.Engine {
 	return router
 }
 
+// trustedProxiesFromEnv reads the comma-separated IPs or CIDRs of the reverse
+// proxies in front of the API. None are trusted by default.
+func trustedProxiesFromEnv() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
 func avatarUploadsRootDir() string {
 	if dir := os.Getenv("AVATAR_UPLOAD_DIR"); dir != "" {
 		return filepath.Dir(dir)
@@ -189,3 +311,16 @@ func (h *Handler) smtpHealthHandler(c *gin.Context) {
 		"status": status,
 	})
 }
+
+func (h *Handler) emailOutboxHandler(c *gin.Context) {
+	stats, err := h.services.EmailOutbox.EmailOutboxStats()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "db_error",
+			"error":  normalizeErrorMessage(http.StatusServiceUnavailable, err.Error()),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
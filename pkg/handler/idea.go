@@ -32,7 +32,7 @@ func (h *Handler) createCompanyIdea(c *gin.Context) {
 
 	id, err := h.services.Idea.CreateCompanyIdea(companyID, int64(userID), input, photoFileName, photoFileData)
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		newErrorResponse(c, companyErrorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
@@ -156,7 +156,7 @@ func (h *Handler) updateCompanyIdea(c *gin.Context) {
 			newErrorResponse(c, http.StatusBadRequest, err.Error())
 			return
 		}
-		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		newErrorResponse(c, companyErrorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requireInternalToken guards operational endpoints that are not meant for
+// users: the request must carry INTERNAL_API_TOKEN as a bearer token. Without
+// the variable the endpoints are disabled.
+func requireInternalToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			newErrorResponse(c, http.StatusNotFound, "not found")
+			return
+		}
+
+		provided := strings.TrimPrefix(c.GetHeader(authorizationHeader), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			newErrorResponse(c, http.StatusUnauthorized, "invalid internal token")
+			return
+		}
+		c.Next()
+	}
+}
+
+func internalAPIToken() string {
+	return os.Getenv("INTERNAL_API_TOKEN")
+}
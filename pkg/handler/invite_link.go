@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/Sovpalo/sovpalo-backend/pkg/service"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) createInviteLink(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	companyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid company id")
+		return
+	}
+
+	var input model.CompanyInviteLinkCreateInput
+	if c.Request.ContentLength > 0 {
+		if err := c.BindJSON(&input); err != nil {
+			newErrorResponse(c, http.StatusBadRequest, bindingErrorMessage(err))
+			return
+		}
+	}
+
+	link, err := h.services.Company.CreateInviteLink(companyID, int64(userID), input)
+	if err != nil {
+		mapInviteLinkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, link)
+}
+
+func (h *Handler) listInviteLinks(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	companyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid company id")
+		return
+	}
+
+	links, err := h.services.Company.ListInviteLinks(companyID, int64(userID))
+	if err != nil {
+		mapInviteLinkError(c, err)
+		return
+	}
+
+	if links == nil {
+		links = []model.CompanyInviteLink{}
+	}
+	c.JSON(http.StatusOK, links)
+}
+
+func (h *Handler) revokeInviteLink(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	companyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid company id")
+		return
+	}
+
+	linkID, err := strconv.ParseInt(c.Param("link_id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid invite link id")
+		return
+	}
+
+	if err := h.services.Company.RevokeInviteLink(companyID, int64(userID), linkID); err != nil {
+		mapInviteLinkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{Status: "ok"})
+}
+
+func (h *Handler) previewInviteLink(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	preview, err := h.services.Company.PreviewInviteLink(c.Param("code"), int64(userID))
+	if err != nil {
+		mapInviteLinkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+func (h *Handler) joinCompanyByInviteLink(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	companyID, err := h.services.Company.JoinCompanyByInviteLink(c.Param("code"), int64(userID))
+	if err != nil {
+		mapInviteLinkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"company_id": companyID})
+}
+
+func mapInviteLinkError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInviteLinkNotFound):
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrInviteLinkExpired), errors.Is(err, service.ErrInviteLinkUsedUp):
+		newErrorResponse(c, http.StatusGone, err.Error())
+	case errors.Is(err, service.ErrAlreadyCompanyMember):
+		newErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrNotCompanyMember):
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrInvalidInviteLinkParams):
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+	default:
+		newErrorResponse(c, companyErrorStatus(err, http.StatusInternalServerError), err.Error())
+	}
+}
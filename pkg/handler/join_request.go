@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/Sovpalo/sovpalo-backend/pkg/service"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) discoverCompanies(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var limit int
+	if value := c.Query("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			newErrorResponse(c, http.StatusBadRequest, "invalid limit parameter")
+			return
+		}
+	}
+
+	var offset int
+	if value := c.Query("offset"); value != "" {
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			newErrorResponse(c, http.StatusBadRequest, "invalid offset parameter")
+			return
+		}
+	}
+
+	companies, err := h.services.Company.DiscoverCompanies(int64(userID), c.Query("q"), limit, offset)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if companies == nil {
+		companies = []model.CompanyDiscoverView{}
+	}
+	c.JSON(http.StatusOK, companies)
+}
+
+func (h *Handler) createJoinRequest(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	companyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid company id")
+		return
+	}
+
+	var input model.CompanyJoinRequestInput
+	if c.Request.ContentLength > 0 {
+		if err := c.BindJSON(&input); err != nil {
+			newErrorResponse(c, http.StatusBadRequest, bindingErrorMessage(err))
+			return
+		}
+	}
+
+	request, err := h.services.Company.CreateJoinRequest(companyID, int64(userID), input.Message)
+	if err != nil {
+		mapJoinRequestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, request)
+}
+
+func (h *Handler) listJoinRequests(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	companyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid company id")
+		return
+	}
+
+	requests, err := h.services.Company.ListJoinRequests(companyID, int64(userID))
+	if err != nil {
+		mapJoinRequestError(c, err)
+		return
+	}
+
+	if requests == nil {
+		requests = []model.CompanyJoinRequestView{}
+	}
+	c.JSON(http.StatusOK, requests)
+}
+
+func (h *Handler) approveJoinRequest(c *gin.Context) {
+	h.respondToJoinRequest(c, h.services.Company.ApproveJoinRequest)
+}
+
+func (h *Handler) rejectJoinRequest(c *gin.Context) {
+	h.respondToJoinRequest(c, h.services.Company.RejectJoinRequest)
+}
+
+func (h *Handler) respondToJoinRequest(c *gin.Context, respond func(companyID int64, userID int64, requestID int64) error) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	companyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid company id")
+		return
+	}
+
+	requestID, err := strconv.ParseInt(c.Param("request_id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid join request id")
+		return
+	}
+
+	if err := respond(companyID, int64(userID), requestID); err != nil {
+		mapJoinRequestError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{Status: "ok"})
+}
+
+func mapJoinRequestError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrCompanyNotFound), errors.Is(err, service.ErrJoinRequestNotFound), errors.Is(err, service.ErrNotCompanyMember):
+		newErrorResponse(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrAlreadyCompanyMember), errors.Is(err, service.ErrJoinRequestAlreadySent):
+		newErrorResponse(c, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrJoinRequestRejectedRecently):
+		newErrorResponse(c, http.StatusTooManyRequests, err.Error())
+	default:
+		newErrorResponse(c, companyErrorStatus(err, http.StatusInternalServerError), err.Error())
+	}
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/service"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) startOIDCSignIn(c *gin.Context) {
+	authURL, err := h.services.Authorization.StartOIDCSignIn(c.Param("provider"))
+	if err != nil {
+		mapRegistrationError(c, err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+func (h *Handler) oidcCallback(c *gin.Context) {
+	if providerError := c.Query("error"); providerError != "" {
+		newErrorResponse(c, http.StatusUnauthorized, service.ErrOIDCSignInFailed.Error()+": "+providerError)
+		return
+	}
+
+	result, err := h.services.Authorization.CompleteOIDCSignIn(c.Param("provider"), c.Query("code"), c.Query("state"), sessionMeta(c))
+	if err != nil {
+		mapRegistrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
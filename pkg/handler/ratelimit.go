@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type rateLimitSubject func(c *gin.Context) string
+
+func clientIPSubject(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// userSubject keys authenticated requests by user ID and falls back to the
+// client IP when the route is not behind userIdentity.
+func userSubject(c *gin.Context) string {
+	userID, err := getUserId(c)
+	if err != nil {
+		return clientIPSubject(c)
+	}
+	return "user:" + strconv.Itoa(userID)
+}
+
+func (h *Handler) rateLimit(policy string, subject rateLimitSubject) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := h.services.RateLimiter.Allow(c.Request.Context(), policy, subject(c))
+		if err != nil {
+			// Fail open: an unavailable limiter must not take the API down.
+			logrus.Warnf("rate limit check failed: %s", err.Error())
+			c.Next()
+			return
+		}
+		if result.Limit == 0 {
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.Reset)))
+			newErrorResponse(c, http.StatusTooManyRequests, "too many requests")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitWrites applies the policy only to state-changing requests.
+func (h *Handler) rateLimitWrites(policy string, subject rateLimitSubject) gin.HandlerFunc {
+	limit := h.rateLimit(policy, subject)
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+		default:
+			limit(c)
+		}
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
@@ -63,6 +63,8 @@ func validationFieldMessage(err validator.FieldError) string {
 		return fmt.Sprintf("field %s must be %s characters long", field, err.Param())
 	case "numeric":
 		return fmt.Sprintf("field %s must contain only digits", field)
+	case "oneof":
+		return fmt.Sprintf("field %s must be one of %s", field, strings.Join(strings.Fields(err.Param()), ", "))
 	default:
 		return fmt.Sprintf("field %s is invalid", field)
 	}
@@ -94,6 +96,14 @@ func normalizeErrorMessage(statusCode int, message string) string {
 		return "Company ID must be a valid number."
 	case "invalid invitation id":
 		return "Invitation ID must be a valid number."
+	case "invalid invite link id":
+		return "Invite link ID must be a valid number."
+	case "invalid join request id":
+		return "Join request ID must be a valid number."
+	case "invalid offset parameter":
+		return "Parameter offset must be a non-negative number."
+	case "invalid email invitation id":
+		return "Email invitation ID must be a valid number."
 	case "invalid user id":
 		return "User ID must be a valid number."
 	case "invalid event id":
@@ -127,30 +137,120 @@ func normalizeErrorMessage(statusCode int, message string) string {
 		return "Event not found."
 	case "company not found":
 		return "Company not found."
+	case "too many requests":
+		return "Too many requests. Please try again later."
 	case "auth challenge storage unavailable":
 		return "Temporary error while processing the verification code. Please try again."
 	case "invalid email or password":
 		return "Incorrect email or password."
+	case "current password is incorrect":
+		return "Current password is incorrect."
+	case "recent sign-in required":
+		return "Please sign in again to confirm this change."
+	case "invalid two-factor code":
+		return "The authentication code is incorrect."
+	case "two-factor challenge not found":
+		return "Two-factor sign-in has expired. Sign in with your password again."
+	case "two-factor authentication is already enabled":
+		return "Two-factor authentication is already enabled."
+	case "two-factor authentication is not enabled":
+		return "Two-factor authentication is not enabled."
+	case "two-factor enrollment was not started":
+		return "Start two-factor setup first to get a secret."
 	case "pending registration not found":
 		return "Verification code was not requested or has already expired."
 	case "verification code expired":
 		return "Verification code has expired. Request a new one."
 	case "incorrect verification code":
 		return "Verification code is incorrect."
+	case "too many attempts":
+		return "Too many incorrect attempts. Start the verification again to get a new code."
+	case "verification code was requested too recently":
+		return "A new code was requested too recently. Please wait a minute and try again."
+	case "invalid refresh token":
+		return "Refresh token is invalid or has expired. Please sign in again."
+	case "refresh token reuse detected":
+		return "This refresh token has already been used. All sessions on this device were signed out."
+	case "session not found":
+		return "Session not found."
+	case "invalid session id":
+		return "Session ID must be a valid number."
+	case "invalid before parameter":
+		return "The before parameter must be a positive entry ID."
+	case "invalid limit parameter":
+		return "The limit parameter must be a positive number."
+	case "invalid telegram authorization":
+		return "Telegram authorization data is invalid."
+	case "telegram authorization expired":
+		return "Telegram authorization has expired. Please log in with Telegram again."
+	case "telegram account is already linked to another user":
+		return "This Telegram account is already linked to another user."
+	case "telegram account is not linked":
+		return "No Telegram account is linked to this user."
+	case "cannot remove the only sign-in method":
+		return "Set an email and password before unlinking Telegram, otherwise you will not be able to sign in."
+	case "invalid personal access token":
+		return "Personal access token is invalid, expired or revoked."
+	case "personal access token not found":
+		return "Personal access token not found."
+	case "invalid token id":
+		return "Token ID must be a valid number."
+	case "personal access tokens cannot be used here":
+		return "This endpoint requires signing in; personal access tokens are not accepted."
+	case "oidc provider not found":
+		return "This sign-in provider is not configured."
+	case "invalid or expired oidc state":
+		return "Sign-in session has expired. Please start signing in again."
+	case "oidc sign-in failed":
+		return "Could not sign in with this provider. Please try again."
 	case "user with this email already exists":
 		return "An account with this email already exists."
 	case "user with this username already exists":
 		return "This username is already taken."
 	case "invitation already handled":
 		return "This invitation has already been processed."
+	case "invite link not found":
+		return "This invite link does not exist or has been revoked."
+	case "invite link has expired":
+		return "This invite link has expired."
+	case "invite link has reached its usage limit":
+		return "This invite link has already been used the maximum number of times."
+	case "email invitation not found":
+		return "This email invitation does not exist or has already been used."
+	case "invitation was sent too recently":
+		return "The invitation was sent recently. Please wait a minute before sending it again."
 	case "cannot invite yourself":
 		return "You cannot invite yourself."
 	case "user already in company":
 		return "This user is already a member of the company."
 	case "invitation already sent":
 		return "An invitation has already been sent to this user."
-	case "only company owner can remove members":
-		return "Only the company owner can remove members."
+	case "invitation not found":
+		return "This invitation does not exist or is no longer pending."
+	case "invitation has expired":
+		return "This invitation has expired. Ask for a new one."
+	case "invitation was declined recently":
+		return "This user declined an invitation recently. Please try again later."
+	case "not enough permissions in the company":
+		return "Your role in this company does not allow this action."
+	case "role must be admin or member":
+		return "Field role must be one of: admin, member."
+	case "join request not found":
+		return "This join request does not exist or has already been handled."
+	case "join request already sent":
+		return "You have already asked to join this company. Please wait for a response."
+	case "join request was rejected recently":
+		return "Your request to join this company was rejected recently. Please try again later."
+	case "rsvp deadline has passed":
+		return "The deadline for responding to this event has passed."
+	case "visibility must be private or listed":
+		return "Field visibility must be one of: private, listed."
+	case "new owner must be another company member":
+		return "You already own this company. Choose another member as the new owner."
+	case "new owner must be a member of the company":
+		return "The new owner must be a member of the company."
+	case "cannot change role of this member":
+		return "You cannot change the role of this member."
 	case "cannot remove company owner":
 		return "The company owner cannot be removed."
 	case "title is required":
@@ -185,20 +285,60 @@ func normalizeErrorMessage(statusCode int, message string) string {
 		return capitalizeMessage(strings.TrimPrefix(message, "invalid password: ")) + "."
 	}
 
+	if strings.HasPrefix(message, "invalid profile: ") {
+		return capitalizeMessage(strings.TrimPrefix(message, "invalid profile: ")) + "."
+	}
+
+	if strings.HasPrefix(message, "invalid token parameters: ") {
+		return capitalizeMessage(strings.TrimPrefix(message, "invalid token parameters: ")) + "."
+	}
+
+	if strings.HasPrefix(message, "invalid invite link parameters: ") {
+		return capitalizeMessage(strings.TrimPrefix(message, "invalid invite link parameters: ")) + "."
+	}
+
+	if strings.HasPrefix(message, "invalid company settings: ") {
+		return capitalizeMessage(strings.TrimPrefix(message, "invalid company settings: ")) + "."
+	}
+
+	if strings.HasPrefix(message, "invalid activity filter: ") {
+		return capitalizeMessage(strings.TrimPrefix(message, "invalid activity filter: ")) + "."
+	}
+
+	if strings.HasPrefix(message, "token is missing scope ") {
+		return "This personal access token does not have the " + strings.TrimPrefix(message, "token is missing scope ") + " scope."
+	}
+
+	if strings.HasPrefix(message, "oidc sign-in failed: ") {
+		return "Sign-in was cancelled or rejected by the provider."
+	}
+
+	if strings.HasPrefix(message, "oidc provider unavailable: ") {
+		return "The sign-in provider is not responding. Please try again later."
+	}
+
 	if strings.Contains(message, "token contains an invalid number of segments") ||
 		strings.Contains(message, "token is malformed") ||
 		strings.Contains(message, "token signature is invalid") ||
 		strings.Contains(message, "token has invalid claims") ||
 		strings.Contains(message, "token is expired") ||
 		strings.Contains(message, "Invalid signing method") ||
-		strings.Contains(message, "Invalid token claims") {
+		strings.Contains(message, "Invalid token claims") ||
+		strings.Contains(message, "token has been revoked") ||
+		strings.Contains(message, "token signed with an unknown key") ||
+		strings.Contains(message, "token is unverifiable") ||
+		strings.Contains(message, "token is missing required claim") {
 		return "Access token is invalid or has expired."
 	}
 
-	if strings.Contains(message, "JWT_SECRET not set") {
+	if strings.Contains(message, "JWT_PRIVATE_KEY_FILE") || strings.Contains(message, "JWT_PREVIOUS_KEY_FILES") {
 		return "Authentication service is temporarily unavailable."
 	}
 
+	if strings.Contains(message, "TELEGRAM_BOT_TOKEN not set") {
+		return "Telegram sign-in is not available right now."
+	}
+
 	if strings.Contains(message, "PASSWORD_SALT not set") {
 		return "Password service is temporarily unavailable."
 	}
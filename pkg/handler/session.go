@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) refreshToken(c *gin.Context) {
+	var input model.RefreshTokenInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, bindingErrorMessage(err))
+		return
+	}
+
+	tokens, err := h.services.Authorization.RefreshSession(input.RefreshToken, sessionMeta(c))
+	if err != nil {
+		mapRegistrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *Handler) logout(c *gin.Context) {
+	var input model.RefreshTokenInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, bindingErrorMessage(err))
+		return
+	}
+
+	if err := h.services.Authorization.Logout(input.RefreshToken); err != nil {
+		mapRegistrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{Status: "ok"})
+}
+
+func (h *Handler) listCurrentUserSessions(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	sessions, err := h.services.Authorization.ListSessions(int64(userID))
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if sessions == nil {
+		sessions = []model.UserSession{}
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+func (h *Handler) revokeCurrentUserSession(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	sessionID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid session id")
+		return
+	}
+
+	if err := h.services.Authorization.RevokeSession(int64(userID), sessionID); err != nil {
+		mapRegistrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, statusResponse{Status: "ok"})
+}
+
+func (h *Handler) jwks(c *gin.Context) {
+	keySet, err := h.services.Authorization.JWKS()
+	if err != nil {
+		newErrorResponse(c, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, keySet)
+}
+
+func (h *Handler) listCurrentUserSecurityLog(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var beforeID int64
+	if value := c.Query("before"); value != "" {
+		beforeID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || beforeID <= 0 {
+			newErrorResponse(c, http.StatusBadRequest, "invalid before parameter")
+			return
+		}
+	}
+
+	var limit int
+	if value := c.Query("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			newErrorResponse(c, http.StatusBadRequest, "invalid limit parameter")
+			return
+		}
+	}
+
+	entries, err := h.services.Authorization.ListSecurityLog(int64(userID), beforeID, limit)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if entries == nil {
+		entries = []model.AuthAuditEntry{}
+	}
+	c.JSON(http.StatusOK, entries)
+}
@@ -15,17 +15,83 @@ func (h *Handler) signIn(c *gin.Context) {
 		return
 	}
 
-	token, err := h.services.Authorization.SignIn(input)
+	result, err := h.services.Authorization.SignIn(input, sessionMeta(c))
 	if err != nil {
 		mapRegistrationError(c, err)
 		return
 	}
 
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) startCodeSignIn(c *gin.Context) {
+	var input model.SignInCodeInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, bindingErrorMessage(err))
+		return
+	}
+
+	if err := h.services.Authorization.StartCodeSignIn(input); err != nil {
+		mapRegistrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":        "verification code sent",
+		"expires_in_sec": int(h.services.Authorization.PendingRegistrationTTL().Seconds()),
+	})
+}
+
+func (h *Handler) verifyCodeSignIn(c *gin.Context) {
+	var input model.SignInCodeVerifyInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, bindingErrorMessage(err))
+		return
+	}
+
+	result, err := h.services.Authorization.VerifyCodeSignIn(input, sessionMeta(c))
+	if err != nil {
+		mapRegistrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) resendCodeSignIn(c *gin.Context) {
+	var input model.SignInCodeInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, bindingErrorMessage(err))
+		return
+	}
+
+	if err := h.services.Authorization.ResendSignInCode(input.Email, c.ClientIP()); err != nil {
+		mapRegistrationError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"token": token,
+		"message":        "verification code resent",
+		"expires_in_sec": int(h.services.Authorization.PendingRegistrationTTL().Seconds()),
 	})
 }
 
+func (h *Handler) verifyTwoFactorSignIn(c *gin.Context) {
+	var input model.TwoFactorSignInInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, bindingErrorMessage(err))
+		return
+	}
+
+	tokens, err := h.services.Authorization.VerifyTwoFactorSignIn(input, sessionMeta(c))
+	if err != nil {
+		mapRegistrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
 func (h *Handler) forgotPassword(c *gin.Context) {
 	var input model.ForgotPasswordInput
 	if err := c.BindJSON(&input); err != nil {
@@ -51,7 +117,7 @@ func (h *Handler) verifyForgotPassword(c *gin.Context) {
 		return
 	}
 
-	if err := h.services.Authorization.VerifyPasswordReset(input); err != nil {
+	if err := h.services.Authorization.VerifyPasswordReset(input, sessionMeta(c)); err != nil {
 		mapRegistrationError(c, err)
 		return
 	}
@@ -68,7 +134,7 @@ func (h *Handler) resendForgotPasswordCode(c *gin.Context) {
 		return
 	}
 
-	if err := h.services.Authorization.ResendPasswordResetCode(input.Email); err != nil {
+	if err := h.services.Authorization.ResendPasswordResetCode(input.Email, c.ClientIP()); err != nil {
 		mapRegistrationError(c, err)
 		return
 	}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/Sovpalo/sovpalo-backend/pkg/service"
 	"github.com/gin-gonic/gin"
 )
 
@@ -13,6 +14,9 @@ func (h *Handler) signUp(c *gin.Context) {
 		newErrorResponse(c, http.StatusBadRequest, bindingErrorMessage(err))
 		return
 	}
+	if input.Locale == "" {
+		input.Locale = service.PreferredLocale(c.GetHeader("Accept-Language"))
+	}
 
 	if err := h.services.Authorization.StartRegistration(input); err != nil {
 		mapRegistrationError(c, err)
@@ -32,15 +36,13 @@ func (h *Handler) verifySignUp(c *gin.Context) {
 		return
 	}
 
-	token, err := h.services.Authorization.VerifyRegistration(input)
+	tokens, err := h.services.Authorization.VerifyRegistration(input, sessionMeta(c))
 	if err != nil {
 		mapRegistrationError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-	})
+	c.JSON(http.StatusOK, tokens)
 }
 
 func (h *Handler) resendSignUpCode(c *gin.Context) {
@@ -52,7 +54,7 @@ func (h *Handler) resendSignUpCode(c *gin.Context) {
 		return
 	}
 
-	if err := h.services.Authorization.ResendRegistrationCode(input.Email); err != nil {
+	if err := h.services.Authorization.ResendRegistrationCode(input.Email, c.ClientIP()); err != nil {
 		mapRegistrationError(c, err)
 		return
 	}
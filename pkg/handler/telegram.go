@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errInvalidTelegramField = errors.New("invalid telegram field")
+
+func (h *Handler) signInWithTelegram(c *gin.Context) {
+	data, err := bindTelegramAuthData(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	result, err := h.services.Authorization.SignInWithTelegram(data, sessionMeta(c))
+	if err != nil {
+		mapRegistrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) linkCurrentUserTelegram(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	data, err := bindTelegramAuthData(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, "invalid input body")
+		return
+	}
+
+	profile, err := h.services.Authorization.LinkTelegram(int64(userID), data, sessionMeta(c))
+	if err != nil {
+		mapRegistrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *Handler) unlinkCurrentUserTelegram(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	profile, err := h.services.Authorization.UnlinkTelegram(int64(userID), sessionMeta(c))
+	if err != nil {
+		mapRegistrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// bindTelegramAuthData reads the Login Widget payload as flat string fields.
+// The hash covers the exact values Telegram sent, so numbers are kept in their
+// original textual form and null fields are dropped.
+func bindTelegramAuthData(c *gin.Context) (map[string]string, error) {
+	var raw map[string]interface{}
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch v := value.(type) {
+		case nil:
+			continue
+		case string:
+			data[key] = v
+		case json.Number:
+			data[key] = v.String()
+		case bool:
+			if v {
+				data[key] = "true"
+			} else {
+				data[key] = "false"
+			}
+		default:
+			return nil, errInvalidTelegramField
+		}
+	}
+
+	return data, nil
+}
@@ -5,8 +5,10 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
 	"github.com/Sovpalo/sovpalo-backend/pkg/service"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 const maxAvatarUploadSize = 5 << 20
@@ -32,6 +34,72 @@ func (h *Handler) getCurrentUser(c *gin.Context) {
 	c.JSON(http.StatusOK, profile)
 }
 
+func (h *Handler) updateCurrentUser(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var input model.UpdateUserInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, bindingErrorMessage(err))
+		return
+	}
+
+	profile, err := h.services.Authorization.UpdateProfile(int64(userID), input)
+	if err != nil {
+		mapRegistrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *Handler) verifyCurrentUserEmail(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var input model.EmailChangeVerifyInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, bindingErrorMessage(err))
+		return
+	}
+
+	profile, err := h.services.Authorization.VerifyEmailChange(int64(userID), input, sessionMeta(c))
+	if err != nil {
+		mapRegistrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *Handler) changeCurrentUserPassword(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	var input model.ChangePasswordInput
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, bindingErrorMessage(err))
+		return
+	}
+
+	tokens, err := h.services.Authorization.ChangePassword(int64(userID), input, sessionMeta(c))
+	if err != nil {
+		mapRegistrationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
 func (h *Handler) deleteCurrentUser(c *gin.Context) {
 	userID, err := getUserId(c)
 	if err != nil {
@@ -39,7 +107,8 @@ func (h *Handler) deleteCurrentUser(c *gin.Context) {
 		return
 	}
 
-	if err := h.services.Authorization.DeleteUser(int64(userID)); err != nil {
+	purgeAfter, err := h.services.Authorization.DeleteUser(int64(userID), sessionMeta(c))
+	if err != nil {
 		if errors.Is(err, service.ErrUserNotFound) {
 			newErrorResponse(c, http.StatusNotFound, err.Error())
 			return
@@ -50,10 +119,38 @@ func (h *Handler) deleteCurrentUser(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "user deleted",
+		"message":     "user deletion scheduled",
+		"purge_after": purgeAfter,
 	})
 }
 
+func (h *Handler) exportCurrentUser(c *gin.Context) {
+	userID, err := getUserId(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	export, err := h.services.Export.GetUserDataExport(int64(userID))
+	if err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			newErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
+
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="sovpalo-export.zip"`)
+	c.Status(http.StatusOK)
+	if err := h.services.Export.WriteUserDataExport(export, c.Writer); err != nil {
+		// the archive is already partially sent, so the status can no longer change
+		logrus.Errorf("failed to write data export for user %d: %s", userID, err.Error())
+	}
+}
+
 func (h *Handler) uploadCurrentUserAvatar(c *gin.Context) {
 	userID, err := getUserId(c)
 	if err != nil {
@@ -88,7 +185,7 @@ func (h *Handler) uploadCurrentUserAvatar(c *gin.Context) {
 		return
 	}
 
-	profile, err := h.services.Authorization.UpdateAvatar(int64(userID), fileHeader.Filename, fileData)
+	profile, err := h.services.Authorization.UpdateAvatar(int64(userID), fileHeader.Filename, fileData, sessionMeta(c))
 	if err != nil {
 		switch {
 		case errors.Is(err, service.ErrUserNotFound):
@@ -111,7 +208,7 @@ func (h *Handler) deleteCurrentUserAvatar(c *gin.Context) {
 		return
 	}
 
-	profile, err := h.services.Authorization.DeleteAvatar(int64(userID))
+	profile, err := h.services.Authorization.DeleteAvatar(int64(userID), sessionMeta(c))
 	if err != nil {
 		if errors.Is(err, service.ErrUserNotFound) {
 			newErrorResponse(c, http.StatusNotFound, err.Error())
@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// Scopes of personal access tokens. Each resource has a read and a write
+// scope; write also allows reading.
+const (
+	ScopeProfileRead       = "profile:read"
+	ScopeProfileWrite      = "profile:write"
+	ScopeCompaniesRead     = "companies:read"
+	ScopeCompaniesWrite    = "companies:write"
+	ScopeEventsRead        = "events:read"
+	ScopeEventsWrite       = "events:write"
+	ScopeIdeasRead         = "ideas:read"
+	ScopeIdeasWrite        = "ideas:write"
+	ScopeAvailabilityRead  = "availability:read"
+	ScopeAvailabilityWrite = "availability:write"
+)
+
+var AccessTokenScopes = []string{
+	ScopeProfileRead,
+	ScopeProfileWrite,
+	ScopeCompaniesRead,
+	ScopeCompaniesWrite,
+	ScopeEventsRead,
+	ScopeEventsWrite,
+	ScopeIdeasRead,
+	ScopeIdeasWrite,
+	ScopeAvailabilityRead,
+	ScopeAvailabilityWrite,
+}
+
+type PersonalAccessToken struct {
+	ID         int64      `db:"id" json:"id"`
+	UserID     int64      `db:"user_id" json:"-"`
+	Name       string     `db:"name" json:"name"`
+	TokenHash  string     `db:"token_hash" json:"-"`
+	Scopes     []string   `db:"scopes" json:"scopes"`
+	ExpiresAt  *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `db:"last_used_at" json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+}
+
+type PersonalAccessTokenCreateInput struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// PersonalAccessTokenCreated is returned once, when the token is created: only
+// its hash is stored.
+type PersonalAccessTokenCreated struct {
+	PersonalAccessToken
+	Token string `json:"token"`
+}
+
+// AccessTokenIdentity is the user and scopes a personal access token grants.
+type AccessTokenIdentity struct {
+	UserID int64
+	Scopes []string
+}
@@ -0,0 +1,74 @@
+package model
+
+import "time"
+
+// Types of company activity entries.
+const (
+	ActivityEventCreated         = "event_created"
+	ActivityEventUpdated         = "event_updated"
+	ActivityEventDeleted         = "event_deleted"
+	ActivityEventRSVP            = "event_rsvp"
+	ActivityIdeaCreated          = "idea_created"
+	ActivityIdeaUpdated          = "idea_updated"
+	ActivityIdeaLiked            = "idea_liked"
+	ActivityIdeaUnliked          = "idea_unliked"
+	ActivityMemberJoined         = "member_joined"
+	ActivityMemberLeft           = "member_left"
+	ActivityMemberRemoved        = "member_removed"
+	ActivityMemberRoleChanged    = "member_role_changed"
+	ActivityOwnershipTransferred = "ownership_transferred"
+	ActivityCompanyUpdated       = "company_updated"
+	ActivitySettingsUpdated      = "settings_updated"
+	ActivityAvailabilityCreated  = "availability_created"
+	ActivityAvailabilityUpdated  = "availability_updated"
+	ActivityAvailabilityDeleted  = "availability_deleted"
+	ActivityProfileUpdated       = "profile_updated"
+)
+
+// ActivityTypes lists every type accepted by the activity feed filter.
+var ActivityTypes = []string{
+	ActivityEventCreated,
+	ActivityEventUpdated,
+	ActivityEventDeleted,
+	ActivityEventRSVP,
+	ActivityIdeaCreated,
+	ActivityIdeaUpdated,
+	ActivityIdeaLiked,
+	ActivityIdeaUnliked,
+	ActivityMemberJoined,
+	ActivityMemberLeft,
+	ActivityMemberRemoved,
+	ActivityMemberRoleChanged,
+	ActivityOwnershipTransferred,
+	ActivityCompanyUpdated,
+	ActivitySettingsUpdated,
+	ActivityAvailabilityCreated,
+	ActivityAvailabilityUpdated,
+	ActivityAvailabilityDeleted,
+	ActivityProfileUpdated,
+}
+
+// CompanyActivity is one entry of the company activity feed. SubjectID is
+// the event, idea, availability slot or user the entry is about.
+type CompanyActivity struct {
+	ID        int64     `db:"id" json:"id"`
+	CompanyID int64     `db:"company_id" json:"company_id"`
+	ActorID   *int64    `db:"actor_id" json:"actor_id,omitempty"`
+	Type      string    `db:"type" json:"type"`
+	SubjectID *int64    `db:"subject_id" json:"subject_id,omitempty"`
+	Details   *string   `db:"details" json:"details,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// CompanyActivityView is an activity entry with the actor's public profile.
+// The actor fields are empty once the actor deleted their account.
+type CompanyActivityView struct {
+	ID             int64     `db:"id" json:"id"`
+	Type           string    `db:"type" json:"type"`
+	ActorID        *int64    `db:"actor_id" json:"actor_id,omitempty"`
+	ActorUsername  *string   `db:"actor_username" json:"actor_username,omitempty"`
+	ActorAvatarURL *string   `db:"actor_avatar_url" json:"actor_avatar_url,omitempty"`
+	SubjectID      *int64    `db:"subject_id" json:"subject_id,omitempty"`
+	Details        *string   `db:"details" json:"details,omitempty"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// Event types recorded in the authentication audit log.
+const (
+	AuditEventSignIn          = "sign_in"
+	AuditEventSignUp          = "sign_up"
+	AuditEventPasswordReset   = "password_reset"
+	AuditEventPasswordChange  = "password_change"
+	AuditEventEmailChange     = "email_change"
+	AuditEventAvatarChange    = "avatar_change"
+	AuditEventAvatarDelete    = "avatar_delete"
+	AuditEventTOTPEnable      = "totp_enable"
+	AuditEventTOTPDisable     = "totp_disable"
+	AuditEventTelegramLink    = "telegram_link"
+	AuditEventTelegramUnlink  = "telegram_unlink"
+	AuditEventAccountDeletion = "account_deletion"
+)
+
+// Outcomes of an audited event. A sign-in that passed the first factor but
+// still waits for a TOTP code is recorded as two_factor_required.
+const (
+	AuditOutcomeSuccess           = "success"
+	AuditOutcomeFailure           = "failure"
+	AuditOutcomeTwoFactorRequired = "two_factor_required"
+)
+
+type AuthAuditEntry struct {
+	ID        int64     `db:"id" json:"id"`
+	UserID    *int64    `db:"user_id" json:"-"`
+	EventType string    `db:"event_type" json:"event_type"`
+	Outcome   string    `db:"outcome" json:"outcome"`
+	IPAddress *string   `db:"ip_address" json:"ip_address,omitempty"`
+	UserAgent *string   `db:"user_agent" json:"user_agent,omitempty"`
+	Details   *string   `db:"details" json:"details,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// SignInHistory tells whether a user has signed in before, and whether any
+// of those sign-ins came from the given IP address or user agent.
+type SignInHistory struct {
+	HasSignIns  bool
+	KnownIP     bool
+	KnownDevice bool
+}
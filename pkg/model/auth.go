@@ -1,11 +1,16 @@
 package model
 
-import "time"
+import (
+	"strconv"
+	"time"
+)
 
 type SignUpInput struct {
 	Email    string `json:"email" binding:"required,email"`
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// Locale of the user's emails; Accept-Language is used when it is empty.
+	Locale string `json:"locale,omitempty" binding:"omitempty,oneof=ru en"`
 }
 
 type SignUpVerifyInput struct {
@@ -18,6 +23,15 @@ type SignInInput struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type SignInCodeInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type SignInCodeVerifyInput struct {
+	Email string `json:"email" binding:"required,email"`
+	Code  string `json:"code" binding:"required,len=4,numeric"`
+}
+
 type ForgotPasswordInput struct {
 	Email string `json:"email" binding:"required,email"`
 }
@@ -28,10 +42,128 @@ type ResetPasswordVerifyInput struct {
 	NewPassword string `json:"new_password" binding:"required"`
 }
 
+type TwoFactorSignInInput struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// TOTPEnrollInput confirms the user before enrolling. Accounts without a
+// password leave Password empty and need a recent sign-in instead.
+type TOTPEnrollInput struct {
+	Password string `json:"password"`
+}
+
+type TOTPConfirmInput struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type TOTPDisableInput struct {
+	Password string `json:"password"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// SessionMeta describes the client a session is issued to.
+type SessionMeta struct {
+	UserAgent string
+	IPAddress string
+	// Locale is taken from Accept-Language and given to accounts created on
+	// sign-in, e.g. with Telegram or OpenID Connect.
+	Locale string
+	// AuthTime is when the caller's access token was issued by a sign-in. It
+	// is zero for tokens issued by a refresh and for unauthenticated requests.
+	AuthTime time.Time
+}
+
+// TokenIdentity is the user a JWT access token was issued to.
+type TokenIdentity struct {
+	UserID   int
+	AuthTime time.Time
+}
+
+type AuthTokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresInSec int    `json:"expires_in_sec"`
+}
+
+type EmailChangeVerifyInput struct {
+	Email string `json:"email" binding:"required,email"`
+	Code  string `json:"code" binding:"required,len=4,numeric"`
+}
+
+// SignInResult is either a token pair or, for accounts with two-factor
+// authentication, a challenge token to exchange at /auth/sign-in/2fa.
+type SignInResult struct {
+	*AuthTokens
+	TwoFactorRequired     bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken        string `json:"challenge_token,omitempty"`
+	ChallengeExpiresInSec int    `json:"challenge_expires_in_sec,omitempty"`
+}
+
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorChallenge is stored between the password step and the second
+// factor of a sign-in.
+type TwoFactorChallenge struct {
+	UserID    int64     `json:"user_id"`
+	Attempts  int       `json:"attempts"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// OIDCAuthState is stored between /auth/oidc/:provider/start and the
+// provider redirecting back to the callback.
+type OIDCAuthState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// UserIdentity links an account at an external OpenID Connect provider to a user.
+type UserIdentity struct {
+	ID        int64     `db:"id" json:"id"`
+	UserID    int64     `db:"user_id" json:"user_id"`
+	Provider  string    `db:"provider" json:"provider"`
+	Subject   string    `db:"subject" json:"subject"`
+	Email     *string   `db:"email" json:"email,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
 type UserProfile struct {
-	Email     string  `json:"email"`
-	Username  string  `json:"username"`
-	AvatarURL *string `json:"avatar_url,omitempty"`
+	Email        string  `json:"email"`
+	Username     string  `json:"username"`
+	FirstName    *string `json:"first_name,omitempty"`
+	SecondName   *string `json:"second_name,omitempty"`
+	AvatarURL    *string `json:"avatar_url,omitempty"`
+	TelegramID   *int64  `json:"telegram_id,omitempty"`
+	TwoFactor    bool    `json:"two_factor_enabled"`
+	Locale       string  `json:"locale"`
+	PendingEmail *string `json:"pending_email,omitempty"`
+}
+
+// TelegramAuthData is the verified payload of the Telegram Login Widget.
+type TelegramAuthData struct {
+	ID        int64
+	FirstName string
+	LastName  string
+	Username  string
+	PhotoURL  string
+	AuthDate  time.Time
 }
 
 type AuthChallengeType string
@@ -39,13 +171,50 @@ type AuthChallengeType string
 const (
 	AuthChallengeTypeSignUp        AuthChallengeType = "sign_up"
 	AuthChallengeTypePasswordReset AuthChallengeType = "password_reset"
+	AuthChallengeTypeEmailChange   AuthChallengeType = "email_change"
+	AuthChallengeTypeLogin         AuthChallengeType = "login"
 )
 
 type PendingAuthChallenge struct {
 	Type         AuthChallengeType `json:"type"`
 	Email        string            `json:"email"`
+	UserID       int64             `json:"user_id,omitempty"`
 	Username     string            `json:"username,omitempty"`
 	PasswordHash string            `json:"password_hash,omitempty"`
+	Locale       string            `json:"locale,omitempty"`
 	Code         string            `json:"code"`
 	ExpiresAt    time.Time         `json:"expires_at"`
 }
+
+// Subject is what the challenge is stored under, see AuthChallengeSubject.
+func (c PendingAuthChallenge) Subject() string {
+	return AuthChallengeSubject(c.Type, c.UserID, c.Email)
+}
+
+// AuthChallengeSubject identifies a pending challenge of the given type.
+// Most challenges belong to an email; an email change also belongs to the
+// user asking for it, so two users entering the same new address do not
+// replace each other's code.
+func AuthChallengeSubject(challengeType AuthChallengeType, userID int64, email string) string {
+	if challengeType == AuthChallengeTypeEmailChange {
+		return strconv.FormatInt(userID, 10) + ":" + email
+	}
+	return email
+}
+
+// JSONWebKey is a public key as published in the JWKS document (RFC 7517).
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
@@ -0,0 +1,106 @@
+package model
+
+// Roles a company member can have. Every company has exactly one owner.
+const (
+	CompanyRoleOwner  = "owner"
+	CompanyRoleAdmin  = "admin"
+	CompanyRoleMember = "member"
+)
+
+// CompanyPermission is an action inside a company that depends on the role.
+type CompanyPermission string
+
+const (
+	// CompanyPermissionView covers reading company data, RSVPs, likes and the
+	// member's own availability.
+	CompanyPermissionView          CompanyPermission = "view"
+	CompanyPermissionInvite        CompanyPermission = "invite_members"
+	CompanyPermissionRemoveMembers CompanyPermission = "remove_members"
+	CompanyPermissionManageRoles   CompanyPermission = "manage_roles"
+	CompanyPermissionEditCompany   CompanyPermission = "edit_company"
+	CompanyPermissionDeleteCompany CompanyPermission = "delete_company"
+	// CompanyPermissionTransferOwnership allows handing the owner role to
+	// another member.
+	CompanyPermissionTransferOwnership CompanyPermission = "transfer_ownership"
+	// CompanyPermissionManageSettings allows changing CompanySettings.
+	CompanyPermissionManageSettings CompanyPermission = "manage_settings"
+	// CompanyPermissionManageJoinRequests allows approving and rejecting
+	// requests to join a listed company.
+	CompanyPermissionManageJoinRequests CompanyPermission = "manage_join_requests"
+	CompanyPermissionCreateEvents       CompanyPermission = "create_events"
+	CompanyPermissionCreateIdeas        CompanyPermission = "create_ideas"
+	// CompanyPermissionModerateEvents allows editing and deleting events
+	// created by other members; authors can always manage their own.
+	CompanyPermissionModerateEvents CompanyPermission = "moderate_events"
+	// CompanyPermissionModerateIdeas allows editing ideas of other members.
+	CompanyPermissionModerateIdeas CompanyPermission = "moderate_ideas"
+)
+
+// CompanyRolePermissions is the permission matrix: what each role may do.
+var CompanyRolePermissions = map[string][]CompanyPermission{
+	CompanyRoleOwner: {
+		CompanyPermissionView,
+		CompanyPermissionInvite,
+		CompanyPermissionRemoveMembers,
+		CompanyPermissionManageJoinRequests,
+		CompanyPermissionManageRoles,
+		CompanyPermissionEditCompany,
+		CompanyPermissionDeleteCompany,
+		CompanyPermissionTransferOwnership,
+		CompanyPermissionManageSettings,
+		CompanyPermissionCreateEvents,
+		CompanyPermissionCreateIdeas,
+		CompanyPermissionModerateEvents,
+		CompanyPermissionModerateIdeas,
+	},
+	CompanyRoleAdmin: {
+		CompanyPermissionView,
+		CompanyPermissionInvite,
+		CompanyPermissionRemoveMembers,
+		CompanyPermissionManageJoinRequests,
+		CompanyPermissionEditCompany,
+		CompanyPermissionCreateEvents,
+		CompanyPermissionCreateIdeas,
+		CompanyPermissionModerateEvents,
+		CompanyPermissionModerateIdeas,
+	},
+	CompanyRoleMember: {
+		CompanyPermissionView,
+		CompanyPermissionInvite,
+		CompanyPermissionCreateEvents,
+		CompanyPermissionCreateIdeas,
+	},
+}
+
+// CompanyRoleAllows reports whether the role grants the permission.
+func CompanyRoleAllows(role string, permission CompanyPermission) bool {
+	for _, granted := range CompanyRolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// CompanyRoleRank orders roles by seniority; a member can only remove or
+// change the role of members ranked below them.
+func CompanyRoleRank(role string) int {
+	switch role {
+	case CompanyRoleOwner:
+		return 3
+	case CompanyRoleAdmin:
+		return 2
+	case CompanyRoleMember:
+		return 1
+	default:
+		return 0
+	}
+}
+
+type CompanyMemberRoleInput struct {
+	Role string `json:"role" binding:"required,oneof=admin member"`
+}
+
+type CompanyOwnershipTransferInput struct {
+	NewOwnerID int64 `json:"new_owner_id" binding:"required"`
+}
@@ -0,0 +1,84 @@
+package model
+
+import "time"
+
+// Who may create events or ideas in a company.
+const (
+	CompanyCreationEveryone = "everyone"
+	CompanyCreationAdmins   = "admins"
+)
+
+// CompanySettings is the per-company configuration. Owners edit it, every
+// member can read it.
+type CompanySettings struct {
+	CompanyID int64 `db:"company_id" json:"company_id"`
+	// Timezone is an IANA name used to render event and availability times.
+	Timezone string `db:"timezone" json:"timezone"`
+	// DefaultEventDurationMinutes sets the end of a new event created
+	// without one.
+	DefaultEventDurationMinutes int    `db:"default_event_duration_minutes" json:"default_event_duration_minutes"`
+	EventCreation               string `db:"event_creation" json:"event_creation"`
+	IdeaCreation                string `db:"idea_creation" json:"idea_creation"`
+	MembersCanInvite            bool   `db:"members_can_invite" json:"members_can_invite"`
+	// DefaultRSVPDeadlineMinutes closes attendance of new events this many
+	// minutes before the start; 0 means no deadline.
+	DefaultRSVPDeadlineMinutes int        `db:"default_rsvp_deadline_minutes" json:"default_rsvp_deadline_minutes"`
+	UpdatedAt                  *time.Time `db:"updated_at" json:"updated_at,omitempty"`
+}
+
+// DefaultCompanySettings are the settings of a company that has not
+// changed any.
+func DefaultCompanySettings(companyID int64) CompanySettings {
+	return CompanySettings{
+		CompanyID:                   companyID,
+		Timezone:                    "UTC",
+		DefaultEventDurationMinutes: 120,
+		EventCreation:               CompanyCreationEveryone,
+		IdeaCreation:                CompanyCreationEveryone,
+		MembersCanInvite:            true,
+	}
+}
+
+// Location returns the company timezone, or UTC if it cannot be loaded.
+func (s CompanySettings) Location() *time.Location {
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// CompanyPermissionConfigurable reports whether the settings can take the
+// permission away from regular members.
+func CompanyPermissionConfigurable(permission CompanyPermission) bool {
+	switch permission {
+	case CompanyPermissionCreateEvents, CompanyPermissionCreateIdeas, CompanyPermissionInvite:
+		return true
+	default:
+		return false
+	}
+}
+
+// MembersAllowed reports whether regular members keep a configurable
+// permission under these settings.
+func (s CompanySettings) MembersAllowed(permission CompanyPermission) bool {
+	switch permission {
+	case CompanyPermissionCreateEvents:
+		return s.EventCreation != CompanyCreationAdmins
+	case CompanyPermissionCreateIdeas:
+		return s.IdeaCreation != CompanyCreationAdmins
+	case CompanyPermissionInvite:
+		return s.MembersCanInvite
+	default:
+		return true
+	}
+}
+
+type CompanySettingsUpdateInput struct {
+	Timezone                    *string `json:"timezone"`
+	DefaultEventDurationMinutes *int    `json:"default_event_duration_minutes"`
+	EventCreation               *string `json:"event_creation" binding:"omitempty,oneof=everyone admins"`
+	IdeaCreation                *string `json:"idea_creation" binding:"omitempty,oneof=everyone admins"`
+	MembersCanInvite            *bool   `json:"members_can_invite"`
+	DefaultRSVPDeadlineMinutes  *int    `json:"default_rsvp_deadline_minutes"`
+}
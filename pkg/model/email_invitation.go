@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// CompanyEmailInvitation invites someone without an account by email. When
+// that email is registered it turns into a regular CompanyInvitation.
+type CompanyEmailInvitation struct {
+	ID         int64     `db:"id" json:"id"`
+	CompanyID  int64     `db:"company_id" json:"company_id"`
+	Email      string    `db:"email" json:"email"`
+	InvitedBy  int64     `db:"invited_by" json:"invited_by"`
+	ExpiresAt  time.Time `db:"expires_at" json:"expires_at"`
+	LastSentAt time.Time `db:"last_sent_at" json:"last_sent_at"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+type CompanyEmailInvitationInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// CompanyInvitationSender is what an invitation email says about the
+// company and the person inviting.
+type CompanyInvitationSender struct {
+	CompanyName   string
+	InviterName   string
+	InviterLocale string
+}
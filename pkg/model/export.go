@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// UserDataExport is everything stored about a user, as returned by
+// GET /auth/me/export.
+type UserDataExport struct {
+	Profile      UserProfile
+	Memberships  []CompanyMembershipExport
+	Events       []Event
+	RSVPs        []EventRSVPExport
+	Ideas        []Idea
+	Likes        []IdeaLikeExport
+	Availability []UserAvailability
+	Media        []MediaArchive
+}
+
+type CompanyMembershipExport struct {
+	CompanyID   int64     `db:"company_id" json:"company_id"`
+	CompanyName string    `db:"company_name" json:"company_name"`
+	Role        string    `db:"role" json:"role"`
+	JoinedAt    time.Time `db:"joined_at" json:"joined_at"`
+}
+
+type EventRSVPExport struct {
+	EventID    int64     `db:"event_id" json:"event_id"`
+	CompanyID  int64     `db:"company_id" json:"company_id"`
+	EventTitle string    `db:"event_title" json:"event_title"`
+	Status     string    `db:"status" json:"status"`
+	UpdatedAt  time.Time `db:"updated_at" json:"updated_at"`
+}
+
+type IdeaLikeExport struct {
+	IdeaID    int64     `db:"idea_id" json:"idea_id"`
+	CompanyID int64     `db:"company_id" json:"company_id"`
+	IdeaTitle string    `db:"idea_title" json:"idea_title"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// CompanyInviteLink lets anyone who has the code join the company until the
+// link expires, runs out of uses or is revoked.
+type CompanyInviteLink struct {
+	ID        int64      `db:"id" json:"id"`
+	CompanyID int64      `db:"company_id" json:"company_id"`
+	Code      string     `db:"code" json:"code"`
+	CreatedBy int64      `db:"created_by" json:"created_by"`
+	ExpiresAt *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	MaxUses   *int       `db:"max_uses" json:"max_uses,omitempty"`
+	Uses      int        `db:"uses" json:"uses"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+}
+
+type CompanyInviteLinkCreateInput struct {
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	MaxUses   *int       `json:"max_uses,omitempty" binding:"omitempty,min=1"`
+}
+
+// CompanyInvitePreview is what a user sees before joining by a link.
+type CompanyInvitePreview struct {
+	CompanyID     int64   `db:"company_id" json:"company_id"`
+	Name          string  `db:"name" json:"name"`
+	Description   *string `db:"description" json:"description,omitempty"`
+	AvatarURL     *string `db:"avatar_url" json:"avatar_url,omitempty"`
+	MemberCount   int     `db:"member_count" json:"member_count"`
+	AlreadyMember bool    `db:"already_member" json:"already_member"`
+}
@@ -0,0 +1,47 @@
+package model
+
+import "time"
+
+// Company visibility. Listed companies can be found through discovery and
+// accept join requests; private companies are invite-only.
+const (
+	CompanyVisibilityPrivate = "private"
+	CompanyVisibilityListed  = "listed"
+)
+
+// CompanyDiscoverView is a listed company as shown in discovery search.
+type CompanyDiscoverView struct {
+	ID          int64   `db:"id" json:"id"`
+	Name        string  `db:"name" json:"name"`
+	Description *string `db:"description" json:"description,omitempty"`
+	AvatarURL   *string `db:"avatar_url" json:"avatar_url,omitempty"`
+	MemberCount int     `db:"member_count" json:"member_count"`
+	IsMember    bool    `db:"is_member" json:"is_member"`
+	// JoinRequestStatus is the status of the current user's join request,
+	// if any.
+	JoinRequestStatus *string `db:"join_request_status" json:"join_request_status,omitempty"`
+}
+
+type CompanyJoinRequest struct {
+	ID          int64      `db:"id" json:"id"`
+	CompanyID   int64      `db:"company_id" json:"company_id"`
+	UserID      int64      `db:"user_id" json:"user_id"`
+	Message     *string    `db:"message" json:"message,omitempty"`
+	Status      string     `db:"status" json:"status"`
+	RespondedBy *int64     `db:"responded_by" json:"responded_by,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	RespondedAt *time.Time `db:"responded_at" json:"responded_at,omitempty"`
+}
+
+type CompanyJoinRequestView struct {
+	ID        int64     `db:"id" json:"id"`
+	UserID    int64     `db:"user_id" json:"user_id"`
+	Username  string    `db:"username" json:"username"`
+	AvatarURL *string   `db:"avatar_url" json:"avatar_url,omitempty"`
+	Message   *string   `db:"message" json:"message,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+type CompanyJoinRequestInput struct {
+	Message *string `json:"message" binding:"omitempty,max=500"`
+}
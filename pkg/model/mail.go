@@ -0,0 +1,48 @@
+package model
+
+// Locales emails are available in. Users without a preference get the
+// default one.
+const (
+	LocaleRU      = "ru"
+	LocaleEN      = "en"
+	DefaultLocale = LocaleRU
+)
+
+var SupportedLocales = []string{LocaleRU, LocaleEN}
+
+// EmailMessage is an email handed to a Mailer. When HTML is set the message
+// is sent as multipart/alternative with Text as the plain-text part.
+type EmailMessage struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Statuses of an email in the outbox. A dead email ran out of delivery
+// attempts and is kept for inspection.
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+	OutboxStatusDead    = "dead"
+)
+
+// OutboxEmail is a queued email claimed by the delivery worker. Attempts
+// counts the earlier failed deliveries.
+type OutboxEmail struct {
+	ID int64
+	EmailMessage
+	Attempts int
+}
+
+// EmailOutboxStats describes the outbox backlog and what the worker of this
+// process has done since it started.
+type EmailOutboxStats struct {
+	Pending             int   `json:"pending"`
+	Retrying            int   `json:"retrying"`
+	Dead                int   `json:"dead"`
+	OldestPendingAgeSec int   `json:"oldest_pending_age_sec"`
+	Delivered           int64 `json:"delivered"`
+	Failed              int64 `json:"failed"`
+	DeadLettered        int64 `json:"dead_lettered"`
+}
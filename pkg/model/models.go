@@ -2,19 +2,23 @@ package model
 
 import (
 	"encoding/json"
-	"net"
 	"time"
 )
 
 type User struct {
-	ID         int64     `db:"id" json:"id"`
-	Email      string    `db:"email" json:"email"`
-	TelegramID *int64    `db:"telegram_id" json:"telegram_id,omitempty"`
-	Username   string    `db:"username" json:"username"`
-	AvatarURL  *string   `db:"avatar_url" json:"avatar_url,omitempty"`
-	Password   string    `db:"password" json:"password"`
-	CreatedAt  time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt  time.Time `db:"updated_at" json:"updated_at"`
+	ID            int64      `db:"id" json:"id"`
+	Email         string     `db:"email" json:"email"`
+	TelegramID    *int64     `db:"telegram_id" json:"telegram_id,omitempty"`
+	Username      string     `db:"username" json:"username"`
+	FirstName     *string    `db:"first_name" json:"first_name,omitempty"`
+	SecondName    *string    `db:"second_name" json:"second_name,omitempty"`
+	AvatarURL     *string    `db:"avatar_url" json:"avatar_url,omitempty"`
+	Locale        string     `db:"locale" json:"locale"`
+	Password      string     `db:"password" json:"password"`
+	TOTPSecret    string     `db:"totp_secret" json:"-"`
+	TOTPEnabledAt *time.Time `db:"totp_enabled_at" json:"-"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time  `db:"updated_at" json:"updated_at"`
 }
 
 type PasswordResetToken struct {
@@ -26,13 +30,19 @@ type PasswordResetToken struct {
 }
 
 type Company struct {
-	ID          int64     `db:"id" json:"id"`
-	Name        string    `db:"name" json:"name"`
-	Description *string   `db:"description" json:"description,omitempty"`
-	AvatarURL   *string   `db:"avatar_url" json:"avatar_url,omitempty"`
-	CreatedBy   int64     `db:"created_by" json:"created_by"`
-	CreatedAt   time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+	ID          int64   `db:"id" json:"id"`
+	Name        string  `db:"name" json:"name"`
+	Description *string `db:"description" json:"description,omitempty"`
+	AvatarURL   *string `db:"avatar_url" json:"avatar_url,omitempty"`
+	// CreatedBy is the creator; it is empty once their account is deleted.
+	CreatedBy *int64 `db:"created_by" json:"created_by,omitempty"`
+	OwnerID   int64  `db:"owner_id" json:"owner_id"`
+	// Visibility is CompanyVisibilityPrivate or CompanyVisibilityListed.
+	Visibility string `db:"visibility" json:"visibility"`
+	// Role of the current user in the company.
+	Role      string    `db:"role" json:"role,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
 }
 
 type CompanyMember struct {
@@ -57,24 +67,43 @@ type CompanyInvitation struct {
 	InvitedBy     int64      `db:"invited_by" json:"invited_by"`
 	Status        string     `db:"status" json:"status"`
 	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	ExpiresAt     *time.Time `db:"expires_at" json:"expires_at,omitempty"`
 	RespondedAt   *time.Time `db:"responded_at" json:"responded_at,omitempty"`
 }
 
 type CompanyInvitationView struct {
-	ID                 int64     `db:"id" json:"id"`
-	CompanyID          int64     `db:"company_id" json:"company_id"`
-	CompanyName        string    `db:"company_name" json:"company_name"`
-	InvitedBy          int64     `db:"invited_by" json:"invited_by"`
-	InvitedByUsername  string    `db:"invited_by_username" json:"invited_by_username"`
-	InvitedByAvatarURL *string   `db:"invited_by_avatar_url" json:"invited_by_avatar_url,omitempty"`
-	Status             string    `db:"status" json:"status"`
-	CreatedAt          time.Time `db:"created_at" json:"created_at"`
+	ID                 int64      `db:"id" json:"id"`
+	CompanyID          int64      `db:"company_id" json:"company_id"`
+	CompanyName        string     `db:"company_name" json:"company_name"`
+	InvitedBy          int64      `db:"invited_by" json:"invited_by"`
+	InvitedByUsername  string     `db:"invited_by_username" json:"invited_by_username"`
+	InvitedByAvatarURL *string    `db:"invited_by_avatar_url" json:"invited_by_avatar_url,omitempty"`
+	Status             string     `db:"status" json:"status"`
+	CreatedAt          time.Time  `db:"created_at" json:"created_at"`
+	ExpiresAt          *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+}
+
+// CompanySentInvitationView is an invitation as seen by the company that
+// sent it. A pending invitation past its expiry is reported as expired even
+// before the expiry job has updated it.
+type CompanySentInvitationView struct {
+	ID                int64      `db:"id" json:"id"`
+	InvitedUserID     int64      `db:"invited_user_id" json:"invited_user_id"`
+	InvitedUsername   string     `db:"invited_username" json:"invited_username"`
+	InvitedAvatarURL  *string    `db:"invited_avatar_url" json:"invited_avatar_url,omitempty"`
+	InvitedBy         int64      `db:"invited_by" json:"invited_by"`
+	InvitedByUsername string     `db:"invited_by_username" json:"invited_by_username"`
+	Status            string     `db:"status" json:"status"`
+	CreatedAt         time.Time  `db:"created_at" json:"created_at"`
+	ExpiresAt         *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	RespondedAt       *time.Time `db:"responded_at" json:"responded_at,omitempty"`
 }
 
 type CompanyUpdateInput struct {
 	Name        *string `json:"name,omitempty"`
 	Description *string `json:"description,omitempty"`
 	AvatarURL   *string `json:"avatar_url,omitempty"`
+	Visibility  *string `json:"visibility,omitempty"`
 }
 
 type Event struct {
@@ -88,9 +117,11 @@ type Event struct {
 	EndTime     *time.Time `db:"end_time" json:"end_time,omitempty"`
 	PlaceName   *string    `db:"place_name" json:"place_name,omitempty"`
 	PlaceLink   *string    `db:"place_link" json:"place_link,omitempty"`
-	Status      string     `db:"status" json:"status"`
-	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
-	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
+	// RSVPDeadline is when attendance stops being editable, if ever.
+	RSVPDeadline *time.Time `db:"rsvp_deadline" json:"rsvp_deadline,omitempty"`
+	Status       string     `db:"status" json:"status"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time  `db:"updated_at" json:"updated_at"`
 }
 
 type EventParticipant struct {
@@ -182,17 +213,22 @@ type Notification struct {
 }
 
 type UserSession struct {
-	ID               int64     `db:"id" json:"id"`
-	UserID           int64     `db:"user_id" json:"user_id"`
-	RefreshTokenHash string    `db:"refresh_token_hash" json:"refresh_token_hash"`
-	UserAgent        *string   `db:"user_agent" json:"user_agent,omitempty"`
-	IPAddress        net.IP    `db:"ip_address" json:"ip_address,omitempty"`
-	ExpiresAt        time.Time `db:"expires_at" json:"expires_at"`
-	CreatedAt        time.Time `db:"created_at" json:"created_at"`
+	ID               int64      `db:"id" json:"id"`
+	UserID           int64      `db:"user_id" json:"user_id"`
+	FamilyID         string     `db:"family_id" json:"-"`
+	RefreshTokenHash string     `db:"refresh_token_hash" json:"-"`
+	UserAgent        *string    `db:"user_agent" json:"user_agent,omitempty"`
+	IPAddress        *string    `db:"ip_address" json:"ip_address,omitempty"`
+	ExpiresAt        time.Time  `db:"expires_at" json:"expires_at"`
+	RotatedAt        *time.Time `db:"rotated_at" json:"-"`
+	RevokedAt        *time.Time `db:"revoked_at" json:"-"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
 }
 
 type UpdateUserInput struct {
+	Username   *string `json:"username,omitempty"`
 	FirstName  *string `json:"first_name,omitempty"`
 	SecondName *string `json:"second_name,omitempty"`
-	Email      *string `json:"email,omitempty"`
+	Email      *string `json:"email,omitempty" binding:"omitempty,email"`
+	Locale     *string `json:"locale,omitempty" binding:"omitempty,oneof=ru en"`
 }
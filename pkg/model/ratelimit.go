@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+type RateLimitRule struct {
+	Limit  int
+	Window time.Duration
+}
+
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	Reset     time.Duration
+}
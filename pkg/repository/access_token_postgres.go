@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *AccessTokenPostgres) CreateAccessToken(token model.PersonalAccessToken) (model.PersonalAccessToken, error) {
+	query := `
+		INSERT INTO personal_access_tokens (user_id, name, token_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	err := r.pool.QueryRow(context.Background(), query,
+		token.UserID, token.Name, token.TokenHash, token.Scopes, token.ExpiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+	return token, err
+}
+
+func (r *AccessTokenPostgres) ListAccessTokens(userID int64) ([]model.PersonalAccessToken, error) {
+	query := `
+		SELECT id, user_id, name, scopes, expires_at, last_used_at, created_at
+		FROM personal_access_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+	rows, err := r.pool.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []model.PersonalAccessToken
+	for rows.Next() {
+		var token model.PersonalAccessToken
+		if err := rows.Scan(
+			&token.ID,
+			&token.UserID,
+			&token.Name,
+			&token.Scopes,
+			&token.ExpiresAt,
+			&token.LastUsedAt,
+			&token.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func (r *AccessTokenPostgres) DeleteAccessToken(userID int64, tokenID int64) error {
+	tag, err := r.pool.Exec(context.Background(), "DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2", tokenID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// UseAccessToken looks up an unexpired token of an active account and
+// records the use.
+func (r *AccessTokenPostgres) UseAccessToken(tokenHash string) (model.AccessTokenIdentity, error) {
+	query := `
+		UPDATE personal_access_tokens t
+		SET last_used_at = NOW()
+		FROM users u
+		WHERE t.token_hash = $1
+		  AND u.id = t.user_id
+		  AND u.deletion_requested_at IS NULL
+		  AND (t.expires_at IS NULL OR t.expires_at > NOW())
+		RETURNING t.user_id, t.scopes
+	`
+	var identity model.AccessTokenIdentity
+	err := r.pool.QueryRow(context.Background(), query, tokenHash).Scan(&identity.UserID, &identity.Scopes)
+	return identity, err
+}
@@ -0,0 +1,11 @@
+package repository
+
+import "github.com/jackc/pgx/v5/pgxpool"
+
+type AccessTokenPostgres struct {
+	pool *pgxpool.Pool
+}
+
+func NewAccessTokenRepository(pool *pgxpool.Pool) *AccessTokenPostgres {
+	return &AccessTokenPostgres{pool: pool}
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+)
+
+func (r *ActivityPostgres) CreateActivity(entry model.CompanyActivity) error {
+	_, err := r.pool.Exec(context.Background(), `
+		INSERT INTO activity (company_id, actor_id, type, subject_id, details)
+		VALUES ($1, $2, $3, $4, $5)
+	`, entry.CompanyID, entry.ActorID, entry.Type, entry.SubjectID, entry.Details)
+	return err
+}
+
+// CreateUserActivity records the entry in every company the actor is a
+// member of; CompanyID of the entry is ignored.
+func (r *ActivityPostgres) CreateUserActivity(entry model.CompanyActivity) error {
+	_, err := r.pool.Exec(context.Background(), `
+		INSERT INTO activity (company_id, actor_id, type, subject_id, details)
+		SELECT company_id, $1, $2, $3, $4
+		FROM company_members
+		WHERE user_id = $1
+	`, entry.ActorID, entry.Type, entry.SubjectID, entry.Details)
+	return err
+}
+
+// ListCompanyActivity returns the newest entries first. A non-zero beforeID
+// continues the listing after that entry, and a non-empty types keeps only
+// entries of those types.
+func (r *ActivityPostgres) ListCompanyActivity(companyID int64, userID int64, beforeID int64, types []string, limit int) ([]model.CompanyActivityView, error) {
+	ctx := context.Background()
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionView); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT a.id, a.type, a.actor_id, u.username, u.avatar_url, a.subject_id, a.details, a.created_at
+		FROM activity a
+		LEFT JOIN users u ON u.id = a.actor_id
+		WHERE a.company_id = $1
+		  AND ($2 = 0 OR a.id < $2)
+		  AND (cardinality($3::text[]) = 0 OR a.type = ANY($3))
+		ORDER BY a.id DESC
+		LIMIT $4
+	`
+	if types == nil {
+		types = []string{}
+	}
+	rows, err := r.pool.Query(ctx, query, companyID, beforeID, types, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []model.CompanyActivityView
+	for rows.Next() {
+		var entry model.CompanyActivityView
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.Type,
+			&entry.ActorID,
+			&entry.ActorUsername,
+			&entry.ActorAvatarURL,
+			&entry.SubjectID,
+			&entry.Details,
+			&entry.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
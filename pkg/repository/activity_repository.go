@@ -0,0 +1,11 @@
+package repository
+
+import "github.com/jackc/pgx/v5/pgxpool"
+
+type ActivityPostgres struct {
+	pool *pgxpool.Pool
+}
+
+func NewActivityRepository(pool *pgxpool.Pool) *ActivityPostgres {
+	return &ActivityPostgres{pool: pool}
+}
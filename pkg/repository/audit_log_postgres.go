@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+)
+
+func (r *AuditLogPostgres) CreateAuditLogEntry(entry model.AuthAuditEntry) error {
+	query := `
+		INSERT INTO auth_audit_log (user_id, event_type, outcome, ip_address, user_agent, details)
+		VALUES ($1, $2, $3, $4::inet, $5, $6)
+	`
+	_, err := r.pool.Exec(context.Background(), query,
+		entry.UserID,
+		entry.EventType,
+		entry.Outcome,
+		entry.IPAddress,
+		entry.UserAgent,
+		entry.Details,
+	)
+	return err
+}
+
+// ListUserAuditLog returns the newest entries of the user first. A non-zero
+// beforeID continues the listing after that entry.
+func (r *AuditLogPostgres) ListUserAuditLog(userID int64, beforeID int64, limit int) ([]model.AuthAuditEntry, error) {
+	query := `
+		SELECT id, user_id, event_type, outcome, host(ip_address), user_agent, details, created_at
+		FROM auth_audit_log
+		WHERE user_id = $1 AND ($2 = 0 OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3
+	`
+	rows, err := r.pool.Query(context.Background(), query, userID, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []model.AuthAuditEntry
+	for rows.Next() {
+		var entry model.AuthAuditEntry
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.UserID,
+			&entry.EventType,
+			&entry.Outcome,
+			&entry.IPAddress,
+			&entry.UserAgent,
+			&entry.Details,
+			&entry.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetSignInHistory checks the user's earlier successful sign-ins and
+// registrations against the given client.
+func (r *AuditLogPostgres) GetSignInHistory(userID int64, ipAddress *string, userAgent *string) (model.SignInHistory, error) {
+	query := `
+		SELECT
+			COUNT(*) > 0,
+			COALESCE(BOOL_OR(ip_address = $3::inet), FALSE),
+			COALESCE(BOOL_OR(user_agent = $4), FALSE)
+		FROM auth_audit_log
+		WHERE user_id = $1 AND event_type = ANY($2) AND outcome = 'success'
+	`
+	var history model.SignInHistory
+	err := r.pool.QueryRow(context.Background(), query,
+		userID,
+		[]string{model.AuditEventSignIn, model.AuditEventSignUp},
+		ipAddress,
+		userAgent,
+	).Scan(&history.HasSignIns, &history.KnownIP, &history.KnownDevice)
+	return history, err
+}
@@ -0,0 +1,11 @@
+package repository
+
+import "github.com/jackc/pgx/v5/pgxpool"
+
+type AuditLogPostgres struct {
+	pool *pgxpool.Pool
+}
+
+func NewAuditLogRepository(pool *pgxpool.Pool) *AuditLogPostgres {
+	return &AuditLogPostgres{pool: pool}
+}
@@ -3,6 +3,10 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/Sovpalo/sovpalo-backend/pkg/model"
 	"github.com/jackc/pgx/v5"
@@ -45,35 +49,291 @@ func (r *AuthPostgres) UsernameExists(username string) (bool, error) {
 
 func (r *AuthPostgres) CreateUser(user model.User) (int, error) {
 	var id int
-	query := "INSERT INTO users (username, email, password) VALUES ($1, $2, $3) RETURNING id"
-	row := r.pool.QueryRow(context.Background(), query, user.Username, user.Email, user.Password)
+	query := `
+		INSERT INTO users (username, email, password, telegram_id, avatar_url, locale)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5, COALESCE(NULLIF($6, ''), 'ru'))
+		RETURNING id
+	`
+	row := r.pool.QueryRow(context.Background(), query, user.Username, user.Email, user.Password, user.TelegramID, user.AvatarURL, user.Locale)
 	if err := row.Scan(&id); err != nil {
 		return 0, err
 	}
 	return id, nil
 }
 
-func (r *AuthPostgres) GetUser(email, password string) (model.User, error) {
+func (r *AuthPostgres) GetUserByEmail(email string) (model.User, error) {
 	var user model.User
-	query := "SELECT id FROM users WHERE email = $1 AND password = $2"
-	err := r.pool.QueryRow(context.Background(), query, email, password).Scan(&user.ID)
+	query := "SELECT id, email, username, locale, COALESCE(password, ''), totp_enabled_at FROM users WHERE email = $1"
+	err := r.pool.QueryRow(context.Background(), query, email).Scan(&user.ID, &user.Email, &user.Username, &user.Locale, &user.Password, &user.TOTPEnabledAt)
 	return user, err
 }
 
-func (r *AuthPostgres) GetUserByEmail(email string) (model.User, error) {
+func (r *AuthPostgres) GetUserByID(userID int64) (model.User, error) {
 	var user model.User
-	query := "SELECT id, email, username, password FROM users WHERE email = $1"
-	err := r.pool.QueryRow(context.Background(), query, email).Scan(&user.ID, &user.Email, &user.Username, &user.Password)
+	query := `
+		SELECT id, COALESCE(email, ''), username, first_name, second_name, avatar_url, telegram_id, locale, COALESCE(password, ''),
+		       COALESCE(totp_secret, ''), totp_enabled_at
+		FROM users
+		WHERE id = $1
+	`
+	err := r.pool.QueryRow(context.Background(), query, userID).Scan(
+		&user.ID,
+		&user.Email,
+		&user.Username,
+		&user.FirstName,
+		&user.SecondName,
+		&user.AvatarURL,
+		&user.TelegramID,
+		&user.Locale,
+		&user.Password,
+		&user.TOTPSecret,
+		&user.TOTPEnabledAt,
+	)
 	return user, err
 }
 
-func (r *AuthPostgres) GetUserByID(userID int64) (model.User, error) {
+func (r *AuthPostgres) UpdateUserProfile(userID int64, input model.UpdateUserInput) error {
+	setParts := make([]string, 0, 4)
+	args := make([]interface{}, 0, 5)
+	argID := 1
+
+	if input.Username != nil {
+		setParts = append(setParts, fmt.Sprintf("username = $%d", argID))
+		args = append(args, *input.Username)
+		argID++
+	}
+	if input.FirstName != nil {
+		setParts = append(setParts, fmt.Sprintf("first_name = NULLIF($%d, '')", argID))
+		args = append(args, *input.FirstName)
+		argID++
+	}
+	if input.SecondName != nil {
+		setParts = append(setParts, fmt.Sprintf("second_name = NULLIF($%d, '')", argID))
+		args = append(args, *input.SecondName)
+		argID++
+	}
+	if input.Locale != nil {
+		setParts = append(setParts, fmt.Sprintf("locale = $%d", argID))
+		args = append(args, *input.Locale)
+		argID++
+	}
+
+	if len(setParts) == 0 {
+		return errors.New("no fields to update")
+	}
+
+	setParts = append(setParts, "updated_at = NOW()")
+	query := fmt.Sprintf("UPDATE users SET %s WHERE id = $%d", strings.Join(setParts, ", "), argID)
+	args = append(args, userID)
+
+	tag, err := r.pool.Exec(context.Background(), query, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *AuthPostgres) UpdateUserEmail(userID int64, email string) error {
+	query := "UPDATE users SET email = $1, updated_at = NOW() WHERE id = $2"
+	tag, err := r.pool.Exec(context.Background(), query, email, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *AuthPostgres) GetUserByTelegramID(telegramID int64) (model.User, error) {
+	var user model.User
+	query := "SELECT id, COALESCE(email, ''), username, avatar_url, telegram_id, locale FROM users WHERE telegram_id = $1"
+	err := r.pool.QueryRow(context.Background(), query, telegramID).Scan(&user.ID, &user.Email, &user.Username, &user.AvatarURL, &user.TelegramID, &user.Locale)
+	return user, err
+}
+
+// GetUserByIdentity returns the user linked to the provider account and
+// records the sign-in on the identity.
+func (r *AuthPostgres) GetUserByIdentity(provider string, subject string) (model.User, error) {
 	var user model.User
-	query := "SELECT id, email, username, avatar_url FROM users WHERE id = $1"
-	err := r.pool.QueryRow(context.Background(), query, userID).Scan(&user.ID, &user.Email, &user.Username, &user.AvatarURL)
+	query := `
+		WITH identity AS (
+			UPDATE user_identities
+			SET last_sign_in_at = NOW()
+			WHERE provider = $1 AND subject = $2
+			RETURNING user_id
+		)
+		SELECT u.id, COALESCE(u.email, ''), u.username, u.locale, u.totp_enabled_at
+		FROM identity
+		JOIN users u ON u.id = identity.user_id
+	`
+	err := r.pool.QueryRow(context.Background(), query, provider, subject).Scan(&user.ID, &user.Email, &user.Username, &user.Locale, &user.TOTPEnabledAt)
 	return user, err
 }
 
+func (r *AuthPostgres) CreateUserIdentity(identity model.UserIdentity) error {
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email, last_sign_in_at)
+		VALUES ($1, $2, $3, $4, NOW())
+	`
+	_, err := r.pool.Exec(context.Background(), query, identity.UserID, identity.Provider, identity.Subject, identity.Email)
+	return err
+}
+
+// CreateUserWithIdentity creates an account for a first sign-in through an
+// external provider together with the identity linking them.
+func (r *AuthPostgres) CreateUserWithIdentity(user model.User, identity model.UserIdentity) (int64, error) {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int64
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO users (username, email, first_name, second_name, avatar_url, locale)
+		VALUES ($1, NULLIF($2, ''), $3, $4, $5, COALESCE(NULLIF($6, ''), 'ru'))
+		RETURNING id
+	`, user.Username, user.Email, user.FirstName, user.SecondName, user.AvatarURL, user.Locale).Scan(&userID); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO user_identities (user_id, provider, subject, email, last_sign_in_at)
+		VALUES ($1, $2, $3, $4, NOW())
+	`, userID, identity.Provider, identity.Subject, identity.Email); err != nil {
+		return 0, err
+	}
+
+	return userID, tx.Commit(ctx)
+}
+
+func (r *AuthPostgres) UpdateUserTelegramID(userID int64, telegramID *int64) error {
+	query := "UPDATE users SET telegram_id = $1, updated_at = NOW() WHERE id = $2"
+	tag, err := r.pool.Exec(context.Background(), query, telegramID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *AuthPostgres) GetTokensValidAfter(userID int64) (*time.Time, error) {
+	var validAfter *time.Time
+	query := "SELECT tokens_valid_after FROM users WHERE id = $1"
+	err := r.pool.QueryRow(context.Background(), query, userID).Scan(&validAfter)
+	return validAfter, err
+}
+
+func (r *AuthPostgres) SetTokensValidAfter(userID int64, validAfter time.Time) error {
+	query := "UPDATE users SET tokens_valid_after = $1, updated_at = NOW() WHERE id = $2"
+	tag, err := r.pool.Exec(context.Background(), query, validAfter, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteUserAccessTokens revokes all of the user's personal access tokens.
+func (r *AuthPostgres) DeleteUserAccessTokens(userID int64) error {
+	_, err := r.pool.Exec(context.Background(), "DELETE FROM personal_access_tokens WHERE user_id = $1", userID)
+	return err
+}
+
+// SetPendingTOTPSecret stores a secret that is not active until
+// EnableTOTP is called. It does nothing for users with TOTP already enabled.
+func (r *AuthPostgres) SetPendingTOTPSecret(userID int64, secret string) error {
+	query := "UPDATE users SET totp_secret = $1, updated_at = NOW() WHERE id = $2 AND totp_enabled_at IS NULL"
+	tag, err := r.pool.Exec(context.Background(), query, secret, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// EnableTOTP activates the pending secret and replaces the user's recovery codes.
+func (r *AuthPostgres) EnableTOTP(userID int64, recoveryCodeHashes []string) error {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE users
+		SET totp_enabled_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL
+	`, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM user_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	for _, codeHash := range recoveryCodeHashes {
+		if _, err := tx.Exec(ctx, "INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)", userID, codeHash); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *AuthPostgres) DisableTOTP(userID int64) error {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, updated_at = NOW() WHERE id = $1", userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM user_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UseRecoveryCode marks an unused recovery code as used and reports whether
+// one matched.
+func (r *AuthPostgres) UseRecoveryCode(userID int64, codeHash string) (bool, error) {
+	query := `
+		UPDATE user_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	tag, err := r.pool.Exec(context.Background(), query, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
 func (r *AuthPostgres) UpdateUserAvatar(userID int64, avatarURL *string) error {
 	query := "UPDATE users SET avatar_url = $1, updated_at = NOW() WHERE id = $2"
 	tag, err := r.pool.Exec(context.Background(), query, avatarURL, userID)
@@ -86,6 +346,60 @@ func (r *AuthPostgres) UpdateUserAvatar(userID int64, avatarURL *string) error {
 	return nil
 }
 
+// ScheduleUserDeletion marks the account for deletion and returns when it was
+// requested. Repeated requests keep the original time.
+func (r *AuthPostgres) ScheduleUserDeletion(userID int64) (time.Time, error) {
+	var requestedAt time.Time
+	query := `
+		UPDATE users
+		SET deletion_requested_at = COALESCE(deletion_requested_at, NOW()), updated_at = NOW()
+		WHERE id = $1
+		RETURNING deletion_requested_at
+	`
+	err := r.pool.QueryRow(context.Background(), query, userID).Scan(&requestedAt)
+	return requestedAt, err
+}
+
+// CancelUserDeletion clears a pending deletion request and reports whether
+// there was one.
+func (r *AuthPostgres) CancelUserDeletion(userID int64) (bool, error) {
+	query := "UPDATE users SET deletion_requested_at = NULL, updated_at = NOW() WHERE id = $1 AND deletion_requested_at IS NOT NULL"
+	tag, err := r.pool.Exec(context.Background(), query, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *AuthPostgres) ListUsersScheduledForDeletion(requestedBefore time.Time, afterID int64, limit int) ([]int64, error) {
+	query := `
+		SELECT id
+		FROM users
+		WHERE deletion_requested_at IS NOT NULL AND deletion_requested_at <= $1 AND id > $2
+		ORDER BY id
+		LIMIT $3
+	`
+	rows, err := r.pool.Query(context.Background(), query, requestedBefore, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// DeleteUser removes the user for good. Companies the user owns are handed
+// to the longest-standing remaining admin, then member, or deleted if
+// nobody else is left. Events, ideas and media the user created in companies that remain
+// are attributed to the company owner.
 func (r *AuthPostgres) DeleteUser(userID int64) error {
 	ctx := context.Background()
 	tx, err := r.pool.Begin(ctx)
@@ -94,10 +408,58 @@ func (r *AuthPostgres) DeleteUser(userID int64) error {
 	}
 	defer tx.Rollback(ctx)
 
-	if _, err := tx.Exec(ctx, "DELETE FROM companies WHERE created_by = $1", userID); err != nil {
+	rows, err := tx.Query(ctx, "SELECT id FROM companies WHERE owner_id = $1", userID)
+	if err != nil {
+		return err
+	}
+	var companyIDs []int64
+	for rows.Next() {
+		var companyID int64
+		if err := rows.Scan(&companyID); err != nil {
+			rows.Close()
+			return err
+		}
+		companyIDs = append(companyIDs, companyID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
 		return err
 	}
 
+	for _, companyID := range companyIDs {
+		var newOwnerID int64
+		err := tx.QueryRow(ctx, `
+			SELECT user_id
+			FROM company_members
+			WHERE company_id = $1 AND user_id <> $2
+			ORDER BY role = 'admin' DESC, joined_at, id
+			LIMIT 1
+		`, companyID, userID).Scan(&newOwnerID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			if _, err := tx.Exec(ctx, "DELETE FROM companies WHERE id = $1", companyID); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := setCompanyOwner(ctx, tx, companyID, userID, newOwnerID); err != nil {
+			return err
+		}
+	}
+
+	for _, query := range []string{
+		"UPDATE events e SET created_by = c.owner_id FROM companies c WHERE c.id = e.company_id AND e.created_by = $1",
+		"UPDATE ideas i SET created_by = c.owner_id FROM companies c WHERE c.id = i.company_id AND i.created_by = $1",
+		"UPDATE media_archive m SET uploaded_by = c.owner_id FROM companies c WHERE c.id = m.company_id AND m.uploaded_by = $1",
+	} {
+		if _, err := tx.Exec(ctx, query, userID); err != nil {
+			return err
+		}
+	}
+
 	tag, err := tx.Exec(ctx, "DELETE FROM users WHERE id = $1", userID)
 	if err != nil {
 		return err
@@ -17,10 +17,41 @@ import (
 const (
 	userExistsPrefix       = "user:exists:"
 	usernameExistsPrefix   = "user:username-exists:"
-	userLoginPrefix        = "user:login:"
+	tokensValidAfterPrefix = "user:tokens-valid-after:"
 	authChallengeKeyPrefix = "auth:challenge:"
+	authAttemptsKeyPrefix  = "auth:attempts:"
+	authCooldownKeyPrefix  = "auth:cooldown:"
+	twoFactorKeyPrefix     = "auth:2fa:"
+	oidcStateKeyPrefix     = "auth:oidc:"
 )
 
+// incrementChallengeAttemptsScript bumps the attempt counter inside the JSON
+// challenge record atomically and keeps the record's TTL.
+var incrementChallengeAttemptsScript = redis.NewScript(`
+local raw = redis.call('GET', KEYS[1])
+if not raw then
+	return -1
+end
+local challenge = cjson.decode(raw)
+challenge['attempts'] = (tonumber(challenge['attempts']) or 0) + 1
+redis.call('SET', KEYS[1], cjson.encode(challenge), 'KEEPTTL')
+return challenge['attempts']
+`)
+
+// incrementAuthAttemptsScript counts an attempt at the challenge in KEYS[1]
+// in the separate counter KEYS[2], which expires ARGV[1] milliseconds after
+// the first attempt.
+var incrementAuthAttemptsScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -1
+end
+local attempts = redis.call('INCR', KEYS[2])
+if attempts == 1 then
+	redis.call('PEXPIRE', KEYS[2], ARGV[1])
+end
+return attempts
+`)
+
 type AuthRepository struct {
 	postgres *AuthPostgres
 	cache    *redis.Client
@@ -95,49 +126,179 @@ func (r *AuthRepository) CreateUser(user model.User) (int, error) {
 		return 0, err
 	}
 
-	r.cacheUser(user, int64(id))
+	r.cacheUser(user)
+	return id, nil
+}
+
+func (r *AuthRepository) GetUserByEmail(email string) (model.User, error) {
+	return r.postgres.GetUserByEmail(email)
+}
+
+func (r *AuthRepository) GetUserByID(userID int64) (model.User, error) {
+	return r.postgres.GetUserByID(userID)
+}
+
+func (r *AuthRepository) GetUserByTelegramID(telegramID int64) (model.User, error) {
+	return r.postgres.GetUserByTelegramID(telegramID)
+}
+
+func (r *AuthRepository) GetUserByIdentity(provider string, subject string) (model.User, error) {
+	return r.postgres.GetUserByIdentity(provider, subject)
+}
+
+func (r *AuthRepository) CreateUserIdentity(identity model.UserIdentity) error {
+	return r.postgres.CreateUserIdentity(identity)
+}
+
+func (r *AuthRepository) CreateUserWithIdentity(user model.User, identity model.UserIdentity) (int64, error) {
+	id, err := r.postgres.CreateUserWithIdentity(user, identity)
+	if err != nil {
+		return 0, err
+	}
+
+	r.cacheUser(user)
 	return id, nil
 }
 
-func (r *AuthRepository) GetUser(email, password string) (model.User, error) {
-	key := fmt.Sprintf("%s%s:%s", userLoginPrefix, strings.ToLower(email), password)
+func (r *AuthRepository) ConvertEmailInvitations(userID int64, email string, declinedBefore time.Time) (int, error) {
+	return r.postgres.ConvertEmailInvitations(userID, email, declinedBefore)
+}
+
+func (r *AuthRepository) UpdateUserTelegramID(userID int64, telegramID *int64) error {
+	return r.postgres.UpdateUserTelegramID(userID, telegramID)
+}
+
+// UpdateUserProfile applies the profile changes and refreshes the cached
+// username lookups when the username changes.
+func (r *AuthRepository) UpdateUserProfile(userID int64, input model.UpdateUserInput) error {
+	user, err := r.postgres.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := r.postgres.UpdateUserProfile(userID, input); err != nil {
+		return err
+	}
+
+	if r.cache == nil || input.Username == nil || *input.Username == user.Username {
+		return nil
+	}
+
+	ctx := context.Background()
+	_ = r.cache.Del(ctx, usernameExistsPrefix+strings.ToLower(user.Username)).Err()
+	_ = r.cache.Set(ctx, usernameExistsPrefix+strings.ToLower(*input.Username), "1", r.cacheTTL).Err()
+	return nil
+}
+
+// UpdateUserEmail swaps the user's email and refreshes the cached email
+// lookups for both the old and the new address.
+func (r *AuthRepository) UpdateUserEmail(userID int64, email string) error {
+	user, err := r.postgres.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := r.postgres.UpdateUserEmail(userID, email); err != nil {
+		return err
+	}
+
+	if r.cache == nil {
+		return nil
+	}
+
+	ctx := context.Background()
+	if user.Email != "" {
+		_ = r.cache.Del(ctx, userExistsPrefix+strings.ToLower(user.Email)).Err()
+	}
+	_ = r.cache.Set(ctx, userExistsPrefix+strings.ToLower(email), "1", r.cacheTTL).Err()
+	return nil
+}
+
+// GetTokensValidAfter returns the moment before which the user's access
+// tokens are no longer accepted, or nil if none was set. It is read on every
+// authenticated request, so the value is cached.
+func (r *AuthRepository) GetTokensValidAfter(userID int64) (*time.Time, error) {
+	key := tokensValidAfterPrefix + strconv.FormatInt(userID, 10)
 	ctx := context.Background()
 
 	if r.cache != nil {
 		val, err := r.cache.Get(ctx, key).Result()
 		if err == nil {
-			id, convErr := strconv.Atoi(val)
-			if convErr != nil {
-				return model.User{}, convErr
+			unix, err := strconv.ParseInt(val, 10, 64)
+			if err == nil {
+				if unix == 0 {
+					return nil, nil
+				}
+				validAfter := time.Unix(unix, 0)
+				return &validAfter, nil
 			}
-			return model.User{ID: int64(id)}, nil
 		}
 	}
 
-	user, err := r.postgres.GetUser(email, password)
+	validAfter, err := r.postgres.GetTokensValidAfter(userID)
 	if err != nil {
-		return model.User{}, err
+		return nil, err
 	}
 
-	if r.cache != nil && user.ID != 0 {
-		_ = r.cache.Set(ctx, key, strconv.FormatInt(user.ID, 10), r.cacheTTL).Err()
+	if r.cache != nil {
+		value := "0"
+		if validAfter != nil {
+			value = strconv.FormatInt(validAfter.Unix(), 10)
+		}
+		_ = r.cache.Set(ctx, key, value, r.cacheTTL).Err()
 	}
 
-	return user, nil
+	return validAfter, nil
 }
 
-func (r *AuthRepository) GetUserByEmail(email string) (model.User, error) {
-	return r.postgres.GetUserByEmail(email)
+func (r *AuthRepository) SetTokensValidAfter(userID int64, validAfter time.Time) error {
+	if err := r.postgres.SetTokensValidAfter(userID, validAfter); err != nil {
+		return err
+	}
+
+	if r.cache != nil {
+		key := tokensValidAfterPrefix + strconv.FormatInt(userID, 10)
+		_ = r.cache.Set(context.Background(), key, strconv.FormatInt(validAfter.Unix(), 10), r.cacheTTL).Err()
+	}
+	return nil
 }
 
-func (r *AuthRepository) GetUserByID(userID int64) (model.User, error) {
-	return r.postgres.GetUserByID(userID)
+func (r *AuthRepository) DeleteUserAccessTokens(userID int64) error {
+	return r.postgres.DeleteUserAccessTokens(userID)
+}
+
+func (r *AuthRepository) SetPendingTOTPSecret(userID int64, secret string) error {
+	return r.postgres.SetPendingTOTPSecret(userID, secret)
+}
+
+func (r *AuthRepository) EnableTOTP(userID int64, recoveryCodeHashes []string) error {
+	return r.postgres.EnableTOTP(userID, recoveryCodeHashes)
+}
+
+func (r *AuthRepository) DisableTOTP(userID int64) error {
+	return r.postgres.DisableTOTP(userID)
+}
+
+func (r *AuthRepository) UseRecoveryCode(userID int64, codeHash string) (bool, error) {
+	return r.postgres.UseRecoveryCode(userID, codeHash)
 }
 
 func (r *AuthRepository) UpdateUserAvatar(userID int64, avatarURL *string) error {
 	return r.postgres.UpdateUserAvatar(userID, avatarURL)
 }
 
+func (r *AuthRepository) ScheduleUserDeletion(userID int64) (time.Time, error) {
+	return r.postgres.ScheduleUserDeletion(userID)
+}
+
+func (r *AuthRepository) CancelUserDeletion(userID int64) (bool, error) {
+	return r.postgres.CancelUserDeletion(userID)
+}
+
+func (r *AuthRepository) ListUsersScheduledForDeletion(requestedBefore time.Time, afterID int64, limit int) ([]int64, error) {
+	return r.postgres.ListUsersScheduledForDeletion(requestedBefore, afterID, limit)
+}
+
 func (r *AuthRepository) DeleteUser(userID int64) error {
 	user, err := r.postgres.GetUserByID(userID)
 	if err != nil {
@@ -156,20 +317,13 @@ func (r *AuthRepository) DeleteUser(userID int64) error {
 	_ = r.cache.Del(ctx,
 		userExistsPrefix+strings.ToLower(user.Email),
 		usernameExistsPrefix+strings.ToLower(user.Username),
+		tokensValidAfterPrefix+strconv.FormatInt(userID, 10),
 	).Err()
 
-	loginPattern := fmt.Sprintf("%s%s:*", userLoginPrefix, strings.ToLower(user.Email))
-	loginIter := r.cache.Scan(ctx, 0, loginPattern, 100).Iterator()
-	for loginIter.Next(ctx) {
-		_ = r.cache.Del(ctx, loginIter.Val()).Err()
-	}
-	if loginIter.Err() != nil {
-		return loginIter.Err()
-	}
-
 	for _, challengeType := range []model.AuthChallengeType{
 		model.AuthChallengeTypeSignUp,
 		model.AuthChallengeTypePasswordReset,
+		model.AuthChallengeTypeLogin,
 	} {
 		_ = r.cache.Del(ctx, authChallengeKey(challengeType, user.Email)).Err()
 	}
@@ -178,35 +332,7 @@ func (r *AuthRepository) DeleteUser(userID int64) error {
 }
 
 func (r *AuthRepository) UpdateUserPassword(email string, passwordHash string) error {
-	user, err := r.postgres.GetUserByEmail(email)
-	if err != nil {
-		return err
-	}
-
-	if err := r.postgres.UpdateUserPassword(email, passwordHash); err != nil {
-		return err
-	}
-
-	if r.cache != nil {
-		ctx := context.Background()
-		pattern := fmt.Sprintf("%s%s:*", userLoginPrefix, strings.ToLower(email))
-		iter := r.cache.Scan(ctx, 0, pattern, 100).Iterator()
-		for iter.Next(ctx) {
-			_ = r.cache.Del(ctx, iter.Val()).Err()
-		}
-		if iter.Err() != nil {
-			return iter.Err()
-		}
-	}
-
-	r.cacheUser(model.User{
-		ID:       user.ID,
-		Email:    user.Email,
-		Username: user.Username,
-		Password: passwordHash,
-	}, user.ID)
-
-	return nil
+	return r.postgres.UpdateUserPassword(email, passwordHash)
 }
 
 func (r *AuthRepository) SavePendingAuthChallenge(challenge model.PendingAuthChallenge, ttl time.Duration) error {
@@ -219,8 +345,14 @@ func (r *AuthRepository) SavePendingAuthChallenge(challenge model.PendingAuthCha
 		return err
 	}
 
-	key := authChallengeKey(challenge.Type, challenge.Email)
-	return r.cache.Set(context.Background(), key, payload, ttl).Err()
+	// a new code comes with a fresh set of attempts
+	subject := challenge.Subject()
+	_, err = r.cache.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.Set(context.Background(), authChallengeKey(challenge.Type, subject), payload, ttl)
+		pipe.Del(context.Background(), authAttemptsKey(challenge.Type, subject))
+		return nil
+	})
+	return err
 }
 
 func (r *AuthRepository) GetPendingAuthChallenge(challengeType model.AuthChallengeType, email string) (model.PendingAuthChallenge, error) {
@@ -251,20 +383,150 @@ func (r *AuthRepository) DeletePendingAuthChallenge(challengeType model.AuthChal
 	return r.cache.Del(context.Background(), key).Err()
 }
 
-func (r *AuthRepository) cacheUser(user model.User, id int64) {
+// IncrementAuthChallengeAttempts counts an attempt at the pending challenge
+// and returns the number of attempts made within window. Issuing a new code
+// resets the count.
+func (r *AuthRepository) IncrementAuthChallengeAttempts(challengeType model.AuthChallengeType, email string, window time.Duration) (int, error) {
+	if r.cache == nil {
+		return 0, errors.New("auth challenge storage unavailable")
+	}
+
+	keys := []string{authChallengeKey(challengeType, email), authAttemptsKey(challengeType, email)}
+	attempts, err := incrementAuthAttemptsScript.Run(context.Background(), r.cache, keys, window.Milliseconds()).Int()
+	if err != nil {
+		return 0, err
+	}
+	if attempts < 0 {
+		return 0, redis.Nil
+	}
+	return attempts, nil
+}
+
+// ResetAuthChallengeAttempts forgets the attempts after a successful
+// verification.
+func (r *AuthRepository) ResetAuthChallengeAttempts(challengeType model.AuthChallengeType, email string) error {
+	if r.cache == nil {
+		return errors.New("auth challenge storage unavailable")
+	}
+
+	return r.cache.Del(context.Background(), authAttemptsKey(challengeType, email)).Err()
+}
+
+func (r *AuthRepository) SaveTwoFactorChallenge(tokenHash string, challenge model.TwoFactorChallenge, ttl time.Duration) error {
+	if r.cache == nil {
+		return errors.New("auth challenge storage unavailable")
+	}
+
+	payload, err := json.Marshal(challenge)
+	if err != nil {
+		return err
+	}
+
+	return r.cache.Set(context.Background(), twoFactorKeyPrefix+tokenHash, payload, ttl).Err()
+}
+
+func (r *AuthRepository) GetTwoFactorChallenge(tokenHash string) (model.TwoFactorChallenge, error) {
+	if r.cache == nil {
+		return model.TwoFactorChallenge{}, errors.New("auth challenge storage unavailable")
+	}
+
+	val, err := r.cache.Get(context.Background(), twoFactorKeyPrefix+tokenHash).Result()
+	if err != nil {
+		return model.TwoFactorChallenge{}, err
+	}
+
+	var challenge model.TwoFactorChallenge
+	if err := json.Unmarshal([]byte(val), &challenge); err != nil {
+		return model.TwoFactorChallenge{}, err
+	}
+
+	return challenge, nil
+}
+
+func (r *AuthRepository) DeleteTwoFactorChallenge(tokenHash string) error {
+	if r.cache == nil {
+		return errors.New("auth challenge storage unavailable")
+	}
+
+	return r.cache.Del(context.Background(), twoFactorKeyPrefix+tokenHash).Err()
+}
+
+func (r *AuthRepository) IncrementTwoFactorChallengeAttempts(tokenHash string) (int, error) {
+	if r.cache == nil {
+		return 0, errors.New("auth challenge storage unavailable")
+	}
+
+	attempts, err := incrementChallengeAttemptsScript.Run(context.Background(), r.cache, []string{twoFactorKeyPrefix + tokenHash}).Int()
+	if err != nil {
+		return 0, err
+	}
+	if attempts < 0 {
+		return 0, redis.Nil
+	}
+	return attempts, nil
+}
+
+func (r *AuthRepository) SaveOIDCState(stateHash string, state model.OIDCAuthState, ttl time.Duration) error {
+	if r.cache == nil {
+		return errors.New("auth challenge storage unavailable")
+	}
+
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return r.cache.Set(context.Background(), oidcStateKeyPrefix+stateHash, payload, ttl).Err()
+}
+
+// TakeOIDCState returns the stored state and deletes it, so every state is
+// accepted by the callback only once.
+func (r *AuthRepository) TakeOIDCState(stateHash string) (model.OIDCAuthState, error) {
+	if r.cache == nil {
+		return model.OIDCAuthState{}, errors.New("auth challenge storage unavailable")
+	}
+
+	val, err := r.cache.GetDel(context.Background(), oidcStateKeyPrefix+stateHash).Result()
+	if err != nil {
+		return model.OIDCAuthState{}, err
+	}
+
+	var state model.OIDCAuthState
+	if err := json.Unmarshal([]byte(val), &state); err != nil {
+		return model.OIDCAuthState{}, err
+	}
+
+	return state, nil
+}
+
+// AcquireAuthCooldown reports whether the cooldown identified by key was free
+// and, if so, holds it for ttl.
+func (r *AuthRepository) AcquireAuthCooldown(key string, ttl time.Duration) (bool, error) {
+	if r.cache == nil {
+		return false, errors.New("auth challenge storage unavailable")
+	}
+
+	return r.cache.SetNX(context.Background(), authCooldownKeyPrefix+strings.ToLower(key), "1", ttl).Result()
+}
+
+func (r *AuthRepository) cacheUser(user model.User) {
 	if r.cache == nil {
 		return
 	}
 
 	ctx := context.Background()
-	existsKey := userExistsPrefix + strings.ToLower(user.Email)
+	if user.Email != "" {
+		existsKey := userExistsPrefix + strings.ToLower(user.Email)
+		_ = r.cache.Set(ctx, existsKey, "1", r.cacheTTL).Err()
+	}
 	usernameKey := usernameExistsPrefix + strings.ToLower(user.Username)
-	loginKey := fmt.Sprintf("%s%s:%s", userLoginPrefix, strings.ToLower(user.Email), user.Password)
-	_ = r.cache.Set(ctx, existsKey, "1", r.cacheTTL).Err()
 	_ = r.cache.Set(ctx, usernameKey, "1", r.cacheTTL).Err()
-	_ = r.cache.Set(ctx, loginKey, strconv.FormatInt(id, 10), r.cacheTTL).Err()
 }
 
 func authChallengeKey(challengeType model.AuthChallengeType, email string) string {
 	return fmt.Sprintf("%s%s:%s", authChallengeKeyPrefix, challengeType, strings.ToLower(email))
 }
+
+func authAttemptsKey(challengeType model.AuthChallengeType, email string) string {
+	return fmt.Sprintf("%s%s:%s", authAttemptsKeyPrefix, challengeType, strings.ToLower(email))
+}
@@ -2,7 +2,6 @@ package repository
 
 import (
 	"context"
-	"errors"
 	"time"
 
 	"github.com/Sovpalo/sovpalo-backend/pkg/model"
@@ -12,16 +11,9 @@ import (
 func (r *AvailabilityPostgres) CreateAvailability(companyID int64, userID int64, input model.AvailabilityCreateInput) (int64, error) {
 	ctx := context.Background()
 
-	var isMember bool
-	if err := r.pool.QueryRow(ctx,
-		"SELECT EXISTS (SELECT 1 FROM company_members WHERE company_id = $1 AND user_id = $2)",
-		companyID, userID,
-	).Scan(&isMember); err != nil {
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionView); err != nil {
 		return 0, err
 	}
-	if !isMember {
-		return 0, errors.New("user is not a member of the company")
-	}
 
 	query := `
 		INSERT INTO user_availability (user_id, company_id, start_time, end_time, note)
@@ -38,16 +30,9 @@ func (r *AvailabilityPostgres) CreateAvailability(companyID int64, userID int64,
 func (r *AvailabilityPostgres) ListAvailability(companyID int64, userID int64) ([]model.UserAvailability, error) {
 	ctx := context.Background()
 
-	var isMember bool
-	if err := r.pool.QueryRow(ctx,
-		"SELECT EXISTS (SELECT 1 FROM company_members WHERE company_id = $1 AND user_id = $2)",
-		companyID, userID,
-	).Scan(&isMember); err != nil {
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionView); err != nil {
 		return nil, err
 	}
-	if !isMember {
-		return nil, errors.New("user is not a member of the company")
-	}
 
 	query := `
 		SELECT ua.id, ua.user_id, u.avatar_url, ua.company_id, ua.start_time, ua.end_time, ua.note, ua.created_at, ua.updated_at
@@ -87,16 +72,9 @@ func (r *AvailabilityPostgres) ListAvailability(companyID int64, userID int64) (
 func (r *AvailabilityPostgres) ListCompanyAvailability(companyID int64, userID int64) ([]model.UserAvailability, error) {
 	ctx := context.Background()
 
-	var isMember bool
-	if err := r.pool.QueryRow(ctx,
-		"SELECT EXISTS (SELECT 1 FROM company_members WHERE company_id = $1 AND user_id = $2)",
-		companyID, userID,
-	).Scan(&isMember); err != nil {
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionView); err != nil {
 		return nil, err
 	}
-	if !isMember {
-		return nil, errors.New("user is not a member of the company")
-	}
 
 	query := `
 		SELECT ua.id, ua.user_id, u.avatar_url, ua.company_id, ua.start_time, ua.end_time, ua.note, ua.created_at, ua.updated_at
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotCompanyMember        = errors.New("user is not a member of the company")
+	ErrCompanyPermissionDenied = errors.New("not enough permissions in the company")
+	ErrAlreadyCompanyMember    = errors.New("user already in company")
+	ErrNewOwnerIsCurrentOwner  = errors.New("new owner must be another company member")
+	ErrNewOwnerNotMember       = errors.New("new owner must be a member of the company")
+)
+
+// queryRower is implemented by both the pool and a transaction, so access
+// can be checked inside the transaction that performs the change.
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// companyRole returns the role of the user in the company, or
+// ErrNotCompanyMember.
+func companyRole(ctx context.Context, db queryRower, companyID int64, userID int64) (string, error) {
+	var role string
+	err := db.QueryRow(ctx,
+		"SELECT role FROM company_members WHERE company_id = $1 AND user_id = $2",
+		companyID, userID,
+	).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrNotCompanyMember
+	}
+	if err != nil {
+		return "", err
+	}
+	return role, nil
+}
+
+// requireCompanyPermission is the single access check for company data: it
+// returns the user's role when the role grants the permission according to
+// model.CompanyRolePermissions. The company settings can additionally take
+// configurable permissions away from regular members.
+func requireCompanyPermission(ctx context.Context, db queryRower, companyID int64, userID int64, permission model.CompanyPermission) (string, error) {
+	role, err := companyRole(ctx, db, companyID, userID)
+	if err != nil {
+		return "", err
+	}
+	if !model.CompanyRoleAllows(role, permission) {
+		return "", ErrCompanyPermissionDenied
+	}
+
+	if role == model.CompanyRoleMember && model.CompanyPermissionConfigurable(permission) {
+		settings, err := companySettings(ctx, db, companyID)
+		if err != nil {
+			return "", err
+		}
+		if !settings.MembersAllowed(permission) {
+			return "", ErrCompanyPermissionDenied
+		}
+	}
+	return role, nil
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/Sovpalo/sovpalo-backend/pkg/model"
 	"github.com/jackc/pgx/v5"
@@ -20,13 +21,13 @@ func (r *CompanyPostgres) CreateCompany(company model.Company) (int64, error) {
 	defer tx.Rollback(ctx)
 
 	var id int64
-	query := "INSERT INTO companies (name, description, avatar_url, created_by) VALUES ($1, $2, $3, $4) RETURNING id"
-	if err := tx.QueryRow(ctx, query, company.Name, company.Description, company.AvatarURL, company.CreatedBy).Scan(&id); err != nil {
+	query := "INSERT INTO companies (name, description, avatar_url, created_by, owner_id) VALUES ($1, $2, $3, $4, $5) RETURNING id"
+	if err := tx.QueryRow(ctx, query, company.Name, company.Description, company.AvatarURL, company.CreatedBy, company.OwnerID).Scan(&id); err != nil {
 		return 0, err
 	}
 
 	memberQuery := "INSERT INTO company_members (company_id, user_id, role) VALUES ($1, $2, 'owner')"
-	if _, err := tx.Exec(ctx, memberQuery, id, company.CreatedBy); err != nil {
+	if _, err := tx.Exec(ctx, memberQuery, id, company.OwnerID); err != nil {
 		return 0, err
 	}
 
@@ -41,7 +42,7 @@ func (r *CompanyPostgres) GetCompany(companyID int64, userID int64) (model.Compa
 	ctx := context.Background()
 	var company model.Company
 	query := `
-		SELECT c.id, c.name, c.description, c.avatar_url, c.created_by, c.created_at, c.updated_at
+		SELECT c.id, c.name, c.description, c.avatar_url, c.created_by, c.owner_id, c.visibility, cm.role, c.created_at, c.updated_at
 		FROM companies c
 		JOIN company_members cm ON cm.company_id = c.id
 		WHERE c.id = $1 AND cm.user_id = $2
@@ -52,6 +53,9 @@ func (r *CompanyPostgres) GetCompany(companyID int64, userID int64) (model.Compa
 		&company.Description,
 		&company.AvatarURL,
 		&company.CreatedBy,
+		&company.OwnerID,
+		&company.Visibility,
+		&company.Role,
 		&company.CreatedAt,
 		&company.UpdatedAt,
 	)
@@ -64,7 +68,7 @@ func (r *CompanyPostgres) GetCompany(companyID int64, userID int64) (model.Compa
 func (r *CompanyPostgres) ListCompanies(userID int64) ([]model.Company, error) {
 	ctx := context.Background()
 	query := `
-		SELECT c.id, c.name, c.description, c.avatar_url, c.created_by, c.created_at, c.updated_at
+		SELECT c.id, c.name, c.description, c.avatar_url, c.created_by, c.owner_id, c.visibility, cm.role, c.created_at, c.updated_at
 		FROM companies c
 		JOIN company_members cm ON cm.company_id = c.id
 		WHERE cm.user_id = $1
@@ -85,6 +89,9 @@ func (r *CompanyPostgres) ListCompanies(userID int64) ([]model.Company, error) {
 			&company.Description,
 			&company.AvatarURL,
 			&company.CreatedBy,
+			&company.OwnerID,
+			&company.Visibility,
+			&company.Role,
 			&company.CreatedAt,
 			&company.UpdatedAt,
 		); err != nil {
@@ -97,8 +104,12 @@ func (r *CompanyPostgres) ListCompanies(userID int64) ([]model.Company, error) {
 
 func (r *CompanyPostgres) UpdateCompany(companyID int64, userID int64, input model.CompanyUpdateInput) error {
 	ctx := context.Background()
-	setParts := make([]string, 0, 4)
-	args := make([]interface{}, 0, 4)
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionEditCompany); err != nil {
+		return err
+	}
+
+	setParts := make([]string, 0, 5)
+	args := make([]interface{}, 0, 5)
 	argID := 1
 
 	if input.Name != nil {
@@ -116,6 +127,11 @@ func (r *CompanyPostgres) UpdateCompany(companyID int64, userID int64, input mod
 		args = append(args, *input.AvatarURL)
 		argID++
 	}
+	if input.Visibility != nil {
+		setParts = append(setParts, fmt.Sprintf("visibility = $%d", argID))
+		args = append(args, *input.Visibility)
+		argID++
+	}
 
 	if len(setParts) == 0 {
 		return errors.New("no fields to update")
@@ -123,12 +139,11 @@ func (r *CompanyPostgres) UpdateCompany(companyID int64, userID int64, input mod
 
 	setParts = append(setParts, "updated_at = NOW()")
 	query := fmt.Sprintf(
-		"UPDATE companies SET %s WHERE id = $%d AND created_by = $%d",
+		"UPDATE companies SET %s WHERE id = $%d",
 		strings.Join(setParts, ", "),
 		argID,
-		argID+1,
 	)
-	args = append(args, companyID, userID)
+	args = append(args, companyID)
 
 	tag, err := r.pool.Exec(ctx, query, args...)
 	if err != nil {
@@ -142,8 +157,11 @@ func (r *CompanyPostgres) UpdateCompany(companyID int64, userID int64, input mod
 
 func (r *CompanyPostgres) DeleteCompany(companyID int64, userID int64) error {
 	ctx := context.Background()
-	query := "DELETE FROM companies WHERE id = $1 AND created_by = $2"
-	tag, err := r.pool.Exec(ctx, query, companyID, userID)
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionDeleteCompany); err != nil {
+		return err
+	}
+
+	tag, err := r.pool.Exec(ctx, "DELETE FROM companies WHERE id = $1", companyID)
 	if err != nil {
 		return err
 	}
@@ -161,51 +179,19 @@ func (r *CompanyPostgres) LeaveCompany(companyID int64, userID int64, newOwnerID
 	}
 	defer tx.Rollback(ctx)
 
-	var creatorID int64
-	if err := tx.QueryRow(ctx, "SELECT created_by FROM companies WHERE id = $1", companyID).Scan(&creatorID); err != nil {
-		if err == pgx.ErrNoRows {
-			return errors.New("company not found")
-		}
-		return err
-	}
-
-	var isMember bool
-	if err := tx.QueryRow(ctx,
-		"SELECT EXISTS (SELECT 1 FROM company_members WHERE company_id = $1 AND user_id = $2)",
-		companyID, userID,
-	).Scan(&isMember); err != nil {
+	role, err := companyRole(ctx, tx, companyID, userID)
+	if err != nil {
 		return err
 	}
-	if !isMember {
-		return errors.New("user is not a member of the company")
-	}
 
-	if creatorID == userID {
+	if role == model.CompanyRoleOwner {
 		if newOwnerID == nil {
 			return errors.New("owner must appoint a new owner before leaving the company")
 		}
-		if *newOwnerID == userID {
-			return errors.New("new owner must be another company member")
-		}
-
-		var newOwnerIsMember bool
-		if err := tx.QueryRow(ctx,
-			"SELECT EXISTS (SELECT 1 FROM company_members WHERE company_id = $1 AND user_id = $2)",
-			companyID, *newOwnerID,
-		).Scan(&newOwnerIsMember); err != nil {
-			return err
-		}
-		if !newOwnerIsMember {
-			return errors.New("new owner must be a member of the company")
-		}
-
-		if _, err := tx.Exec(ctx, "UPDATE companies SET created_by = $1, updated_at = NOW() WHERE id = $2", *newOwnerID, companyID); err != nil {
-			return err
-		}
-		if _, err := tx.Exec(ctx, "UPDATE company_members SET role = 'owner' WHERE company_id = $1 AND user_id = $2", companyID, *newOwnerID); err != nil {
+		if err := requireNewOwnerCandidate(ctx, tx, companyID, userID, *newOwnerID); err != nil {
 			return err
 		}
-		if _, err := tx.Exec(ctx, "UPDATE company_members SET role = 'member' WHERE company_id = $1 AND user_id = $2", companyID, userID); err != nil {
+		if err := setCompanyOwner(ctx, tx, companyID, userID, *newOwnerID); err != nil {
 			return err
 		}
 	} else if newOwnerID != nil {
@@ -230,23 +216,68 @@ func (r *CompanyPostgres) LeaveCompany(companyID int64, userID int64, newOwnerID
 	return nil
 }
 
-func (r *CompanyPostgres) CreateInvitation(companyID int64, invitedBy int64, username string) (model.CompanyInvitation, error) {
+// TransferOwnership hands the company to another member; the previous owner
+// stays in the company as a regular member.
+func (r *CompanyPostgres) TransferOwnership(companyID int64, userID int64, newOwnerID int64) error {
 	ctx := context.Background()
 	tx, err := r.pool.Begin(ctx)
 	if err != nil {
-		return model.CompanyInvitation{}, err
+		return err
 	}
 	defer tx.Rollback(ctx)
 
-	var isMember bool
-	if err := tx.QueryRow(ctx,
-		"SELECT EXISTS (SELECT 1 FROM company_members WHERE company_id = $1 AND user_id = $2)",
-		companyID, invitedBy,
-	).Scan(&isMember); err != nil {
+	if _, err := requireCompanyPermission(ctx, tx, companyID, userID, model.CompanyPermissionTransferOwnership); err != nil {
+		return err
+	}
+	if err := requireNewOwnerCandidate(ctx, tx, companyID, userID, newOwnerID); err != nil {
+		return err
+	}
+	if err := setCompanyOwner(ctx, tx, companyID, userID, newOwnerID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func requireNewOwnerCandidate(ctx context.Context, tx pgx.Tx, companyID int64, ownerID int64, newOwnerID int64) error {
+	if newOwnerID == ownerID {
+		return ErrNewOwnerIsCurrentOwner
+	}
+	if _, err := companyRole(ctx, tx, companyID, newOwnerID); err != nil {
+		if errors.Is(err, ErrNotCompanyMember) {
+			return ErrNewOwnerNotMember
+		}
+		return err
+	}
+	return nil
+}
+
+// setCompanyOwner is the only place that changes the owner: it updates
+// companies.owner_id and moves the owner role, demoting the previous owner
+// first because a company can have only one.
+func setCompanyOwner(ctx context.Context, tx pgx.Tx, companyID int64, ownerID int64, newOwnerID int64) error {
+	if _, err := tx.Exec(ctx, "UPDATE companies SET owner_id = $1, updated_at = NOW() WHERE id = $2", newOwnerID, companyID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "UPDATE company_members SET role = 'member' WHERE company_id = $1 AND user_id = $2", companyID, ownerID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, "UPDATE company_members SET role = 'owner' WHERE company_id = $1 AND user_id = $2", companyID, newOwnerID)
+	return err
+}
+
+// CreateInvitation invites the user until expiresAt. An earlier invitation of
+// the same user is reused unless it is still pending, or it was declined
+// after declinedBefore.
+func (r *CompanyPostgres) CreateInvitation(companyID int64, invitedBy int64, username string, expiresAt time.Time, declinedBefore time.Time) (model.CompanyInvitation, error) {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
 		return model.CompanyInvitation{}, err
 	}
-	if !isMember {
-		return model.CompanyInvitation{}, errors.New("user is not a member of the company")
+	defer tx.Rollback(ctx)
+
+	if _, err := requireCompanyPermission(ctx, tx, companyID, invitedBy, model.CompanyPermissionInvite); err != nil {
+		return model.CompanyInvitation{}, err
 	}
 
 	var invitedUserID int64
@@ -260,72 +291,85 @@ func (r *CompanyPostgres) CreateInvitation(companyID int64, invitedBy int64, use
 		return model.CompanyInvitation{}, errors.New("cannot invite yourself")
 	}
 
-	var exists bool
-	if err := tx.QueryRow(ctx,
-		"SELECT EXISTS (SELECT 1 FROM company_members WHERE company_id = $1 AND user_id = $2)",
-		companyID, invitedUserID,
-	).Scan(&exists); err != nil {
+	if _, err := companyRole(ctx, tx, companyID, invitedUserID); err == nil {
+		return model.CompanyInvitation{}, ErrAlreadyCompanyMember
+	} else if !errors.Is(err, ErrNotCompanyMember) {
 		return model.CompanyInvitation{}, err
 	}
-	if exists {
-		return model.CompanyInvitation{}, errors.New("user already in company")
-	}
 
-	if err := tx.QueryRow(ctx,
-		"SELECT EXISTS (SELECT 1 FROM company_invitations WHERE company_id = $1 AND invited_user_id = $2 AND status = 'pending')",
+	var (
+		previousStatus      string
+		previousExpiresAt   *time.Time
+		previousRespondedAt *time.Time
+	)
+	err = tx.QueryRow(ctx,
+		"SELECT status, expires_at, responded_at FROM company_invitations WHERE company_id = $1 AND invited_user_id = $2 FOR UPDATE",
 		companyID, invitedUserID,
-	).Scan(&exists); err != nil {
+	).Scan(&previousStatus, &previousExpiresAt, &previousRespondedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 		return model.CompanyInvitation{}, err
 	}
-	if exists {
-		return model.CompanyInvitation{}, errors.New("invitation already sent")
+	if err == nil {
+		switch {
+		case previousStatus == "pending" && (previousExpiresAt == nil || previousExpiresAt.After(time.Now())):
+			return model.CompanyInvitation{}, errors.New("invitation already sent")
+		case previousStatus == "declined" && previousRespondedAt != nil && previousRespondedAt.After(declinedBefore):
+			return model.CompanyInvitation{}, ErrInvitationDeclinedRecently
+		}
 	}
 
 	var invitation model.CompanyInvitation
 	inviteQuery := `
-		INSERT INTO company_invitations (company_id, invited_user_id, invited_by, status)
-		VALUES ($1, $2, $3, 'pending')
+		INSERT INTO company_invitations (company_id, invited_user_id, invited_by, status, expires_at)
+		VALUES ($1, $2, $3, 'pending', $4)
 		ON CONFLICT (company_id, invited_user_id) DO UPDATE
 		SET invited_by = EXCLUDED.invited_by,
 		    status = 'pending',
 		    created_at = NOW(),
+		    expires_at = EXCLUDED.expires_at,
 		    responded_at = NULL
-		RETURNING id, company_id, invited_user_id, invited_by, status, created_at
+		RETURNING id, company_id, invited_user_id, invited_by, status, created_at, expires_at
 	`
-	if err := tx.QueryRow(ctx, inviteQuery, companyID, invitedUserID, invitedBy).Scan(
+	if err := tx.QueryRow(ctx, inviteQuery, companyID, invitedUserID, invitedBy, expiresAt).Scan(
 		&invitation.ID,
 		&invitation.CompanyID,
 		&invitation.InvitedUserID,
 		&invitation.InvitedBy,
 		&invitation.Status,
 		&invitation.CreatedAt,
+		&invitation.ExpiresAt,
 	); err != nil {
 		return model.CompanyInvitation{}, err
 	}
 
-	var companyName string
-	if err := tx.QueryRow(ctx, "SELECT name FROM companies WHERE id = $1", companyID).Scan(&companyName); err != nil {
+	if err := notifyCompanyInvitation(ctx, tx, invitation); err != nil {
 		return model.CompanyInvitation{}, err
 	}
-	var inviterUsername string
-	if err := tx.QueryRow(ctx, "SELECT username FROM users WHERE id = $1", invitedBy).Scan(&inviterUsername); err != nil {
+
+	if err := tx.Commit(ctx); err != nil {
 		return model.CompanyInvitation{}, err
 	}
+	return invitation, nil
+}
+
+// notifyCompanyInvitation tells the invited user about the invitation.
+func notifyCompanyInvitation(ctx context.Context, tx pgx.Tx, invitation model.CompanyInvitation) error {
+	var companyName string
+	if err := tx.QueryRow(ctx, "SELECT name FROM companies WHERE id = $1", invitation.CompanyID).Scan(&companyName); err != nil {
+		return err
+	}
+	var inviterUsername string
+	if err := tx.QueryRow(ctx, "SELECT username FROM users WHERE id = $1", invitation.InvitedBy).Scan(&inviterUsername); err != nil {
+		return err
+	}
 
 	notificationTitle := "Company invitation"
 	notificationMessage := fmt.Sprintf("You were invited to %s by %s", companyName, inviterUsername)
-	_, err = tx.Exec(ctx, `
+	_, err := tx.Exec(ctx, `
 		INSERT INTO notifications (user_id, type, title, message, related_entity_type, related_entity_id)
 		VALUES ($1, 'company_invite', $2, $3, 'company_invitation', $4)
-	`, invitedUserID, notificationTitle, notificationMessage, invitation.ID)
-	if err != nil {
-		return model.CompanyInvitation{}, err
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return model.CompanyInvitation{}, err
-	}
-	return invitation, nil
+	`, invitation.InvitedUserID, notificationTitle, notificationMessage, invitation.ID)
+	return err
 }
 
 func (r *CompanyPostgres) ListInvitations(userID int64) ([]model.CompanyInvitationView, error) {
@@ -338,11 +382,14 @@ func (r *CompanyPostgres) ListInvitations(userID int64) ([]model.CompanyInvitati
 		       u.username AS invited_by_username,
 		       u.avatar_url AS invited_by_avatar_url,
 		       ci.status,
-		       ci.created_at
+		       ci.created_at,
+		       ci.expires_at
 		FROM company_invitations ci
 		JOIN companies c ON c.id = ci.company_id
 		JOIN users u ON u.id = ci.invited_by
-		WHERE ci.invited_user_id = $1 AND ci.status = 'pending'
+		WHERE ci.invited_user_id = $1
+		  AND ci.status = 'pending'
+		  AND (ci.expires_at IS NULL OR ci.expires_at > NOW())
 		ORDER BY ci.created_at DESC
 	`
 	rows, err := r.pool.Query(ctx, query, userID)
@@ -363,6 +410,7 @@ func (r *CompanyPostgres) ListInvitations(userID int64) ([]model.CompanyInvitati
 			&invite.InvitedByAvatarURL,
 			&invite.Status,
 			&invite.CreatedAt,
+			&invite.ExpiresAt,
 		); err != nil {
 			return nil, err
 		}
@@ -371,19 +419,22 @@ func (r *CompanyPostgres) ListInvitations(userID int64) ([]model.CompanyInvitati
 	return invites, rows.Err()
 }
 
-func (r *CompanyPostgres) AcceptInvitation(inviteID int64, userID int64) error {
+// AcceptInvitation adds the user to the inviting company and returns the
+// company id.
+func (r *CompanyPostgres) AcceptInvitation(inviteID int64, userID int64) (int64, error) {
 	ctx := context.Background()
 	tx, err := r.pool.Begin(ctx)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer tx.Rollback(ctx)
 
 	var invitation model.CompanyInvitation
 	query := `
-		SELECT id, company_id, invited_user_id, invited_by, status, created_at, responded_at
+		SELECT id, company_id, invited_user_id, invited_by, status, created_at, expires_at, responded_at
 		FROM company_invitations
 		WHERE id = $1 AND invited_user_id = $2
+		FOR UPDATE
 	`
 	if err := tx.QueryRow(ctx, query, inviteID, userID).Scan(
 		&invitation.ID,
@@ -392,12 +443,16 @@ func (r *CompanyPostgres) AcceptInvitation(inviteID int64, userID int64) error {
 		&invitation.InvitedBy,
 		&invitation.Status,
 		&invitation.CreatedAt,
+		&invitation.ExpiresAt,
 		&invitation.RespondedAt,
 	); err != nil {
-		return err
+		return 0, err
+	}
+	if invitation.Status == "expired" || (invitation.Status == "pending" && invitation.ExpiresAt != nil && !invitation.ExpiresAt.After(time.Now())) {
+		return 0, ErrInvitationExpired
 	}
 	if invitation.Status != "pending" {
-		return errors.New("invitation already handled")
+		return 0, errors.New("invitation already handled")
 	}
 
 	_, err = tx.Exec(ctx, `
@@ -406,7 +461,7 @@ func (r *CompanyPostgres) AcceptInvitation(inviteID int64, userID int64) error {
 		ON CONFLICT (company_id, user_id) DO NOTHING
 	`, invitation.CompanyID, userID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	_, err = tx.Exec(ctx, `
@@ -415,10 +470,13 @@ func (r *CompanyPostgres) AcceptInvitation(inviteID int64, userID int64) error {
 		WHERE id = $1
 	`, inviteID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	return tx.Commit(ctx)
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return invitation.CompanyID, nil
 }
 
 func (r *CompanyPostgres) DeclineInvitation(inviteID int64, userID int64) error {
@@ -441,16 +499,9 @@ func (r *CompanyPostgres) DeclineInvitation(inviteID int64, userID int64) error
 func (r *CompanyPostgres) ListCompanyMembers(companyID int64, userID int64) ([]model.CompanyMemberView, error) {
 	ctx := context.Background()
 
-	var isMember bool
-	if err := r.pool.QueryRow(ctx,
-		"SELECT EXISTS (SELECT 1 FROM company_members WHERE company_id = $1 AND user_id = $2)",
-		companyID, userID,
-	).Scan(&isMember); err != nil {
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionView); err != nil {
 		return nil, err
 	}
-	if !isMember {
-		return nil, errors.New("user is not a member of the company")
-	}
 
 	query := `
 		SELECT cm.user_id, u.username, u.avatar_url, cm.role
@@ -476,27 +527,71 @@ func (r *CompanyPostgres) ListCompanyMembers(companyID int64, userID int64) ([]m
 	return members, rows.Err()
 }
 
-func (r *CompanyPostgres) RemoveCompanyMember(companyID int64, ownerID int64, memberUserID int64) error {
+func (r *CompanyPostgres) RemoveCompanyMember(companyID int64, userID int64, memberUserID int64) error {
 	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
 
-	var creatorID int64
-	if err := r.pool.QueryRow(ctx, "SELECT created_by FROM companies WHERE id = $1", companyID).Scan(&creatorID); err != nil {
+	role, err := requireCompanyPermission(ctx, tx, companyID, userID, model.CompanyPermissionRemoveMembers)
+	if err != nil {
 		return err
 	}
-	if creatorID != ownerID {
-		return errors.New("only company owner can remove members")
+	memberRole, err := companyRole(ctx, tx, companyID, memberUserID)
+	if err != nil {
+		if errors.Is(err, ErrNotCompanyMember) {
+			return pgx.ErrNoRows
+		}
+		return err
 	}
-	if memberUserID == creatorID {
+	if memberRole == model.CompanyRoleOwner {
 		return errors.New("cannot remove company owner")
 	}
+	if model.CompanyRoleRank(memberRole) >= model.CompanyRoleRank(role) {
+		return ErrCompanyPermissionDenied
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM company_members WHERE company_id = $1 AND user_id = $2", companyID, memberUserID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM user_availability WHERE company_id = $1 AND user_id = $2", companyID, memberUserID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
 
-	query := "DELETE FROM company_members WHERE company_id = $1 AND user_id = $2"
-	tag, err := r.pool.Exec(ctx, query, companyID, memberUserID)
+// UpdateCompanyMemberRole makes a member an admin or a regular member. The
+// owner role is only handed over by TransferOwnership and LeaveCompany.
+func (r *CompanyPostgres) UpdateCompanyMemberRole(companyID int64, userID int64, memberUserID int64, newRole string) error {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
 	if err != nil {
 		return err
 	}
-	if tag.RowsAffected() == 0 {
-		return pgx.ErrNoRows
+	defer tx.Rollback(ctx)
+
+	role, err := requireCompanyPermission(ctx, tx, companyID, userID, model.CompanyPermissionManageRoles)
+	if err != nil {
+		return err
 	}
-	return nil
+	memberRole, err := companyRole(ctx, tx, companyID, memberUserID)
+	if err != nil {
+		if errors.Is(err, ErrNotCompanyMember) {
+			return pgx.ErrNoRows
+		}
+		return err
+	}
+	if memberUserID == userID || model.CompanyRoleRank(memberRole) >= model.CompanyRoleRank(role) {
+		return errors.New("cannot change role of this member")
+	}
+
+	if _, err := tx.Exec(ctx,
+		"UPDATE company_members SET role = $1 WHERE company_id = $2 AND user_id = $3",
+		newRole, companyID, memberUserID,
+	); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
 }
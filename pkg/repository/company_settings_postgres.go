@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/jackc/pgx/v5"
+)
+
+// companySettings loads the company settings, falling back to the defaults
+// for a company that never changed them.
+func companySettings(ctx context.Context, db queryRower, companyID int64) (model.CompanySettings, error) {
+	settings := model.CompanySettings{CompanyID: companyID}
+	err := db.QueryRow(ctx, `
+		SELECT timezone, default_event_duration_minutes, event_creation, idea_creation,
+		       members_can_invite, default_rsvp_deadline_minutes, updated_at
+		FROM company_settings
+		WHERE company_id = $1
+	`, companyID).Scan(
+		&settings.Timezone,
+		&settings.DefaultEventDurationMinutes,
+		&settings.EventCreation,
+		&settings.IdeaCreation,
+		&settings.MembersCanInvite,
+		&settings.DefaultRSVPDeadlineMinutes,
+		&settings.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.DefaultCompanySettings(companyID), nil
+	}
+	if err != nil {
+		return model.CompanySettings{}, err
+	}
+	return settings, nil
+}
+
+func (r *CompanyPostgres) GetCompanySettings(companyID int64, userID int64) (model.CompanySettings, error) {
+	ctx := context.Background()
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionView); err != nil {
+		return model.CompanySettings{}, err
+	}
+	return companySettings(ctx, r.pool, companyID)
+}
+
+// UpdateCompanySettings replaces the whole settings document of the company.
+func (r *CompanyPostgres) UpdateCompanySettings(companyID int64, userID int64, settings model.CompanySettings) (model.CompanySettings, error) {
+	ctx := context.Background()
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionManageSettings); err != nil {
+		return model.CompanySettings{}, err
+	}
+
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO company_settings (
+		    company_id, timezone, default_event_duration_minutes, event_creation, idea_creation,
+		    members_can_invite, default_rsvp_deadline_minutes
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (company_id) DO UPDATE
+		SET timezone = EXCLUDED.timezone,
+		    default_event_duration_minutes = EXCLUDED.default_event_duration_minutes,
+		    event_creation = EXCLUDED.event_creation,
+		    idea_creation = EXCLUDED.idea_creation,
+		    members_can_invite = EXCLUDED.members_can_invite,
+		    default_rsvp_deadline_minutes = EXCLUDED.default_rsvp_deadline_minutes,
+		    updated_at = NOW()
+	`,
+		companyID,
+		settings.Timezone,
+		settings.DefaultEventDurationMinutes,
+		settings.EventCreation,
+		settings.IdeaCreation,
+		settings.MembersCanInvite,
+		settings.DefaultRSVPDeadlineMinutes,
+	)
+	if err != nil {
+		return model.CompanySettings{}, err
+	}
+	return companySettings(ctx, r.pool, companyID)
+}
+
+// GetCompanySettings lets the availability service render times in the
+// company timezone.
+func (r *AvailabilityPostgres) GetCompanySettings(companyID int64, userID int64) (model.CompanySettings, error) {
+	ctx := context.Background()
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionView); err != nil {
+		return model.CompanySettings{}, err
+	}
+	return companySettings(ctx, r.pool, companyID)
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrEmailInvitationNotFound     = errors.New("email invitation not found")
+	ErrEmailInvitationSentRecently = errors.New("invitation was sent too recently")
+)
+
+// GetInvitationSender checks that the user may invite people to the company
+// and returns the names shown in the invitation email.
+func (r *CompanyPostgres) GetInvitationSender(companyID int64, userID int64) (model.CompanyInvitationSender, error) {
+	ctx := context.Background()
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionInvite); err != nil {
+		return model.CompanyInvitationSender{}, err
+	}
+
+	var sender model.CompanyInvitationSender
+	err := r.pool.QueryRow(ctx, `
+		SELECT c.name, u.username, u.locale
+		FROM companies c, users u
+		WHERE c.id = $1 AND u.id = $2
+	`, companyID, userID).Scan(&sender.CompanyName, &sender.InviterName, &sender.InviterLocale)
+	return sender, err
+}
+
+func (r *CompanyPostgres) GetEmailInvitation(companyID int64, userID int64, inviteID int64) (model.CompanyEmailInvitation, error) {
+	ctx := context.Background()
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionInvite); err != nil {
+		return model.CompanyEmailInvitation{}, err
+	}
+
+	var invitation model.CompanyEmailInvitation
+	err := r.pool.QueryRow(ctx, `
+		SELECT id, company_id, email, invited_by, expires_at, last_sent_at, created_at
+		FROM company_email_invitations
+		WHERE id = $1 AND company_id = $2
+	`, inviteID, companyID).Scan(
+		&invitation.ID,
+		&invitation.CompanyID,
+		&invitation.Email,
+		&invitation.InvitedBy,
+		&invitation.ExpiresAt,
+		&invitation.LastSentAt,
+		&invitation.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.CompanyEmailInvitation{}, ErrEmailInvitationNotFound
+	}
+	return invitation, err
+}
+
+// CreateEmailInvitation stores the invitation, or renews the expiry of an
+// existing one for the same email, and queues the message in the same
+// transaction. Renewal is refused while the last email was sent after
+// sentBefore. Whether the email already has an account is deliberately not
+// checked, so the inviter cannot learn it: such an invitation is converted
+// on the user's next sign-in.
+func (r *CompanyPostgres) CreateEmailInvitation(invitation model.CompanyEmailInvitation, sentBefore time.Time, message model.EmailMessage) (model.CompanyEmailInvitation, error) {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return model.CompanyEmailInvitation{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := requireCompanyPermission(ctx, tx, invitation.CompanyID, invitation.InvitedBy, model.CompanyPermissionInvite); err != nil {
+		return model.CompanyEmailInvitation{}, err
+	}
+
+	var created model.CompanyEmailInvitation
+	err = tx.QueryRow(ctx, `
+		INSERT INTO company_email_invitations (company_id, email, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (company_id, (lower(email))) DO UPDATE
+		SET invited_by = EXCLUDED.invited_by,
+		    expires_at = EXCLUDED.expires_at,
+		    last_sent_at = NOW()
+		WHERE company_email_invitations.last_sent_at <= $5
+		RETURNING id, company_id, email, invited_by, expires_at, last_sent_at, created_at
+	`, invitation.CompanyID, invitation.Email, invitation.InvitedBy, invitation.ExpiresAt, sentBefore).Scan(
+		&created.ID,
+		&created.CompanyID,
+		&created.Email,
+		&created.InvitedBy,
+		&created.ExpiresAt,
+		&created.LastSentAt,
+		&created.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.CompanyEmailInvitation{}, ErrEmailInvitationSentRecently
+	}
+	if err != nil {
+		return model.CompanyEmailInvitation{}, err
+	}
+
+	if err := enqueueEmail(ctx, tx, message); err != nil {
+		return model.CompanyEmailInvitation{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.CompanyEmailInvitation{}, err
+	}
+	return created, nil
+}
+
+// ConvertEmailInvitations turns the unexpired email invitations of the
+// user's email into pending company invitations, with the usual notification,
+// and returns how many were created. An earlier invitation of the user to the
+// same company is reopened, following the re-invite rules of CreateInvitation:
+// invitations to companies the user is already a member of, already has a
+// pending invitation to, or declined after declinedBefore are dropped.
+func (r *AuthPostgres) ConvertEmailInvitations(userID int64, email string, declinedBefore time.Time) (int, error) {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		WITH claimed AS (
+		    DELETE FROM company_email_invitations
+		    WHERE lower(email) = lower($2)
+		    RETURNING company_id, invited_by, expires_at
+		)
+		INSERT INTO company_invitations (company_id, invited_user_id, invited_by, status, expires_at)
+		SELECT company_id, $1, invited_by, 'pending', expires_at
+		FROM claimed
+		WHERE expires_at > NOW()
+		  AND NOT EXISTS (
+		      SELECT 1 FROM company_members cm
+		      WHERE cm.company_id = claimed.company_id AND cm.user_id = $1
+		  )
+		ON CONFLICT (company_id, invited_user_id) DO UPDATE
+		SET invited_by = EXCLUDED.invited_by,
+		    status = 'pending',
+		    created_at = NOW(),
+		    expires_at = EXCLUDED.expires_at,
+		    responded_at = NULL
+		WHERE NOT (company_invitations.status = 'pending'
+		           AND (company_invitations.expires_at IS NULL OR company_invitations.expires_at > NOW()))
+		  AND NOT (company_invitations.status = 'declined'
+		           AND company_invitations.responded_at > $3)
+		RETURNING id, company_id, invited_by, expires_at
+	`, userID, email, declinedBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	var invitations []model.CompanyInvitation
+	for rows.Next() {
+		invitation := model.CompanyInvitation{InvitedUserID: userID}
+		if err := rows.Scan(&invitation.ID, &invitation.CompanyID, &invitation.InvitedBy, &invitation.ExpiresAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, invitation := range invitations {
+		if err := notifyCompanyInvitation(ctx, tx, invitation); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(invitations), nil
+}
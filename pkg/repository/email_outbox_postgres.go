@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// execer is implemented by both the pool and a transaction, so an email can
+// be queued as part of the transaction that caused it.
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+func enqueueEmail(ctx context.Context, db execer, message model.EmailMessage) error {
+	_, err := db.Exec(ctx, `
+		INSERT INTO email_outbox (recipient, subject, body, html_body)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+	`, message.To, message.Subject, message.Text, message.HTML)
+	return err
+}
+
+func (r *EmailOutboxPostgres) EnqueueEmail(message model.EmailMessage) error {
+	return enqueueEmail(context.Background(), r.pool, message)
+}
+
+// ClaimPendingEmails locks up to limit emails that are due for delivery for
+// the duration of lease. Emails of a worker that died mid-delivery become
+// due again once the lease is over.
+func (r *EmailOutboxPostgres) ClaimPendingEmails(limit int, lease time.Duration) ([]model.OutboxEmail, error) {
+	query := `
+		UPDATE email_outbox
+		SET locked_until = NOW() + $2 * INTERVAL '1 millisecond'
+		WHERE id IN (
+			SELECT id
+			FROM email_outbox
+			WHERE status = 'pending'
+			  AND next_attempt_at <= NOW()
+			  AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY next_attempt_at, id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, recipient, subject, body, COALESCE(html_body, ''), attempts
+	`
+	rows, err := r.pool.Query(context.Background(), query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var emails []model.OutboxEmail
+	for rows.Next() {
+		var email model.OutboxEmail
+		if err := rows.Scan(&email.ID, &email.To, &email.Subject, &email.Text, &email.HTML, &email.Attempts); err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+	return emails, rows.Err()
+}
+
+// MarkEmailSent records the delivery and drops the message body: emails carry
+// sign-in and verification codes that must not outlive the delivery.
+func (r *EmailOutboxPostgres) MarkEmailSent(emailID int64) error {
+	tag, err := r.pool.Exec(context.Background(), `
+		UPDATE email_outbox
+		SET status = 'sent', sent_at = NOW(), locked_until = NULL, last_error = NULL,
+			body = '', html_body = NULL
+		WHERE id = $1
+	`, emailID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// MarkEmailFailed records a failed delivery. The email is retried at
+// nextAttemptAt, or moved to the dead state when nextAttemptAt is nil; a dead
+// email loses its body like a sent one.
+func (r *EmailOutboxPostgres) MarkEmailFailed(emailID int64, lastError string, nextAttemptAt *time.Time) error {
+	tag, err := r.pool.Exec(context.Background(), `
+		UPDATE email_outbox
+		SET attempts = attempts + 1,
+			last_error = $2,
+			locked_until = NULL,
+			status = CASE WHEN $3::timestamptz IS NULL THEN 'dead' ELSE 'pending' END,
+			next_attempt_at = COALESCE($3, next_attempt_at),
+			body = CASE WHEN $3::timestamptz IS NULL THEN '' ELSE body END,
+			html_body = CASE WHEN $3::timestamptz IS NULL THEN NULL ELSE html_body END
+		WHERE id = $1
+	`, emailID, lastError, nextAttemptAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *EmailOutboxPostgres) DeleteSentEmails(sentBefore time.Time) (int64, error) {
+	tag, err := r.pool.Exec(context.Background(), "DELETE FROM email_outbox WHERE status = 'sent' AND sent_at < $1", sentBefore)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (r *EmailOutboxPostgres) GetEmailOutboxStats() (model.EmailOutboxStats, error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE status = 'pending'),
+			COUNT(*) FILTER (WHERE status = 'pending' AND attempts > 0),
+			COUNT(*) FILTER (WHERE status = 'dead'),
+			COALESCE(EXTRACT(EPOCH FROM NOW() - MIN(created_at) FILTER (WHERE status = 'pending')), 0)::int
+		FROM email_outbox
+		WHERE status <> 'sent'
+	`
+	var stats model.EmailOutboxStats
+	err := r.pool.QueryRow(context.Background(), query).Scan(
+		&stats.Pending,
+		&stats.Retrying,
+		&stats.Dead,
+		&stats.OldestPendingAgeSec,
+	)
+	return stats, err
+}
@@ -0,0 +1,11 @@
+package repository
+
+import "github.com/jackc/pgx/v5/pgxpool"
+
+type EmailOutboxPostgres struct {
+	pool *pgxpool.Pool
+}
+
+func NewEmailOutboxRepository(pool *pgxpool.Pool) *EmailOutboxPostgres {
+	return &EmailOutboxPostgres{pool: pool}
+}
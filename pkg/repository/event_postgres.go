@@ -5,31 +5,26 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/Sovpalo/sovpalo-backend/pkg/model"
 	"github.com/jackc/pgx/v5"
 )
 
+var ErrRSVPDeadlinePassed = errors.New("rsvp deadline has passed")
+
 func (r *EventPostgres) CreateEvent(event model.Event) (int64, error) {
 	ctx := context.Background()
 
 	if event.CompanyID != nil {
-		var isMember bool
-		err := r.pool.QueryRow(ctx,
-			"SELECT EXISTS (SELECT 1 FROM company_members WHERE company_id = $1 AND user_id = $2)",
-			*event.CompanyID, event.CreatedBy,
-		).Scan(&isMember)
-		if err != nil {
+		if _, err := requireCompanyPermission(ctx, r.pool, *event.CompanyID, event.CreatedBy, model.CompanyPermissionCreateEvents); err != nil {
 			return 0, err
 		}
-		if !isMember {
-			return 0, errors.New("user is not a member of the company")
-		}
 	}
 
 	query := `
-		INSERT INTO events (company_id, created_by, title, description, photo_url, start_time, end_time, place_name, place_link, status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 'pending')
+		INSERT INTO events (company_id, created_by, title, description, photo_url, start_time, end_time, place_name, place_link, rsvp_deadline, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, 'pending')
 		RETURNING id
 	`
 	var id int64
@@ -45,6 +40,7 @@ func (r *EventPostgres) CreateEvent(event model.Event) (int64, error) {
 		event.EndTime,
 		event.PlaceName,
 		event.PlaceLink,
+		event.RSVPDeadline,
 	).Scan(&id)
 	if err != nil {
 		return 0, err
@@ -57,7 +53,7 @@ func (r *EventPostgres) GetEvent(eventID int64, userID int64) (model.Event, erro
 	var event model.Event
 	query := `
 		SELECT e.id, e.company_id, e.created_by, e.title, e.description, e.photo_url, e.start_time, e.end_time,
-		       e.place_name, e.place_link, e.status, e.created_at, e.updated_at
+		       e.place_name, e.place_link, e.rsvp_deadline, e.status, e.created_at, e.updated_at
 		FROM events e
 		LEFT JOIN company_members cm ON cm.company_id = e.company_id AND cm.user_id = $2
 		WHERE e.id = $1
@@ -77,6 +73,7 @@ func (r *EventPostgres) GetEvent(eventID int64, userID int64) (model.Event, erro
 		&event.EndTime,
 		&event.PlaceName,
 		&event.PlaceLink,
+		&event.RSVPDeadline,
 		&event.Status,
 		&event.CreatedAt,
 		&event.UpdatedAt,
@@ -91,7 +88,7 @@ func (r *EventPostgres) ListEvents(userID int64) ([]model.Event, error) {
 	ctx := context.Background()
 	query := `
 		SELECT DISTINCT e.id, e.company_id, e.created_by, e.title, e.description, e.photo_url, e.start_time, e.end_time,
-		       e.place_name, e.place_link, e.status, e.created_at, e.updated_at
+		       e.place_name, e.place_link, e.rsvp_deadline, e.status, e.created_at, e.updated_at
 		FROM events e
 		LEFT JOIN company_members cm ON cm.company_id = e.company_id AND cm.user_id = $1
 		WHERE (e.company_id IS NOT NULL AND cm.user_id IS NOT NULL)
@@ -118,6 +115,7 @@ func (r *EventPostgres) ListEvents(userID int64) ([]model.Event, error) {
 			&event.EndTime,
 			&event.PlaceName,
 			&event.PlaceLink,
+			&event.RSVPDeadline,
 			&event.Status,
 			&event.CreatedAt,
 			&event.UpdatedAt,
@@ -131,20 +129,13 @@ func (r *EventPostgres) ListEvents(userID int64) ([]model.Event, error) {
 
 func (r *EventPostgres) ListCompanyEvents(companyID int64, userID int64) ([]model.Event, error) {
 	ctx := context.Background()
-	var isMember bool
-	if err := r.pool.QueryRow(ctx,
-		"SELECT EXISTS (SELECT 1 FROM company_members WHERE company_id = $1 AND user_id = $2)",
-		companyID, userID,
-	).Scan(&isMember); err != nil {
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionView); err != nil {
 		return nil, err
 	}
-	if !isMember {
-		return nil, errors.New("user is not a member of the company")
-	}
 
 	query := `
 		SELECT e.id, e.company_id, e.created_by, e.title, e.description, e.photo_url, e.start_time, e.end_time,
-		       e.place_name, e.place_link, e.status, e.created_at, e.updated_at
+		       e.place_name, e.place_link, e.rsvp_deadline, e.status, e.created_at, e.updated_at
 		FROM events e
 		WHERE e.company_id = $1
 		ORDER BY e.created_at DESC
@@ -169,6 +160,7 @@ func (r *EventPostgres) ListCompanyEvents(companyID int64, userID int64) ([]mode
 			&event.EndTime,
 			&event.PlaceName,
 			&event.PlaceLink,
+			&event.RSVPDeadline,
 			&event.Status,
 			&event.CreatedAt,
 			&event.UpdatedAt,
@@ -183,6 +175,10 @@ func (r *EventPostgres) ListCompanyEvents(companyID int64, userID int64) ([]mode
 func (r *EventPostgres) UpdateEvent(eventID int64, userID int64, input model.EventUpdateInput) error {
 	ctx := context.Background()
 
+	if err := r.requireEventManager(ctx, eventID, userID); err != nil {
+		return err
+	}
+
 	setParts := make([]string, 0, 6)
 	args := make([]interface{}, 0, 7)
 	argID := 1
@@ -203,7 +199,11 @@ func (r *EventPostgres) UpdateEvent(eventID int64, userID int64, input model.Eve
 		argID++
 	}
 	if input.StartTime != nil {
-		setParts = append(setParts, fmt.Sprintf("start_time = $%d", argID))
+		// The RSVP deadline keeps its distance to the start.
+		setParts = append(setParts,
+			fmt.Sprintf("rsvp_deadline = rsvp_deadline + ($%d - start_time)", argID),
+			fmt.Sprintf("start_time = $%d", argID),
+		)
 		args = append(args, *input.StartTime)
 		argID++
 	}
@@ -213,17 +213,9 @@ func (r *EventPostgres) UpdateEvent(eventID int64, userID int64, input model.Eve
 		argID++
 	}
 	if input.CompanyID != nil {
-		var isMember bool
-		err := r.pool.QueryRow(ctx,
-			"SELECT EXISTS (SELECT 1 FROM company_members WHERE company_id = $1 AND user_id = $2)",
-			*input.CompanyID, userID,
-		).Scan(&isMember)
-		if err != nil {
+		if _, err := requireCompanyPermission(ctx, r.pool, *input.CompanyID, userID, model.CompanyPermissionCreateEvents); err != nil {
 			return err
 		}
-		if !isMember {
-			return errors.New("user is not a member of the company")
-		}
 
 		setParts = append(setParts, fmt.Sprintf("company_id = $%d", argID))
 		args = append(args, *input.CompanyID)
@@ -236,12 +228,11 @@ func (r *EventPostgres) UpdateEvent(eventID int64, userID int64, input model.Eve
 
 	setParts = append(setParts, "updated_at = NOW()")
 	query := fmt.Sprintf(
-		"UPDATE events SET %s WHERE id = $%d AND created_by = $%d",
+		"UPDATE events SET %s WHERE id = $%d",
 		strings.Join(setParts, ", "),
 		argID,
-		argID+1,
 	)
-	args = append(args, eventID, userID)
+	args = append(args, eventID)
 
 	tag, err := r.pool.Exec(ctx, query, args...)
 	if err != nil {
@@ -255,8 +246,11 @@ func (r *EventPostgres) UpdateEvent(eventID int64, userID int64, input model.Eve
 
 func (r *EventPostgres) DeleteEvent(eventID int64, userID int64) error {
 	ctx := context.Background()
-	query := "DELETE FROM events WHERE id = $1 AND created_by = $2"
-	tag, err := r.pool.Exec(ctx, query, eventID, userID)
+	if err := r.requireEventManager(ctx, eventID, userID); err != nil {
+		return err
+	}
+
+	tag, err := r.pool.Exec(ctx, "DELETE FROM events WHERE id = $1", eventID)
 	if err != nil {
 		return err
 	}
@@ -266,27 +260,49 @@ func (r *EventPostgres) DeleteEvent(eventID int64, userID int64) error {
 	return nil
 }
 
+// requireEventManager allows the author of an event to change it, and in a
+// company also members whose role can moderate events. Events the user
+// cannot see are reported as not found.
+func (r *EventPostgres) requireEventManager(ctx context.Context, eventID int64, userID int64) error {
+	var companyID *int64
+	var createdBy int64
+	if err := r.pool.QueryRow(ctx, "SELECT company_id, created_by FROM events WHERE id = $1", eventID).Scan(&companyID, &createdBy); err != nil {
+		return err
+	}
+	if createdBy == userID {
+		return nil
+	}
+	if companyID == nil {
+		return pgx.ErrNoRows
+	}
+
+	_, err := requireCompanyPermission(ctx, r.pool, *companyID, userID, model.CompanyPermissionModerateEvents)
+	if errors.Is(err, ErrNotCompanyMember) {
+		return pgx.ErrNoRows
+	}
+	return err
+}
+
 func (r *EventPostgres) SetCompanyEventAttendance(companyID int64, eventID int64, userID int64, status string) error {
 	ctx := context.Background()
 
-	var isMember bool
-	if err := r.pool.QueryRow(ctx,
-		"SELECT EXISTS (SELECT 1 FROM company_members WHERE company_id = $1 AND user_id = $2)",
-		companyID, userID,
-	).Scan(&isMember); err != nil {
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionView); err != nil {
 		return err
 	}
-	if !isMember {
-		return errors.New("user is not a member of the company")
-	}
 
-	var eventCompanyID *int64
-	if err := r.pool.QueryRow(ctx, "SELECT company_id FROM events WHERE id = $1", eventID).Scan(&eventCompanyID); err != nil {
+	var (
+		eventCompanyID *int64
+		rsvpDeadline   *time.Time
+	)
+	if err := r.pool.QueryRow(ctx, "SELECT company_id, rsvp_deadline FROM events WHERE id = $1", eventID).Scan(&eventCompanyID, &rsvpDeadline); err != nil {
 		return err
 	}
 	if eventCompanyID == nil || *eventCompanyID != companyID {
 		return pgx.ErrNoRows
 	}
+	if rsvpDeadline != nil && !rsvpDeadline.After(time.Now()) {
+		return ErrRSVPDeadlinePassed
+	}
 
 	query := `
 		INSERT INTO event_participants (event_id, user_id, status, notified)
@@ -301,16 +317,9 @@ func (r *EventPostgres) SetCompanyEventAttendance(companyID int64, eventID int64
 func (r *EventPostgres) ListCompanyEventAttendance(companyID int64, eventID int64, userID int64) ([]model.EventAttendanceView, error) {
 	ctx := context.Background()
 
-	var isMember bool
-	if err := r.pool.QueryRow(ctx,
-		"SELECT EXISTS (SELECT 1 FROM company_members WHERE company_id = $1 AND user_id = $2)",
-		companyID, userID,
-	).Scan(&isMember); err != nil {
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionView); err != nil {
 		return nil, err
 	}
-	if !isMember {
-		return nil, errors.New("user is not a member of the company")
-	}
 
 	var eventCompanyID *int64
 	if err := r.pool.QueryRow(ctx, "SELECT company_id FROM events WHERE id = $1", eventID).Scan(&eventCompanyID); err != nil {
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+)
+
+func (r *ExportPostgres) ListUserMemberships(userID int64) ([]model.CompanyMembershipExport, error) {
+	query := `
+		SELECT c.id, c.name, cm.role, cm.joined_at
+		FROM company_members cm
+		JOIN companies c ON c.id = cm.company_id
+		WHERE cm.user_id = $1
+		ORDER BY cm.joined_at
+	`
+	rows, err := r.pool.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []model.CompanyMembershipExport
+	for rows.Next() {
+		var item model.CompanyMembershipExport
+		if err := rows.Scan(&item.CompanyID, &item.CompanyName, &item.Role, &item.JoinedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *ExportPostgres) ListUserEvents(userID int64) ([]model.Event, error) {
+	query := `
+		SELECT id, company_id, created_by, title, description, photo_url, start_time, end_time,
+		       place_name, place_link, status, created_at, updated_at
+		FROM events
+		WHERE created_by = $1
+		ORDER BY created_at
+	`
+	rows, err := r.pool.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []model.Event
+	for rows.Next() {
+		var event model.Event
+		if err := rows.Scan(
+			&event.ID,
+			&event.CompanyID,
+			&event.CreatedBy,
+			&event.Title,
+			&event.Description,
+			&event.PhotoURL,
+			&event.StartTime,
+			&event.EndTime,
+			&event.PlaceName,
+			&event.PlaceLink,
+			&event.Status,
+			&event.CreatedAt,
+			&event.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (r *ExportPostgres) ListUserRSVPs(userID int64) ([]model.EventRSVPExport, error) {
+	query := `
+		SELECT ep.event_id, e.company_id, e.title, ep.status, ep.updated_at
+		FROM event_participants ep
+		JOIN events e ON e.id = ep.event_id
+		WHERE ep.user_id = $1
+		ORDER BY ep.updated_at
+	`
+	rows, err := r.pool.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []model.EventRSVPExport
+	for rows.Next() {
+		var item model.EventRSVPExport
+		if err := rows.Scan(&item.EventID, &item.CompanyID, &item.EventTitle, &item.Status, &item.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *ExportPostgres) ListUserIdeas(userID int64) ([]model.Idea, error) {
+	query := `
+		SELECT id, company_id, created_by, title, description, photo_url, COALESCE(source, ''), llm_prompt,
+		       COALESCE(is_saved, TRUE), created_at, updated_at
+		FROM ideas
+		WHERE created_by = $1
+		ORDER BY created_at
+	`
+	rows, err := r.pool.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ideas []model.Idea
+	for rows.Next() {
+		var idea model.Idea
+		if err := rows.Scan(
+			&idea.ID,
+			&idea.CompanyID,
+			&idea.CreatedBy,
+			&idea.Title,
+			&idea.Description,
+			&idea.PhotoURL,
+			&idea.Source,
+			&idea.LLMPrompt,
+			&idea.IsSaved,
+			&idea.CreatedAt,
+			&idea.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		ideas = append(ideas, idea)
+	}
+	return ideas, rows.Err()
+}
+
+func (r *ExportPostgres) ListUserIdeaLikes(userID int64) ([]model.IdeaLikeExport, error) {
+	query := `
+		SELECT il.idea_id, i.company_id, i.title, il.created_at
+		FROM idea_likes il
+		JOIN ideas i ON i.id = il.idea_id
+		WHERE il.user_id = $1
+		ORDER BY il.created_at
+	`
+	rows, err := r.pool.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []model.IdeaLikeExport
+	for rows.Next() {
+		var item model.IdeaLikeExport
+		if err := rows.Scan(&item.IdeaID, &item.CompanyID, &item.IdeaTitle, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *ExportPostgres) ListUserAvailability(userID int64) ([]model.UserAvailability, error) {
+	query := `
+		SELECT id, user_id, company_id, start_time, end_time, note, created_at, updated_at
+		FROM user_availability
+		WHERE user_id = $1
+		ORDER BY start_time
+	`
+	rows, err := r.pool.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []model.UserAvailability
+	for rows.Next() {
+		var item model.UserAvailability
+		if err := rows.Scan(
+			&item.ID,
+			&item.UserID,
+			&item.CompanyID,
+			&item.StartTime,
+			&item.EndTime,
+			&item.Note,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (r *ExportPostgres) ListUserMedia(userID int64) ([]model.MediaArchive, error) {
+	query := `
+		SELECT id, company_id, uploaded_by, event_id, file_name, file_url, file_type, file_size,
+		       thumbnail_url, description, metadata, created_at
+		FROM media_archive
+		WHERE uploaded_by = $1
+		ORDER BY created_at
+	`
+	rows, err := r.pool.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []model.MediaArchive
+	for rows.Next() {
+		var item model.MediaArchive
+		if err := rows.Scan(
+			&item.ID,
+			&item.CompanyID,
+			&item.UploadedBy,
+			&item.EventID,
+			&item.FileName,
+			&item.FileURL,
+			&item.FileType,
+			&item.FileSize,
+			&item.ThumbnailURL,
+			&item.Description,
+			&item.Metadata,
+			&item.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
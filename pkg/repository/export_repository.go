@@ -0,0 +1,11 @@
+package repository
+
+import "github.com/jackc/pgx/v5/pgxpool"
+
+type ExportPostgres struct {
+	pool *pgxpool.Pool
+}
+
+func NewExportRepository(pool *pgxpool.Pool) *ExportPostgres {
+	return &ExportPostgres{pool: pool}
+}
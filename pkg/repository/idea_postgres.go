@@ -13,16 +13,9 @@ import (
 func (r *IdeaPostgres) CreateCompanyIdea(companyID int64, userID int64, input model.IdeaCreateInput) (int64, error) {
 	ctx := context.Background()
 
-	var isMember bool
-	if err := r.pool.QueryRow(ctx,
-		"SELECT EXISTS (SELECT 1 FROM company_members WHERE company_id = $1 AND user_id = $2)",
-		companyID, userID,
-	).Scan(&isMember); err != nil {
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionCreateIdeas); err != nil {
 		return 0, err
 	}
-	if !isMember {
-		return 0, errors.New("user is not a member of the company")
-	}
 
 	query := `
 		INSERT INTO ideas (company_id, created_by, title, description, photo_url, source)
@@ -39,16 +32,9 @@ func (r *IdeaPostgres) CreateCompanyIdea(companyID int64, userID int64, input mo
 func (r *IdeaPostgres) ListCompanyIdeas(companyID int64, userID int64) ([]model.IdeaView, error) {
 	ctx := context.Background()
 
-	var isMember bool
-	if err := r.pool.QueryRow(ctx,
-		"SELECT EXISTS (SELECT 1 FROM company_members WHERE company_id = $1 AND user_id = $2)",
-		companyID, userID,
-	).Scan(&isMember); err != nil {
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionView); err != nil {
 		return nil, err
 	}
-	if !isMember {
-		return nil, errors.New("user is not a member of the company")
-	}
 
 	query := `
 		SELECT i.id,
@@ -105,16 +91,9 @@ func (r *IdeaPostgres) ListCompanyIdeas(companyID int64, userID int64) ([]model.
 func (r *IdeaPostgres) GetCompanyIdea(companyID int64, userID int64, ideaID int64) (model.IdeaView, error) {
 	ctx := context.Background()
 
-	var isMember bool
-	if err := r.pool.QueryRow(ctx,
-		"SELECT EXISTS (SELECT 1 FROM company_members WHERE company_id = $1 AND user_id = $2)",
-		companyID, userID,
-	).Scan(&isMember); err != nil {
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionView); err != nil {
 		return model.IdeaView{}, err
 	}
-	if !isMember {
-		return model.IdeaView{}, errors.New("user is not a member of the company")
-	}
 
 	query := `
 		SELECT i.id,
@@ -161,16 +140,10 @@ func (r *IdeaPostgres) GetCompanyIdea(companyID int64, userID int64, ideaID int6
 func (r *IdeaPostgres) UpdateCompanyIdea(companyID int64, userID int64, ideaID int64, input model.IdeaUpdateInput) error {
 	ctx := context.Background()
 
-	var isMember bool
-	if err := r.pool.QueryRow(ctx,
-		"SELECT EXISTS (SELECT 1 FROM company_members WHERE company_id = $1 AND user_id = $2)",
-		companyID, userID,
-	).Scan(&isMember); err != nil {
+	role, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionView)
+	if err != nil {
 		return err
 	}
-	if !isMember {
-		return errors.New("user is not a member of the company")
-	}
 
 	setParts := make([]string, 0, 4)
 	args := make([]interface{}, 0, 6)
@@ -198,13 +171,16 @@ func (r *IdeaPostgres) UpdateCompanyIdea(companyID int64, userID int64, ideaID i
 
 	setParts = append(setParts, "updated_at = NOW()")
 	query := fmt.Sprintf(
-		"UPDATE ideas SET %s WHERE id = $%d AND company_id = $%d AND created_by = $%d",
+		"UPDATE ideas SET %s WHERE id = $%d AND company_id = $%d",
 		strings.Join(setParts, ", "),
 		argID,
 		argID+1,
-		argID+2,
 	)
-	args = append(args, ideaID, companyID, userID)
+	args = append(args, ideaID, companyID)
+	if !model.CompanyRoleAllows(role, model.CompanyPermissionModerateIdeas) {
+		query += fmt.Sprintf(" AND created_by = $%d", argID+2)
+		args = append(args, userID)
+	}
 
 	tag, err := r.pool.Exec(ctx, query, args...)
 	if err != nil {
@@ -219,16 +195,9 @@ func (r *IdeaPostgres) UpdateCompanyIdea(companyID int64, userID int64, ideaID i
 func (r *IdeaPostgres) LikeCompanyIdea(companyID int64, userID int64, ideaID int64) error {
 	ctx := context.Background()
 
-	var isMember bool
-	if err := r.pool.QueryRow(ctx,
-		"SELECT EXISTS (SELECT 1 FROM company_members WHERE company_id = $1 AND user_id = $2)",
-		companyID, userID,
-	).Scan(&isMember); err != nil {
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionView); err != nil {
 		return err
 	}
-	if !isMember {
-		return errors.New("user is not a member of the company")
-	}
 
 	var ideaCompanyID int64
 	if err := r.pool.QueryRow(ctx, "SELECT company_id FROM ideas WHERE id = $1", ideaID).Scan(&ideaCompanyID); err != nil {
@@ -249,16 +218,9 @@ func (r *IdeaPostgres) LikeCompanyIdea(companyID int64, userID int64, ideaID int
 func (r *IdeaPostgres) UnlikeCompanyIdea(companyID int64, userID int64, ideaID int64) error {
 	ctx := context.Background()
 
-	var isMember bool
-	if err := r.pool.QueryRow(ctx,
-		"SELECT EXISTS (SELECT 1 FROM company_members WHERE company_id = $1 AND user_id = $2)",
-		companyID, userID,
-	).Scan(&isMember); err != nil {
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionView); err != nil {
 		return err
 	}
-	if !isMember {
-		return errors.New("user is not a member of the company")
-	}
 
 	var ideaCompanyID int64
 	if err := r.pool.QueryRow(ctx, "SELECT company_id FROM ideas WHERE id = $1", ideaID).Scan(&ideaCompanyID); err != nil {
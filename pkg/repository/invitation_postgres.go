@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvitationNotFound         = errors.New("invitation not found")
+	ErrInvitationExpired          = errors.New("invitation has expired")
+	ErrInvitationDeclinedRecently = errors.New("invitation was declined recently")
+)
+
+// ListSentInvitations returns the invitations the company has sent that were
+// not accepted, newest first.
+func (r *CompanyPostgres) ListSentInvitations(companyID int64, userID int64) ([]model.CompanySentInvitationView, error) {
+	ctx := context.Background()
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionInvite); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ci.id,
+		       ci.invited_user_id,
+		       invited.username,
+		       invited.avatar_url,
+		       ci.invited_by,
+		       inviter.username,
+		       CASE
+		           WHEN ci.status = 'pending' AND ci.expires_at <= NOW() THEN 'expired'
+		           ELSE ci.status
+		       END AS status,
+		       ci.created_at,
+		       ci.expires_at,
+		       ci.responded_at
+		FROM company_invitations ci
+		JOIN users invited ON invited.id = ci.invited_user_id
+		JOIN users inviter ON inviter.id = ci.invited_by
+		WHERE ci.company_id = $1 AND ci.status <> 'accepted'
+		ORDER BY ci.created_at DESC
+	`
+	rows, err := r.pool.Query(ctx, query, companyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invites []model.CompanySentInvitationView
+	for rows.Next() {
+		var invite model.CompanySentInvitationView
+		if err := rows.Scan(
+			&invite.ID,
+			&invite.InvitedUserID,
+			&invite.InvitedUsername,
+			&invite.InvitedAvatarURL,
+			&invite.InvitedBy,
+			&invite.InvitedByUsername,
+			&invite.Status,
+			&invite.CreatedAt,
+			&invite.ExpiresAt,
+			&invite.RespondedAt,
+		); err != nil {
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+	return invites, rows.Err()
+}
+
+// RevokeInvitation cancels a pending invitation together with its
+// notification. The inviter can revoke their own invitations; other
+// invitations need the permission to remove members.
+func (r *CompanyPostgres) RevokeInvitation(companyID int64, userID int64, inviteID int64) error {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := companyRole(ctx, tx, companyID, userID); err != nil {
+		return err
+	}
+
+	var invitedBy int64
+	err = tx.QueryRow(ctx,
+		"SELECT invited_by FROM company_invitations WHERE id = $1 AND company_id = $2 AND status = 'pending' FOR UPDATE",
+		inviteID, companyID,
+	).Scan(&invitedBy)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvitationNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if invitedBy != userID {
+		if _, err := requireCompanyPermission(ctx, tx, companyID, userID, model.CompanyPermissionRemoveMembers); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM company_invitations WHERE id = $1", inviteID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx,
+		"DELETE FROM notifications WHERE related_entity_type = 'company_invitation' AND related_entity_id = $1",
+		inviteID,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ExpireInvitations marks pending invitations past their expiry as expired
+// and removes expired email invitations. It returns how many of each were
+// affected.
+func (r *CompanyPostgres) ExpireInvitations() (int64, int64, error) {
+	ctx := context.Background()
+	expired, err := r.pool.Exec(ctx, `
+		UPDATE company_invitations
+		SET status = 'expired'
+		WHERE status = 'pending' AND expires_at <= NOW()
+	`)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	removed, err := r.pool.Exec(ctx, "DELETE FROM company_email_invitations WHERE expires_at <= NOW()")
+	if err != nil {
+		return 0, 0, err
+	}
+	return expired.RowsAffected(), removed.RowsAffected(), nil
+}
@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInviteLinkNotFound = errors.New("invite link not found")
+	ErrInviteLinkExpired  = errors.New("invite link has expired")
+	ErrInviteLinkUsedUp   = errors.New("invite link has reached its usage limit")
+)
+
+func (r *CompanyPostgres) CreateInviteLink(userID int64, link model.CompanyInviteLink) (model.CompanyInviteLink, error) {
+	ctx := context.Background()
+	if _, err := requireCompanyPermission(ctx, r.pool, link.CompanyID, userID, model.CompanyPermissionInvite); err != nil {
+		return model.CompanyInviteLink{}, err
+	}
+
+	query := `
+		INSERT INTO company_invite_links (company_id, code, created_by, expires_at, max_uses)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, company_id, code, created_by, expires_at, max_uses, uses, created_at
+	`
+	var created model.CompanyInviteLink
+	if err := r.pool.QueryRow(ctx, query, link.CompanyID, link.Code, userID, link.ExpiresAt, link.MaxUses).Scan(
+		&created.ID,
+		&created.CompanyID,
+		&created.Code,
+		&created.CreatedBy,
+		&created.ExpiresAt,
+		&created.MaxUses,
+		&created.Uses,
+		&created.CreatedAt,
+	); err != nil {
+		return model.CompanyInviteLink{}, err
+	}
+	return created, nil
+}
+
+// ListInviteLinks returns the company's links that can still be used.
+func (r *CompanyPostgres) ListInviteLinks(companyID int64, userID int64) ([]model.CompanyInviteLink, error) {
+	ctx := context.Background()
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionInvite); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT id, company_id, code, created_by, expires_at, max_uses, uses, created_at
+		FROM company_invite_links
+		WHERE company_id = $1
+		  AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		  AND (max_uses IS NULL OR uses < max_uses)
+		ORDER BY created_at DESC
+	`
+	rows, err := r.pool.Query(ctx, query, companyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []model.CompanyInviteLink
+	for rows.Next() {
+		var link model.CompanyInviteLink
+		if err := rows.Scan(
+			&link.ID,
+			&link.CompanyID,
+			&link.Code,
+			&link.CreatedBy,
+			&link.ExpiresAt,
+			&link.MaxUses,
+			&link.Uses,
+			&link.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+func (r *CompanyPostgres) RevokeInviteLink(companyID int64, userID int64, linkID int64) error {
+	ctx := context.Background()
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionInvite); err != nil {
+		return err
+	}
+
+	tag, err := r.pool.Exec(ctx,
+		"UPDATE company_invite_links SET revoked_at = NOW() WHERE id = $1 AND company_id = $2 AND revoked_at IS NULL",
+		linkID, companyID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInviteLinkNotFound
+	}
+	return nil
+}
+
+func (r *CompanyPostgres) GetInvitePreview(code string, userID int64) (model.CompanyInvitePreview, error) {
+	ctx := context.Background()
+	link, err := usableInviteLink(ctx, r.pool, code, false)
+	if err != nil {
+		return model.CompanyInvitePreview{}, err
+	}
+
+	query := `
+		SELECT c.id,
+		       c.name,
+		       c.description,
+		       c.avatar_url,
+		       (SELECT COUNT(*) FROM company_members cm WHERE cm.company_id = c.id) AS member_count,
+		       EXISTS (
+		           SELECT 1 FROM company_members cm
+		           WHERE cm.company_id = c.id AND cm.user_id = $2
+		       ) AS already_member
+		FROM companies c
+		WHERE c.id = $1
+	`
+	var preview model.CompanyInvitePreview
+	if err := r.pool.QueryRow(ctx, query, link.CompanyID, userID).Scan(
+		&preview.CompanyID,
+		&preview.Name,
+		&preview.Description,
+		&preview.AvatarURL,
+		&preview.MemberCount,
+		&preview.AlreadyMember,
+	); err != nil {
+		return model.CompanyInvitePreview{}, err
+	}
+	return preview, nil
+}
+
+// JoinCompanyByInviteLink adds the user to the link's company and counts the
+// use. A pending invitation of the user to that company is accepted too.
+func (r *CompanyPostgres) JoinCompanyByInviteLink(code string, userID int64) (int64, error) {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	link, err := usableInviteLink(ctx, tx, code, true)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := companyRole(ctx, tx, link.CompanyID, userID); err == nil {
+		return 0, ErrAlreadyCompanyMember
+	} else if !errors.Is(err, ErrNotCompanyMember) {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(ctx,
+		"INSERT INTO company_members (company_id, user_id, role) VALUES ($1, $2, 'member')",
+		link.CompanyID, userID,
+	); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, "UPDATE company_invite_links SET uses = uses + 1 WHERE id = $1", link.ID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE company_invitations
+		SET status = 'accepted', responded_at = NOW()
+		WHERE company_id = $1 AND invited_user_id = $2 AND status = 'pending'
+	`, link.CompanyID, userID); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return link.CompanyID, nil
+}
+
+// usableInviteLink loads a link by code and checks that it is not revoked,
+// expired or used up, and that its creator may still invite members: a link
+// stops working once they leave, lose the role or the company settings take
+// the permission away. forUpdate locks the row so concurrent joins cannot
+// exceed max_uses.
+func usableInviteLink(ctx context.Context, db queryRower, code string, forUpdate bool) (model.CompanyInviteLink, error) {
+	query := `
+		SELECT id, company_id, code, created_by, expires_at, max_uses, uses, created_at
+		FROM company_invite_links
+		WHERE code = $1 AND revoked_at IS NULL
+	`
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+
+	var link model.CompanyInviteLink
+	err := db.QueryRow(ctx, query, code).Scan(
+		&link.ID,
+		&link.CompanyID,
+		&link.Code,
+		&link.CreatedBy,
+		&link.ExpiresAt,
+		&link.MaxUses,
+		&link.Uses,
+		&link.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.CompanyInviteLink{}, ErrInviteLinkNotFound
+	}
+	if err != nil {
+		return model.CompanyInviteLink{}, err
+	}
+
+	if link.ExpiresAt != nil && !link.ExpiresAt.After(time.Now()) {
+		return model.CompanyInviteLink{}, ErrInviteLinkExpired
+	}
+	if link.MaxUses != nil && link.Uses >= *link.MaxUses {
+		return model.CompanyInviteLink{}, ErrInviteLinkUsedUp
+	}
+
+	if _, err := requireCompanyPermission(ctx, db, link.CompanyID, link.CreatedBy, model.CompanyPermissionInvite); err != nil {
+		if errors.Is(err, ErrNotCompanyMember) || errors.Is(err, ErrCompanyPermissionDenied) {
+			return model.CompanyInviteLink{}, ErrInviteLinkNotFound
+		}
+		return model.CompanyInviteLink{}, err
+	}
+	return link, nil
+}
@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrCompanyNotFound        = errors.New("company not found")
+	ErrJoinRequestNotFound    = errors.New("join request not found")
+	ErrJoinRequestAlreadySent = errors.New("join request already sent")
+	// ErrJoinRequestRejectedRecently is returned when the user's previous
+	// request was rejected within the cooldown.
+	ErrJoinRequestRejectedRecently = errors.New("join request was rejected recently")
+)
+
+// DiscoverCompanies searches listed companies by name and description,
+// largest first. An empty search returns all listed companies.
+func (r *CompanyPostgres) DiscoverCompanies(userID int64, search string, limit int, offset int) ([]model.CompanyDiscoverView, error) {
+	ctx := context.Background()
+	query := `
+		SELECT c.id,
+		       c.name,
+		       c.description,
+		       c.avatar_url,
+		       (SELECT COUNT(*) FROM company_members cm WHERE cm.company_id = c.id) AS member_count,
+		       EXISTS (
+		           SELECT 1 FROM company_members cm
+		           WHERE cm.company_id = c.id AND cm.user_id = $1
+		       ) AS is_member,
+		       jr.status AS join_request_status
+		FROM companies c
+		LEFT JOIN company_join_requests jr ON jr.company_id = c.id AND jr.user_id = $1
+		WHERE c.visibility = 'listed'
+		  AND ($2 = '' OR c.name ILIKE $2 ESCAPE '\' OR c.description ILIKE $2 ESCAPE '\')
+		ORDER BY member_count DESC, c.id
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.pool.Query(ctx, query, userID, likePattern(search), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var companies []model.CompanyDiscoverView
+	for rows.Next() {
+		var company model.CompanyDiscoverView
+		if err := rows.Scan(
+			&company.ID,
+			&company.Name,
+			&company.Description,
+			&company.AvatarURL,
+			&company.MemberCount,
+			&company.IsMember,
+			&company.JoinRequestStatus,
+		); err != nil {
+			return nil, err
+		}
+		companies = append(companies, company)
+	}
+	return companies, rows.Err()
+}
+
+// likePattern turns a search string into an ILIKE substring pattern with
+// the wildcard characters escaped.
+func likePattern(search string) string {
+	if search == "" {
+		return ""
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(search)
+	return "%" + escaped + "%"
+}
+
+// CreateJoinRequest asks to join a listed company and notifies its owner and
+// admins. A previously rejected request can be sent again if it was rejected
+// before rejectedBefore.
+func (r *CompanyPostgres) CreateJoinRequest(companyID int64, userID int64, message *string, rejectedBefore time.Time) (model.CompanyJoinRequest, error) {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return model.CompanyJoinRequest{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	var companyName string
+	err = tx.QueryRow(ctx,
+		"SELECT name FROM companies WHERE id = $1 AND visibility = 'listed'",
+		companyID,
+	).Scan(&companyName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.CompanyJoinRequest{}, ErrCompanyNotFound
+	}
+	if err != nil {
+		return model.CompanyJoinRequest{}, err
+	}
+
+	if _, err := companyRole(ctx, tx, companyID, userID); err == nil {
+		return model.CompanyJoinRequest{}, ErrAlreadyCompanyMember
+	} else if !errors.Is(err, ErrNotCompanyMember) {
+		return model.CompanyJoinRequest{}, err
+	}
+
+	var (
+		previousStatus      string
+		previousRespondedAt *time.Time
+	)
+	err = tx.QueryRow(ctx,
+		"SELECT status, responded_at FROM company_join_requests WHERE company_id = $1 AND user_id = $2 FOR UPDATE",
+		companyID, userID,
+	).Scan(&previousStatus, &previousRespondedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return model.CompanyJoinRequest{}, err
+	}
+	if err == nil && previousStatus == "rejected" && previousRespondedAt != nil && previousRespondedAt.After(rejectedBefore) {
+		return model.CompanyJoinRequest{}, ErrJoinRequestRejectedRecently
+	}
+
+	var request model.CompanyJoinRequest
+	err = tx.QueryRow(ctx, `
+		INSERT INTO company_join_requests (company_id, user_id, message)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (company_id, user_id) DO UPDATE
+		SET message = EXCLUDED.message,
+		    status = 'pending',
+		    responded_by = NULL,
+		    created_at = NOW(),
+		    responded_at = NULL
+		WHERE company_join_requests.status <> 'pending'
+		RETURNING id, company_id, user_id, message, status, responded_by, created_at, responded_at
+	`, companyID, userID, message).Scan(
+		&request.ID,
+		&request.CompanyID,
+		&request.UserID,
+		&request.Message,
+		&request.Status,
+		&request.RespondedBy,
+		&request.CreatedAt,
+		&request.RespondedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.CompanyJoinRequest{}, ErrJoinRequestAlreadySent
+	}
+	if err != nil {
+		return model.CompanyJoinRequest{}, err
+	}
+
+	var username string
+	if err := tx.QueryRow(ctx, "SELECT username FROM users WHERE id = $1", userID).Scan(&username); err != nil {
+		return model.CompanyJoinRequest{}, err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO notifications (user_id, type, title, message, related_entity_type, related_entity_id)
+		SELECT user_id, 'company_join_request', $2, $3, 'company_join_request', $4
+		FROM company_members
+		WHERE company_id = $1 AND role IN ('owner', 'admin')
+	`, companyID, "Join request", fmt.Sprintf("%s asked to join %s", username, companyName), request.ID); err != nil {
+		return model.CompanyJoinRequest{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.CompanyJoinRequest{}, err
+	}
+	return request, nil
+}
+
+// ListJoinRequests returns the pending join requests of the company, oldest
+// first.
+func (r *CompanyPostgres) ListJoinRequests(companyID int64, userID int64) ([]model.CompanyJoinRequestView, error) {
+	ctx := context.Background()
+	if _, err := requireCompanyPermission(ctx, r.pool, companyID, userID, model.CompanyPermissionManageJoinRequests); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT jr.id, jr.user_id, u.username, u.avatar_url, jr.message, jr.created_at
+		FROM company_join_requests jr
+		JOIN users u ON u.id = jr.user_id
+		WHERE jr.company_id = $1 AND jr.status = 'pending'
+		ORDER BY jr.created_at, jr.id
+	`
+	rows, err := r.pool.Query(ctx, query, companyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []model.CompanyJoinRequestView
+	for rows.Next() {
+		var request model.CompanyJoinRequestView
+		if err := rows.Scan(
+			&request.ID,
+			&request.UserID,
+			&request.Username,
+			&request.AvatarURL,
+			&request.Message,
+			&request.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+	return requests, rows.Err()
+}
+
+// RespondToJoinRequest approves or rejects a pending join request and
+// notifies the requester. Approval adds them as a member. It returns the
+// requester's id.
+func (r *CompanyPostgres) RespondToJoinRequest(companyID int64, userID int64, requestID int64, approve bool) (int64, error) {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := requireCompanyPermission(ctx, tx, companyID, userID, model.CompanyPermissionManageJoinRequests); err != nil {
+		return 0, err
+	}
+
+	status := "rejected"
+	if approve {
+		status = "approved"
+	}
+
+	var requesterID int64
+	err = tx.QueryRow(ctx, `
+		UPDATE company_join_requests
+		SET status = $1, responded_by = $2, responded_at = NOW()
+		WHERE id = $3 AND company_id = $4 AND status = 'pending'
+		RETURNING user_id
+	`, status, userID, requestID, companyID).Scan(&requesterID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrJoinRequestNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if approve {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO company_members (company_id, user_id, role)
+			VALUES ($1, $2, 'member')
+			ON CONFLICT (company_id, user_id) DO NOTHING
+		`, companyID, requesterID); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE company_invitations
+			SET status = 'accepted', responded_at = NOW()
+			WHERE company_id = $1 AND invited_user_id = $2 AND status = 'pending'
+		`, companyID, requesterID); err != nil {
+			return 0, err
+		}
+	}
+
+	var companyName string
+	if err := tx.QueryRow(ctx, "SELECT name FROM companies WHERE id = $1", companyID).Scan(&companyName); err != nil {
+		return 0, err
+	}
+	notificationType := "company_join_rejected"
+	notificationMessage := fmt.Sprintf("Your request to join %s was declined", companyName)
+	if approve {
+		notificationType = "company_join_approved"
+		notificationMessage = fmt.Sprintf("Your request to join %s was approved", companyName)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO notifications (user_id, type, title, message, related_entity_type, related_entity_id)
+		VALUES ($1, $2, 'Join request', $3, 'company', $4)
+	`, requesterID, notificationType, notificationMessage, companyID); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return requesterID, nil
+}
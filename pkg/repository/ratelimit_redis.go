@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/redis/go-redis/v9"
+)
+
+const rateLimitKeyPrefix = "ratelimit:"
+
+// slidingWindowScript keeps one sorted-set entry per accepted request and
+// admits a new one only while fewer than limit entries fall into the window.
+// It returns {allowed, count, reset_ms}.
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], 0, now - window)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', KEYS[1], window)
+local reset = window
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, count, reset}
+`)
+
+type RateLimitRedis struct {
+	client *redis.Client
+}
+
+func NewRateLimitRepository(client *redis.Client) *RateLimitRedis {
+	return &RateLimitRedis{client: client}
+}
+
+func (r *RateLimitRedis) Allow(ctx context.Context, key string, rule model.RateLimitRule) (model.RateLimitResult, error) {
+	if r.client == nil {
+		return model.RateLimitResult{}, errors.New("rate limit storage unavailable")
+	}
+
+	now := time.Now()
+	member := fmt.Sprintf("%d-%d", now.UnixNano(), rand.Uint32())
+	values, err := slidingWindowScript.Run(ctx, r.client, []string{rateLimitKeyPrefix + key},
+		now.UnixMilli(),
+		rule.Window.Milliseconds(),
+		rule.Limit,
+		member,
+	).Int64Slice()
+	if err != nil {
+		return model.RateLimitResult{}, err
+	}
+	if len(values) != 3 {
+		return model.RateLimitResult{}, errors.New("unexpected rate limit response")
+	}
+
+	remaining := rule.Limit - int(values[1])
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return model.RateLimitResult{
+		Allowed:   values[0] == 1,
+		Limit:     rule.Limit,
+		Remaining: remaining,
+		Reset:     time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
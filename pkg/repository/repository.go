@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"time"
 
 	"github.com/Sovpalo/sovpalo-backend/pkg/model"
@@ -10,19 +11,33 @@ import (
 
 type Repository struct {
 	Authorization
+	Session
+	AccessToken
 	Company
 	Event
 	Availability
 	Idea
+	Export
+	AuditLog
+	Activity
+	EmailOutbox
+	RateLimit
 }
 
 func NewRepository(pool *pgxpool.Pool, cache *redis.Client) *Repository {
 	return &Repository{
 		Authorization: NewAuthRepository(pool, cache),
+		Session:       NewSessionRepository(pool),
+		AccessToken:   NewAccessTokenRepository(pool),
 		Company:       NewCompanyRepository(pool),
 		Event:         NewEventRepository(pool),
 		Availability:  NewAvailabilityRepository(pool),
 		Idea:          NewIdeaRepository(pool),
+		Export:        NewExportRepository(pool),
+		AuditLog:      NewAuditLogRepository(pool),
+		Activity:      NewActivityRepository(pool),
+		EmailOutbox:   NewEmailOutboxRepository(pool),
+		RateLimit:     NewRateLimitRepository(cache),
 	}
 }
 
@@ -30,15 +45,58 @@ type Authorization interface {
 	UserExists(email string) (bool, error)
 	UsernameExists(username string) (bool, error)
 	CreateUser(user model.User) (int, error)
-	GetUser(email, password string) (model.User, error)
 	GetUserByEmail(email string) (model.User, error)
 	GetUserByID(userID int64) (model.User, error)
+	GetUserByTelegramID(telegramID int64) (model.User, error)
+	UpdateUserTelegramID(userID int64, telegramID *int64) error
+	GetUserByIdentity(provider string, subject string) (model.User, error)
+	CreateUserIdentity(identity model.UserIdentity) error
+	CreateUserWithIdentity(user model.User, identity model.UserIdentity) (int64, error)
+	ConvertEmailInvitations(userID int64, email string, declinedBefore time.Time) (int, error)
+	UpdateUserProfile(userID int64, input model.UpdateUserInput) error
+	UpdateUserEmail(userID int64, email string) error
 	UpdateUserAvatar(userID int64, avatarURL *string) error
+	SetPendingTOTPSecret(userID int64, secret string) error
+	EnableTOTP(userID int64, recoveryCodeHashes []string) error
+	DisableTOTP(userID int64) error
+	UseRecoveryCode(userID int64, codeHash string) (bool, error)
+	GetTokensValidAfter(userID int64) (*time.Time, error)
+	SetTokensValidAfter(userID int64, validAfter time.Time) error
+	DeleteUserAccessTokens(userID int64) error
+	ScheduleUserDeletion(userID int64) (time.Time, error)
+	CancelUserDeletion(userID int64) (bool, error)
+	ListUsersScheduledForDeletion(requestedBefore time.Time, afterID int64, limit int) ([]int64, error)
 	DeleteUser(userID int64) error
 	UpdateUserPassword(email string, passwordHash string) error
 	SavePendingAuthChallenge(challenge model.PendingAuthChallenge, ttl time.Duration) error
 	GetPendingAuthChallenge(challengeType model.AuthChallengeType, email string) (model.PendingAuthChallenge, error)
 	DeletePendingAuthChallenge(challengeType model.AuthChallengeType, email string) error
+	IncrementAuthChallengeAttempts(challengeType model.AuthChallengeType, email string, window time.Duration) (int, error)
+	ResetAuthChallengeAttempts(challengeType model.AuthChallengeType, email string) error
+	SaveTwoFactorChallenge(tokenHash string, challenge model.TwoFactorChallenge, ttl time.Duration) error
+	GetTwoFactorChallenge(tokenHash string) (model.TwoFactorChallenge, error)
+	DeleteTwoFactorChallenge(tokenHash string) error
+	IncrementTwoFactorChallengeAttempts(tokenHash string) (int, error)
+	SaveOIDCState(stateHash string, state model.OIDCAuthState, ttl time.Duration) error
+	TakeOIDCState(stateHash string) (model.OIDCAuthState, error)
+	AcquireAuthCooldown(key string, ttl time.Duration) (bool, error)
+}
+
+type Session interface {
+	CreateSession(session model.UserSession) (int64, error)
+	GetSessionByTokenHash(tokenHash string) (model.UserSession, error)
+	RotateSession(sessionID int64, next model.UserSession) (int64, error)
+	RevokeSessionFamily(familyID string) error
+	ListActiveSessions(userID int64) ([]model.UserSession, error)
+	RevokeUserSession(userID int64, sessionID int64) error
+	RevokeAllUserSessions(userID int64) error
+}
+
+type AccessToken interface {
+	CreateAccessToken(token model.PersonalAccessToken) (model.PersonalAccessToken, error)
+	ListAccessTokens(userID int64) ([]model.PersonalAccessToken, error)
+	DeleteAccessToken(userID int64, tokenID int64) error
+	UseAccessToken(tokenHash string) (model.AccessTokenIdentity, error)
 }
 
 type Company interface {
@@ -48,14 +106,37 @@ type Company interface {
 	UpdateCompany(companyID int64, userID int64, input model.CompanyUpdateInput) error
 	DeleteCompany(companyID int64, userID int64) error
 	LeaveCompany(companyID int64, userID int64, newOwnerID *int64) error
+	TransferOwnership(companyID int64, userID int64, newOwnerID int64) error
 
-	CreateInvitation(companyID int64, invitedBy int64, username string) (model.CompanyInvitation, error)
+	CreateInvitation(companyID int64, invitedBy int64, username string, expiresAt time.Time, declinedBefore time.Time) (model.CompanyInvitation, error)
 	ListInvitations(userID int64) ([]model.CompanyInvitationView, error)
-	AcceptInvitation(inviteID int64, userID int64) error
+	AcceptInvitation(inviteID int64, userID int64) (int64, error)
 	DeclineInvitation(inviteID int64, userID int64) error
+	ListSentInvitations(companyID int64, userID int64) ([]model.CompanySentInvitationView, error)
+	RevokeInvitation(companyID int64, userID int64, inviteID int64) error
+	ExpireInvitations() (int64, int64, error)
+
+	CreateInviteLink(userID int64, link model.CompanyInviteLink) (model.CompanyInviteLink, error)
+	ListInviteLinks(companyID int64, userID int64) ([]model.CompanyInviteLink, error)
+	RevokeInviteLink(companyID int64, userID int64, linkID int64) error
+	GetInvitePreview(code string, userID int64) (model.CompanyInvitePreview, error)
+	JoinCompanyByInviteLink(code string, userID int64) (int64, error)
+
+	GetInvitationSender(companyID int64, userID int64) (model.CompanyInvitationSender, error)
+	GetEmailInvitation(companyID int64, userID int64, inviteID int64) (model.CompanyEmailInvitation, error)
+	CreateEmailInvitation(invitation model.CompanyEmailInvitation, sentBefore time.Time, message model.EmailMessage) (model.CompanyEmailInvitation, error)
+
+	DiscoverCompanies(userID int64, search string, limit int, offset int) ([]model.CompanyDiscoverView, error)
+	CreateJoinRequest(companyID int64, userID int64, message *string, rejectedBefore time.Time) (model.CompanyJoinRequest, error)
+	ListJoinRequests(companyID int64, userID int64) ([]model.CompanyJoinRequestView, error)
+	RespondToJoinRequest(companyID int64, userID int64, requestID int64, approve bool) (int64, error)
+
+	GetCompanySettings(companyID int64, userID int64) (model.CompanySettings, error)
+	UpdateCompanySettings(companyID int64, userID int64, settings model.CompanySettings) (model.CompanySettings, error)
 
 	ListCompanyMembers(companyID int64, userID int64) ([]model.CompanyMemberView, error)
-	RemoveCompanyMember(companyID int64, ownerID int64, memberUserID int64) error
+	RemoveCompanyMember(companyID int64, userID int64, memberUserID int64) error
+	UpdateCompanyMemberRole(companyID int64, userID int64, memberUserID int64, role string) error
 }
 
 type Event interface {
@@ -77,6 +158,7 @@ type Availability interface {
 	DeleteAvailability(companyID int64, userID int64, availabilityID int64) error
 	ListCompanyMemberIDs(companyID int64) ([]int64, error)
 	ListAvailabilityInRange(companyID int64, start time.Time, end time.Time) ([]model.UserAvailability, error)
+	GetCompanySettings(companyID int64, userID int64) (model.CompanySettings, error)
 }
 
 type Idea interface {
@@ -87,3 +169,38 @@ type Idea interface {
 	LikeCompanyIdea(companyID int64, userID int64, ideaID int64) error
 	UnlikeCompanyIdea(companyID int64, userID int64, ideaID int64) error
 }
+
+type Export interface {
+	ListUserMemberships(userID int64) ([]model.CompanyMembershipExport, error)
+	ListUserEvents(userID int64) ([]model.Event, error)
+	ListUserRSVPs(userID int64) ([]model.EventRSVPExport, error)
+	ListUserIdeas(userID int64) ([]model.Idea, error)
+	ListUserIdeaLikes(userID int64) ([]model.IdeaLikeExport, error)
+	ListUserAvailability(userID int64) ([]model.UserAvailability, error)
+	ListUserMedia(userID int64) ([]model.MediaArchive, error)
+}
+
+type AuditLog interface {
+	CreateAuditLogEntry(entry model.AuthAuditEntry) error
+	ListUserAuditLog(userID int64, beforeID int64, limit int) ([]model.AuthAuditEntry, error)
+	GetSignInHistory(userID int64, ipAddress *string, userAgent *string) (model.SignInHistory, error)
+}
+
+type Activity interface {
+	CreateActivity(entry model.CompanyActivity) error
+	CreateUserActivity(entry model.CompanyActivity) error
+	ListCompanyActivity(companyID int64, userID int64, beforeID int64, types []string, limit int) ([]model.CompanyActivityView, error)
+}
+
+type EmailOutbox interface {
+	EnqueueEmail(message model.EmailMessage) error
+	ClaimPendingEmails(limit int, lease time.Duration) ([]model.OutboxEmail, error)
+	MarkEmailSent(emailID int64) error
+	MarkEmailFailed(emailID int64, lastError string, nextAttemptAt *time.Time) error
+	DeleteSentEmails(sentBefore time.Time) (int64, error)
+	GetEmailOutboxStats() (model.EmailOutboxStats, error)
+}
+
+type RateLimit interface {
+	Allow(ctx context.Context, key string, rule model.RateLimitRule) (model.RateLimitResult, error)
+}
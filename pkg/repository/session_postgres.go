@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/jackc/pgx/v5"
+)
+
+func (r *SessionPostgres) CreateSession(session model.UserSession) (int64, error) {
+	ctx := context.Background()
+	query := `
+		INSERT INTO user_sessions (user_id, family_id, refresh_token_hash, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5::inet, $6)
+		RETURNING id
+	`
+	var id int64
+	err := r.pool.QueryRow(ctx, query,
+		session.UserID,
+		session.FamilyID,
+		session.RefreshTokenHash,
+		session.UserAgent,
+		session.IPAddress,
+		session.ExpiresAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *SessionPostgres) GetSessionByTokenHash(tokenHash string) (model.UserSession, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, user_id, family_id, refresh_token_hash, user_agent, host(ip_address), expires_at, rotated_at, revoked_at, created_at
+		FROM user_sessions
+		WHERE refresh_token_hash = $1
+	`
+	var session model.UserSession
+	err := r.pool.QueryRow(ctx, query, tokenHash).Scan(
+		&session.ID,
+		&session.UserID,
+		&session.FamilyID,
+		&session.RefreshTokenHash,
+		&session.UserAgent,
+		&session.IPAddress,
+		&session.ExpiresAt,
+		&session.RotatedAt,
+		&session.RevokedAt,
+		&session.CreatedAt,
+	)
+	if err != nil {
+		return model.UserSession{}, err
+	}
+	return session, nil
+}
+
+// RotateSession marks the current refresh token as used and stores its
+// successor in the same family. It returns pgx.ErrNoRows when the current
+// token was already rotated or revoked by a concurrent request.
+func (r *SessionPostgres) RotateSession(sessionID int64, next model.UserSession) (int64, error) {
+	ctx := context.Background()
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE user_sessions
+		SET rotated_at = NOW()
+		WHERE id = $1 AND rotated_at IS NULL AND revoked_at IS NULL
+	`, sessionID)
+	if err != nil {
+		return 0, err
+	}
+	if tag.RowsAffected() == 0 {
+		return 0, pgx.ErrNoRows
+	}
+
+	var id int64
+	query := `
+		INSERT INTO user_sessions (user_id, family_id, refresh_token_hash, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5::inet, $6)
+		RETURNING id
+	`
+	if err := tx.QueryRow(ctx, query,
+		next.UserID,
+		next.FamilyID,
+		next.RefreshTokenHash,
+		next.UserAgent,
+		next.IPAddress,
+		next.ExpiresAt,
+	).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *SessionPostgres) RevokeSessionFamily(familyID string) error {
+	ctx := context.Background()
+	query := "UPDATE user_sessions SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL"
+	_, err := r.pool.Exec(ctx, query, familyID)
+	return err
+}
+
+func (r *SessionPostgres) ListActiveSessions(userID int64) ([]model.UserSession, error) {
+	ctx := context.Background()
+	query := `
+		SELECT id, user_id, family_id, refresh_token_hash, user_agent, host(ip_address), expires_at, rotated_at, revoked_at, created_at
+		FROM user_sessions
+		WHERE user_id = $1
+		  AND rotated_at IS NULL
+		  AND revoked_at IS NULL
+		  AND expires_at > NOW()
+		ORDER BY created_at DESC
+	`
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []model.UserSession
+	for rows.Next() {
+		var session model.UserSession
+		if err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.FamilyID,
+			&session.RefreshTokenHash,
+			&session.UserAgent,
+			&session.IPAddress,
+			&session.ExpiresAt,
+			&session.RotatedAt,
+			&session.RevokedAt,
+			&session.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (r *SessionPostgres) RevokeUserSession(userID int64, sessionID int64) error {
+	ctx := context.Background()
+	query := `
+		UPDATE user_sessions
+		SET revoked_at = NOW()
+		WHERE revoked_at IS NULL
+		  AND family_id = (
+		      SELECT family_id FROM user_sessions
+		      WHERE id = $1 AND user_id = $2 AND rotated_at IS NULL AND revoked_at IS NULL
+		  )
+	`
+	tag, err := r.pool.Exec(ctx, query, sessionID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *SessionPostgres) RevokeAllUserSessions(userID int64) error {
+	ctx := context.Background()
+	query := "UPDATE user_sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL"
+	_, err := r.pool.Exec(ctx, query, userID)
+	return err
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/Sovpalo/sovpalo-backend/pkg/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvalidAccessToken       = errors.New("invalid personal access token")
+	ErrAccessTokenNotFound      = errors.New("personal access token not found")
+	ErrInvalidAccessTokenParams = errors.New("invalid token parameters")
+)
+
+// personalAccessTokenPrefix tells personal access tokens apart from JWTs in
+// the Authorization header.
+const personalAccessTokenPrefix = "spat_"
+
+const maxAccessTokenNameLength = 100
+
+type AccessTokenService struct {
+	repo repository.AccessToken
+}
+
+func NewAccessTokenService(repo repository.AccessToken) *AccessTokenService {
+	return &AccessTokenService{repo: repo}
+}
+
+// CreateAccessToken returns the new token in plain text. Only its hash is
+// stored, so it cannot be shown again.
+func (s *AccessTokenService) CreateAccessToken(userID int64, input model.PersonalAccessTokenCreateInput) (model.PersonalAccessTokenCreated, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return model.PersonalAccessTokenCreated{}, fmt.Errorf("%w: name is required", ErrInvalidAccessTokenParams)
+	}
+	if utf8.RuneCountInString(name) > maxAccessTokenNameLength {
+		return model.PersonalAccessTokenCreated{}, fmt.Errorf("%w: name must be at most %d characters long", ErrInvalidAccessTokenParams, maxAccessTokenNameLength)
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return model.PersonalAccessTokenCreated{}, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAccessTokenParams)
+	}
+
+	scopes, err := normalizeScopes(input.Scopes)
+	if err != nil {
+		return model.PersonalAccessTokenCreated{}, err
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return model.PersonalAccessTokenCreated{}, err
+	}
+	plain := personalAccessTokenPrefix + secret
+
+	token, err := s.repo.CreateAccessToken(model.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hashToken(plain),
+		Scopes:    scopes,
+		ExpiresAt: input.ExpiresAt,
+	})
+	if err != nil {
+		return model.PersonalAccessTokenCreated{}, err
+	}
+
+	return model.PersonalAccessTokenCreated{PersonalAccessToken: token, Token: plain}, nil
+}
+
+func (s *AccessTokenService) ListAccessTokens(userID int64) ([]model.PersonalAccessToken, error) {
+	return s.repo.ListAccessTokens(userID)
+}
+
+func (s *AccessTokenService) RevokeAccessToken(userID int64, tokenID int64) error {
+	if err := s.repo.DeleteAccessToken(userID, tokenID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAccessTokenNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// AuthenticateAccessToken resolves a personal access token to its user and
+// scopes. Expired tokens and tokens of accounts scheduled for deletion are
+// rejected.
+func (s *AccessTokenService) AuthenticateAccessToken(token string) (model.AccessTokenIdentity, error) {
+	if !IsPersonalAccessToken(token) {
+		return model.AccessTokenIdentity{}, ErrInvalidAccessToken
+	}
+
+	identity, err := s.repo.UseAccessToken(hashToken(token))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.AccessTokenIdentity{}, ErrInvalidAccessToken
+		}
+		return model.AccessTokenIdentity{}, err
+	}
+	return identity, nil
+}
+
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, personalAccessTokenPrefix)
+}
+
+// RequiredScope returns the scope needed to call an endpoint of resource with
+// the given HTTP method: GET and HEAD read, everything else writes.
+func RequiredScope(resource string, method string) string {
+	if method == http.MethodGet || method == http.MethodHead {
+		return resource + ":read"
+	}
+	return resource + ":write"
+}
+
+// HasScope reports whether granted covers required. A write scope also
+// grants read access to the same resource.
+func HasScope(granted []string, required string) bool {
+	for _, scope := range granted {
+		if scope == required {
+			return true
+		}
+		if strings.HasSuffix(required, ":read") && scope == strings.TrimSuffix(required, ":read")+":write" {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeScopes(scopes []string) ([]string, error) {
+	known := make(map[string]bool, len(model.AccessTokenScopes))
+	for _, scope := range model.AccessTokenScopes {
+		known[scope] = true
+	}
+
+	seen := map[string]bool{}
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !known[scope] {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidAccessTokenParams, scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidAccessTokenParams)
+	}
+
+	sort.Strings(result)
+	return result, nil
+}
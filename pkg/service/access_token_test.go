@@ -0,0 +1,87 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/repository"
+)
+
+func TestHasScopeWriteImpliesRead(t *testing.T) {
+	granted := []string{"events:write", "availability:read"}
+
+	cases := []struct {
+		resource string
+		method   string
+		want     bool
+	}{
+		{"events", http.MethodGet, true},
+		{"events", http.MethodPost, true},
+		{"availability", http.MethodGet, true},
+		{"availability", http.MethodPatch, false},
+		{"companies", http.MethodGet, false},
+	}
+	for _, tc := range cases {
+		if got := HasScope(granted, RequiredScope(tc.resource, tc.method)); got != tc.want {
+			t.Fatalf("%s %s: expected %v, got %v", tc.method, tc.resource, tc.want, got)
+		}
+	}
+}
+
+func TestNormalizeScopes(t *testing.T) {
+	scopes, err := normalizeScopes([]string{" Events:Write", "availability:read", "events:write"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(scopes, []string{"availability:read", "events:write"}) {
+		t.Fatalf("unexpected scopes: %v", scopes)
+	}
+
+	if _, err := normalizeScopes([]string{"admin"}); !errors.Is(err, ErrInvalidAccessTokenParams) {
+		t.Fatalf("expected unknown scope to be rejected, got %v", err)
+	}
+}
+
+type revokeRepoStub struct {
+	*authRepoStub
+	deletedAccessTokens []int64
+}
+
+func (s *revokeRepoStub) SetTokensValidAfter(userID int64, validAfter time.Time) error {
+	s.validAfter[userID] = validAfter
+	return nil
+}
+
+func (s *revokeRepoStub) DeleteUserAccessTokens(userID int64) error {
+	s.deletedAccessTokens = append(s.deletedAccessTokens, userID)
+	return nil
+}
+
+type revokeSessionStub struct {
+	repository.Session
+	revoked []int64
+}
+
+func (s *revokeSessionStub) RevokeAllUserSessions(userID int64) error {
+	s.revoked = append(s.revoked, userID)
+	return nil
+}
+
+func TestRevokeAllTokensRevokesAccessTokens(t *testing.T) {
+	repo := &revokeRepoStub{authRepoStub: newAuthRepoStub()}
+	sessions := &revokeSessionStub{}
+	svc := NewAuthService(repo, sessions, nil, nil, NewMemoryMailer())
+
+	if err := svc.revokeAllTokens(7); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(repo.deletedAccessTokens, []int64{7}) {
+		t.Fatalf("expected personal access tokens to be revoked, got %v", repo.deletedAccessTokens)
+	}
+	if !reflect.DeepEqual(sessions.revoked, []int64{7}) {
+		t.Fatalf("expected sessions to be revoked, got %v", sessions.revoked)
+	}
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/Sovpalo/sovpalo-backend/pkg/repository"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultActivityLimit = 50
+	maxActivityLimit     = 100
+)
+
+var ErrInvalidActivityFilter = errors.New("invalid activity filter")
+
+type ActivityService struct {
+	repo repository.Activity
+}
+
+func NewActivityService(repo repository.Activity) *ActivityService {
+	return &ActivityService{repo: repo}
+}
+
+// ListCompanyActivity returns the company feed, newest first. A non-zero
+// beforeID continues a previous page; types keeps only entries of those
+// types.
+func (s *ActivityService) ListCompanyActivity(companyID int64, userID int64, beforeID int64, types []string, limit int) ([]model.CompanyActivityView, error) {
+	for _, activityType := range types {
+		if !slices.Contains(model.ActivityTypes, activityType) {
+			return nil, fmt.Errorf("%w: type must be one of: %s", ErrInvalidActivityFilter, strings.Join(model.ActivityTypes, ", "))
+		}
+	}
+	if limit <= 0 {
+		limit = defaultActivityLimit
+	}
+	if limit > maxActivityLimit {
+		limit = maxActivityLimit
+	}
+	return s.repo.ListCompanyActivity(companyID, userID, beforeID, types, limit)
+}
+
+// recordActivity appends an entry to the company feed. Like the audit log,
+// the feed never fails the change it describes: errors are only logged.
+func recordActivity(repo repository.Activity, companyID int64, actorID int64, activityType string, subjectID int64, details string) {
+	if repo == nil {
+		return
+	}
+	entry := newActivity(companyID, actorID, activityType, subjectID, details)
+	if err := repo.CreateActivity(entry); err != nil {
+		logrus.Errorf("failed to record %s activity in company %d: %s", activityType, companyID, err.Error())
+	}
+}
+
+// recordUserActivity appends an entry to the feeds of every company the
+// actor is a member of.
+func recordUserActivity(repo repository.Activity, actorID int64, activityType string) {
+	if repo == nil {
+		return
+	}
+	entry := newActivity(0, actorID, activityType, actorID, "")
+	if err := repo.CreateUserActivity(entry); err != nil {
+		logrus.Errorf("failed to record %s activity of user %d: %s", activityType, actorID, err.Error())
+	}
+}
+
+func newActivity(companyID int64, actorID int64, activityType string, subjectID int64, details string) model.CompanyActivity {
+	entry := model.CompanyActivity{
+		CompanyID: companyID,
+		ActorID:   &actorID,
+		Type:      activityType,
+		Details:   optionalString(details),
+	}
+	if subjectID != 0 {
+		entry.SubjectID = &subjectID
+	}
+	return entry
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/Sovpalo/sovpalo-backend/pkg/repository"
+)
+
+type activityRepoStub struct {
+	entries []model.CompanyActivity
+	types   []string
+	limit   int
+}
+
+func (s *activityRepoStub) CreateActivity(entry model.CompanyActivity) error {
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func (s *activityRepoStub) CreateUserActivity(entry model.CompanyActivity) error {
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func (s *activityRepoStub) ListCompanyActivity(companyID int64, userID int64, beforeID int64, types []string, limit int) ([]model.CompanyActivityView, error) {
+	s.types = types
+	s.limit = limit
+	return nil, nil
+}
+
+type ideaRepoStub struct {
+	repository.Idea
+}
+
+func (s ideaRepoStub) LikeCompanyIdea(companyID int64, userID int64, ideaID int64) error {
+	return nil
+}
+
+func (s ideaRepoStub) UnlikeCompanyIdea(companyID int64, userID int64, ideaID int64) error {
+	return nil
+}
+
+func assertActivityEntries(t *testing.T, entries []model.CompanyActivity, want []model.CompanyActivity) {
+	t.Helper()
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d activity entries, got %d", len(want), len(entries))
+	}
+	for i, entry := range entries {
+		expected := want[i]
+		if entry.CompanyID != expected.CompanyID || *entry.ActorID != *expected.ActorID || entry.Type != expected.Type {
+			t.Fatalf("entry %d: unexpected activity entry: %+v", i, entry)
+		}
+		if (entry.SubjectID == nil) != (expected.SubjectID == nil) || (entry.SubjectID != nil && *entry.SubjectID != *expected.SubjectID) {
+			t.Fatalf("entry %d: unexpected subject: %+v", i, entry)
+		}
+		if (entry.Details == nil) != (expected.Details == nil) || (entry.Details != nil && *entry.Details != *expected.Details) {
+			t.Fatalf("entry %d: unexpected details: %+v", i, entry)
+		}
+	}
+}
+
+func TestActivityServiceListCompanyActivity(t *testing.T) {
+	repo := &activityRepoStub{}
+	svc := NewActivityService(repo)
+
+	if _, err := svc.ListCompanyActivity(1, 1, 0, []string{model.ActivityEventCreated, "party"}, 0); !errors.Is(err, ErrInvalidActivityFilter) {
+		t.Fatalf("expected invalid filter error, got %v", err)
+	}
+
+	cases := map[int]int{0: defaultActivityLimit, 10: 10, 1000: maxActivityLimit}
+	for limit, want := range cases {
+		if _, err := svc.ListCompanyActivity(1, 1, 0, []string{model.ActivityIdeaLiked}, limit); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if repo.limit != want || len(repo.types) != 1 {
+			t.Fatalf("limit %d: got limit %d and types %v", limit, repo.limit, repo.types)
+		}
+	}
+}
+
+func TestIdeaServiceLikeRecordsActivity(t *testing.T) {
+	activity := &activityRepoStub{}
+	svc := NewIdeaService(ideaRepoStub{}, activity)
+
+	if err := svc.LikeCompanyIdea(1, 2, 3); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(activity.entries) != 1 {
+		t.Fatalf("expected one activity entry, got %d", len(activity.entries))
+	}
+	entry := activity.entries[0]
+	if entry.CompanyID != 1 || *entry.ActorID != 2 || entry.Type != model.ActivityIdeaLiked || *entry.SubjectID != 3 || entry.Details != nil {
+		t.Fatalf("unexpected activity entry: %+v", entry)
+	}
+}
+
+func TestIdeaServiceUnlikeRecordsActivity(t *testing.T) {
+	activity := &activityRepoStub{}
+	svc := NewIdeaService(ideaRepoStub{}, activity)
+
+	if err := svc.UnlikeCompanyIdea(1, 2, 3); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	assertActivityEntries(t, activity.entries, []model.CompanyActivity{
+		newActivity(1, 2, model.ActivityIdeaUnliked, 3, ""),
+	})
+}
+
+func TestCompanyServiceRecordsManagementActivity(t *testing.T) {
+	activity := &activityRepoStub{}
+	svc := NewCompanyService(&companyRepoStub{roles: map[int64]string{}}, activity)
+
+	if err := svc.UpdateCompanyMemberRole(1, 2, 3, model.CompanyRoleAdmin); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := svc.TransferOwnership(1, 2, 3); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	timezone := "Europe/Moscow"
+	if _, err := svc.UpdateCompanySettings(1, 3, model.CompanySettingsUpdateInput{Timezone: &timezone}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	assertActivityEntries(t, activity.entries, []model.CompanyActivity{
+		newActivity(1, 2, model.ActivityMemberRoleChanged, 3, model.CompanyRoleAdmin),
+		newActivity(1, 2, model.ActivityOwnershipTransferred, 3, ""),
+		newActivity(1, 3, model.ActivitySettingsUpdated, 0, ""),
+	})
+}
+
+func TestAvailabilityServiceRecordsActivity(t *testing.T) {
+	activity := &activityRepoStub{}
+	svc := NewAvailabilityService(availabilityRepoStub{}, activity)
+	input := model.AvailabilityCreateInput{
+		StartTime: time.Date(2026, 4, 8, 9, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2026, 4, 8, 10, 0, 0, 0, time.UTC),
+	}
+
+	id, err := svc.CreateAvailability(1, 2, input)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := svc.UpdateAvailability(1, 2, id, input); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := svc.DeleteAvailability(1, 2, id); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	assertActivityEntries(t, activity.entries, []model.CompanyActivity{
+		newActivity(1, 2, model.ActivityAvailabilityCreated, id, ""),
+		newActivity(1, 2, model.ActivityAvailabilityUpdated, id, ""),
+		newActivity(1, 2, model.ActivityAvailabilityDeleted, id, ""),
+	})
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultSecurityLogLimit = 50
+	maxSecurityLogLimit     = 100
+)
+
+// Sign-in methods stored in the details of sign_in entries.
+const (
+	signInMethodPassword  = "password"
+	signInMethodEmailCode = "email_code"
+	signInMethodTwoFactor = "two_factor"
+	signInMethodTelegram  = "telegram"
+)
+
+func securityAlertsEnabledFromEnv() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("SECURITY_ALERTS_ENABLED"))
+	return enabled
+}
+
+// ListSecurityLog returns the user's audit log entries, newest first. A
+// non-zero beforeID continues a previous page.
+func (s *AuthService) ListSecurityLog(userID int64, beforeID int64, limit int) ([]model.AuthAuditEntry, error) {
+	if s.auditLog == nil {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = defaultSecurityLogLimit
+	}
+	if limit > maxSecurityLogLimit {
+		limit = maxSecurityLogLimit
+	}
+	return s.auditLog.ListUserAuditLog(userID, beforeID, limit)
+}
+
+// audit appends an entry to the authentication audit log. A nil err records
+// a success, anything else a failure with the error text in the details.
+// Writing the log never fails the request: errors are only logged.
+func (s *AuthService) audit(eventType string, userID int64, meta model.SessionMeta, err error, details string) {
+	if s.auditLog == nil {
+		return
+	}
+
+	outcome := model.AuditOutcomeSuccess
+	switch {
+	case errors.Is(err, ErrTwoFactorRequired):
+		outcome = model.AuditOutcomeTwoFactorRequired
+	case err != nil:
+		outcome = model.AuditOutcomeFailure
+		if details == "" {
+			details = err.Error()
+		} else {
+			details = details + ": " + err.Error()
+		}
+	}
+
+	entry := model.AuthAuditEntry{
+		EventType: eventType,
+		Outcome:   outcome,
+		IPAddress: optionalString(meta.IPAddress),
+		UserAgent: optionalString(meta.UserAgent),
+		Details:   optionalString(details),
+	}
+	if userID != 0 {
+		entry.UserID = &userID
+	}
+
+	if err := s.auditLog.CreateAuditLogEntry(entry); err != nil {
+		logrus.Errorf("failed to write auth audit log entry %s for user %d: %s", eventType, userID, err.Error())
+	}
+}
+
+// recordSignIn audits a successful sign-in and claims email invitations
+// sent to the user's address. When security alerts are enabled the user is
+// emailed about sign-ins from an IP address or device that none of their
+// earlier sign-ins came from.
+func (s *AuthService) recordSignIn(user model.User, method string, meta model.SessionMeta) {
+	if user.Email != "" {
+		s.claimEmailInvitations(user.ID, user.Email)
+	}
+
+	if s.auditLog == nil {
+		return
+	}
+
+	if s.securityAlerts && user.Email != "" {
+		history, err := s.auditLog.GetSignInHistory(user.ID, optionalString(meta.IPAddress), optionalString(meta.UserAgent))
+		if err != nil {
+			logrus.Errorf("failed to load sign-in history of user %d: %s", user.ID, err.Error())
+		} else if history.HasSignIns && (!history.KnownIP || !history.KnownDevice) {
+			s.sendNewSignInAlert(user, meta, time.Now())
+		}
+	}
+
+	s.audit(model.AuditEventSignIn, user.ID, meta, nil, method)
+}
+
+func (s *AuthService) sendNewSignInAlert(user model.User, meta model.SessionMeta, at time.Time) {
+	message, err := renderEmail(user.Locale, emailTemplateNewSignIn, user.Email, newSignInEmailData{
+		Time:      at.UTC().Format("2006-01-02 15:04 MST"),
+		IPAddress: defaultString(meta.IPAddress, "—"),
+		Device:    defaultString(meta.UserAgent, "—"),
+	})
+	if err != nil {
+		logrus.Errorf("failed to render new sign-in alert: %s", err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.mailer.Send(ctx, message); err != nil {
+		logrus.Errorf("failed to send new sign-in alert: %s", err.Error())
+	}
+}
+
+// userIDByEmail attributes a failed attempt to an account when one exists;
+// it returns 0 otherwise.
+func (s *AuthService) userIDByEmail(email string) int64 {
+	user, err := s.repo.GetUserByEmail(email)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logrus.Errorf("failed to look up user for audit log: %s", err.Error())
+		}
+		return 0
+	}
+	return user.ID
+}
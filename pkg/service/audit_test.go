@@ -0,0 +1,118 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/Sovpalo/sovpalo-backend/pkg/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+type auditLogStub struct {
+	repository.AuditLog
+	entries []model.AuthAuditEntry
+}
+
+func (s *auditLogStub) CreateAuditLogEntry(entry model.AuthAuditEntry) error {
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func (s *authRepoStub) GetUserByEmail(email string) (model.User, error) {
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return model.User{}, pgx.ErrNoRows
+}
+
+func TestAuthServiceSignInAuditsFailedAttempts(t *testing.T) {
+	repo := newAuthRepoStub()
+	passwordHash, err := hashPassword("StrongPass1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	repo.users[1] = model.User{ID: 1, Email: "alice@example.com", Password: passwordHash}
+
+	auditLog := &auditLogStub{}
+	svc := NewAuthService(repo, nil, auditLog, nil, NewMemoryMailer())
+	meta := model.SessionMeta{IPAddress: "203.0.113.7", UserAgent: "curl/8.0"}
+
+	if _, err := svc.SignIn(model.SignInInput{Email: "alice@example.com", Password: "WrongPass1"}, meta); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected invalid credentials, got %v", err)
+	}
+	if _, err := svc.SignIn(model.SignInInput{Email: "bob@example.com", Password: "WrongPass1"}, meta); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected invalid credentials, got %v", err)
+	}
+
+	if len(auditLog.entries) != 2 {
+		t.Fatalf("expected 2 audit entries, got %d", len(auditLog.entries))
+	}
+	entry := auditLog.entries[0]
+	if entry.UserID == nil || *entry.UserID != 1 || entry.EventType != model.AuditEventSignIn || entry.Outcome != model.AuditOutcomeFailure {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+	if entry.IPAddress == nil || *entry.IPAddress != "203.0.113.7" || entry.Details == nil || *entry.Details != "password: invalid email or password" {
+		t.Fatalf("unexpected entry details: %+v", entry)
+	}
+	if auditLog.entries[1].UserID != nil {
+		t.Fatalf("expected unknown account to be logged without user, got %+v", auditLog.entries[1])
+	}
+}
+
+func TestAuthServiceCodeSignInAuditsIncorrectCode(t *testing.T) {
+	repo := newAuthRepoStub()
+	repo.users[1] = model.User{ID: 1, Email: "alice@example.com"}
+	repo.challenges[model.AuthChallengeTypeLogin] = model.PendingAuthChallenge{
+		Type:      model.AuthChallengeTypeLogin,
+		Email:     "alice@example.com",
+		Code:      "1234",
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	auditLog := &auditLogStub{}
+	svc := NewAuthService(repo, nil, auditLog, nil, NewMemoryMailer())
+
+	_, err := svc.VerifyCodeSignIn(model.SignInCodeVerifyInput{Email: "alice@example.com", Code: "0000"}, model.SessionMeta{})
+	if !errors.Is(err, ErrIncorrectVerificationCode) {
+		t.Fatalf("expected incorrect code error, got %v", err)
+	}
+
+	if len(auditLog.entries) != 1 {
+		t.Fatalf("expected 1 audit entry, got %d", len(auditLog.entries))
+	}
+	entry := auditLog.entries[0]
+	if entry.UserID == nil || *entry.UserID != 1 || entry.Outcome != model.AuditOutcomeFailure || entry.IPAddress != nil {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+}
+
+type claimRepoStub struct {
+	*authRepoStub
+	claimed        []string
+	declinedBefore time.Time
+}
+
+func (s *claimRepoStub) ConvertEmailInvitations(userID int64, email string, declinedBefore time.Time) (int, error) {
+	s.claimed = append(s.claimed, email)
+	s.declinedBefore = declinedBefore
+	return 0, nil
+}
+
+func TestAuthServiceSignInClaimsEmailInvitations(t *testing.T) {
+	repo := &claimRepoStub{authRepoStub: newAuthRepoStub()}
+	svc := NewAuthService(repo, nil, nil, nil, NewMemoryMailer())
+
+	svc.recordSignIn(model.User{ID: 1, Email: "alice@example.com"}, signInMethodPassword, model.SessionMeta{})
+	svc.recordSignIn(model.User{ID: 2}, signInMethodTelegram, model.SessionMeta{})
+
+	if len(repo.claimed) != 1 || repo.claimed[0] != "alice@example.com" {
+		t.Fatalf("expected invitations of alice@example.com to be claimed, got %v", repo.claimed)
+	}
+	if cooldown := time.Since(repo.declinedBefore); cooldown < defaultReinviteCooldown || cooldown > defaultReinviteCooldown+time.Minute {
+		t.Fatalf("expected the re-invite cooldown to apply, got declinedBefore %s ago", cooldown)
+	}
+}
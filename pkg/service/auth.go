@@ -3,7 +3,7 @@ package service
 import (
 	"context"
 	"crypto/rand"
-	"crypto/sha1"
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -11,25 +11,32 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/Sovpalo/sovpalo-backend/pkg/model"
 	"github.com/Sovpalo/sovpalo-backend/pkg/repository"
-	"github.com/dgrijalva/jwt-go"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/jackc/pgx/v5"
 	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
 )
 
 var (
 	ErrInvalidPassword             = errors.New("invalid password")
 	ErrInvalidCredentials          = errors.New("invalid email or password")
+	ErrIncorrectCurrentPassword    = errors.New("current password is incorrect")
+	ErrRecentSignInRequired        = errors.New("recent sign-in required")
+	ErrTokenRevoked                = errors.New("token has been revoked")
 	ErrUserAlreadyExists           = errors.New("user with this email already exists")
 	ErrUsernameAlreadyExists       = errors.New("user with this username already exists")
 	ErrUserNotFound                = errors.New("user not found")
 	ErrPendingRegistrationNotFound = errors.New("pending registration not found")
 	ErrVerificationCodeExpired     = errors.New("verification code expired")
 	ErrIncorrectVerificationCode   = errors.New("incorrect verification code")
+	ErrTooManyAttempts             = errors.New("too many attempts")
+	ErrResendTooSoon               = errors.New("verification code was requested too recently")
 	ErrAvatarTooLarge              = errors.New("avatar file is too large")
 	ErrAvatarInvalidType           = errors.New("avatar must be a png, jpeg, webp or gif image")
 )
@@ -37,44 +44,101 @@ var (
 const maxAvatarSize = 5 << 20
 
 type AuthService struct {
-	repo         repository.Authorization
-	jwtSecret    []byte
-	passwordSalt string
-	pendingTTL   time.Duration
-}
-
-func NewAuthService(repo repository.Authorization) *AuthService {
-	return &AuthService{
-		repo:         repo,
-		jwtSecret:    []byte(os.Getenv("JWT_SECRET")),
-		passwordSalt: os.Getenv("PASSWORD_SALT"),
-		pendingTTL:   10 * time.Minute,
+	repo                repository.Authorization
+	sessions            repository.Session
+	auditLog            repository.AuditLog
+	activity            repository.Activity
+	mailer              Mailer
+	keys                *KeyManager
+	keysErr             error
+	telegramBotToken    string
+	telegramAuthMaxAge  time.Duration
+	oidcProviders       map[string]*oidcProvider
+	passwordSalt        string
+	pendingTTL          time.Duration
+	accessTTL           time.Duration
+	refreshTTL          time.Duration
+	twoFactorTTL        time.Duration
+	maxCodeAttempts     int
+	reauthMaxAge        time.Duration
+	resendEmailCooldown time.Duration
+	resendIPCooldown    time.Duration
+	deletionGracePeriod time.Duration
+	reinviteCooldown    time.Duration
+	securityAlerts      bool
+}
+
+func NewAuthService(repo repository.Authorization, sessions repository.Session, auditLog repository.AuditLog, activity repository.Activity, mailer Mailer) *AuthService {
+	keys, keysErr := NewKeyManagerFromEnv()
+	if keysErr != nil {
+		logrus.Errorf("failed to load JWT keys: %s", keysErr.Error())
 	}
-}
 
+	return &AuthService{
+		repo:                repo,
+		sessions:            sessions,
+		auditLog:            auditLog,
+		activity:            activity,
+		mailer:              mailer,
+		keys:                keys,
+		keysErr:             keysErr,
+		telegramBotToken:    os.Getenv("TELEGRAM_BOT_TOKEN"),
+		telegramAuthMaxAge:  24 * time.Hour,
+		oidcProviders:       oidcProvidersFromEnv(),
+		passwordSalt:        os.Getenv("PASSWORD_SALT"),
+		pendingTTL:          10 * time.Minute,
+		accessTTL:           15 * time.Minute,
+		refreshTTL:          30 * 24 * time.Hour,
+		twoFactorTTL:        5 * time.Minute,
+		maxCodeAttempts:     5,
+		reauthMaxAge:        10 * time.Minute,
+		resendEmailCooldown: time.Minute,
+		resendIPCooldown:    10 * time.Second,
+		deletionGracePeriod: durationFromEnv("ACCOUNT_DELETION_GRACE_PERIOD", defaultDeletionGracePeriod, 0),
+		reinviteCooldown:    durationFromEnv("INVITATION_REINVITE_COOLDOWN", defaultReinviteCooldown, 0),
+		securityAlerts:      securityAlertsEnabledFromEnv(),
+	}
+}
+
+const tokenIssuer = "sovpalo"
+
+// tokenClaims are the claims of an access token. AuthTime is only set on
+// tokens issued by a sign-in, not by a refresh.
 type tokenClaims struct {
-	jwt.StandardClaims
-	UserId int `json:"user_id"`
+	jwt.RegisteredClaims
+	UserId   int              `json:"user_id"`
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"`
 }
 
-func (s *AuthService) ParseToken(accessToken string) (int, error) {
-	if len(s.jwtSecret) == 0 {
-		return 0, errors.New("JWT_SECRET not set")
+func (s *AuthService) ParseToken(accessToken string) (model.TokenIdentity, error) {
+	if s.keys == nil {
+		return model.TokenIdentity{}, s.keysErr
 	}
-	token, err := jwt.ParseWithClaims(accessToken, &tokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("Invalid signing method")
-		}
-		return s.jwtSecret, nil
-	})
+
+	var claims tokenClaims
+	if err := s.keys.Parse(accessToken, &claims); err != nil {
+		return model.TokenIdentity{}, err
+	}
+	if claims.IssuedAt == nil {
+		return model.TokenIdentity{}, errors.New("Invalid token claims")
+	}
+
+	validAfter, err := s.repo.GetTokensValidAfter(int64(claims.UserId))
 	if err != nil {
-		return 0, err
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.TokenIdentity{}, ErrUserNotFound
+		}
+		return model.TokenIdentity{}, err
 	}
-	claims, ok := token.Claims.(*tokenClaims)
-	if !ok {
-		return 0, errors.New("Invalid token claims")
+	if validAfter != nil && claims.IssuedAt.Unix() < validAfter.Unix() {
+		return model.TokenIdentity{}, ErrTokenRevoked
+	}
+
+	identity := model.TokenIdentity{UserID: claims.UserId}
+	if claims.AuthTime != nil {
+		identity.AuthTime = claims.AuthTime.Time
 	}
-	return claims.UserId, nil
+	return identity, nil
 }
 
 func (s *AuthService) UserExists(email string) (bool, error) {
@@ -94,14 +158,10 @@ func (s *AuthService) GetProfile(userID int64) (model.UserProfile, error) {
 		return model.UserProfile{}, err
 	}
 
-	return model.UserProfile{
-		Email:     user.Email,
-		Username:  user.Username,
-		AvatarURL: user.AvatarURL,
-	}, nil
+	return userProfile(user), nil
 }
 
-func (s *AuthService) UpdateAvatar(userID int64, fileName string, fileData []byte) (model.UserProfile, error) {
+func (s *AuthService) UpdateAvatar(userID int64, fileName string, fileData []byte, meta model.SessionMeta) (model.UserProfile, error) {
 	user, err := s.repo.GetUserByID(userID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -126,15 +186,14 @@ func (s *AuthService) UpdateAvatar(userID int64, fileName string, fileData []byt
 	if user.AvatarURL != nil && *user.AvatarURL != avatarURL {
 		_ = removeAvatarByURL(*user.AvatarURL)
 	}
+	s.audit(model.AuditEventAvatarChange, userID, meta, nil, "")
+	recordUserActivity(s.activity, userID, model.ActivityProfileUpdated)
 
-	return model.UserProfile{
-		Email:     user.Email,
-		Username:  user.Username,
-		AvatarURL: &avatarURL,
-	}, nil
+	user.AvatarURL = &avatarURL
+	return userProfile(user), nil
 }
 
-func (s *AuthService) DeleteAvatar(userID int64) (model.UserProfile, error) {
+func (s *AuthService) DeleteAvatar(userID int64, meta model.SessionMeta) (model.UserProfile, error) {
 	user, err := s.repo.GetUserByID(userID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -153,22 +212,11 @@ func (s *AuthService) DeleteAvatar(userID int64) (model.UserProfile, error) {
 	if user.AvatarURL != nil {
 		_ = removeAvatarByURL(*user.AvatarURL)
 	}
+	s.audit(model.AuditEventAvatarDelete, userID, meta, nil, "")
+	recordUserActivity(s.activity, userID, model.ActivityProfileUpdated)
 
-	return model.UserProfile{
-		Email:    user.Email,
-		Username: user.Username,
-	}, nil
-}
-
-func (s *AuthService) DeleteUser(userID int64) error {
-	if err := s.repo.DeleteUser(userID); err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return ErrUserNotFound
-		}
-		return err
-	}
-
-	return nil
+	user.AvatarURL = nil
+	return userProfile(user), nil
 }
 
 func saveAvatarFile(userID int64, fileName string, fileData []byte) (string, error) {
@@ -269,12 +317,30 @@ func randomHex(size int) (string, error) {
 	return fmt.Sprintf("%x", buf), nil
 }
 
-func (s *AuthService) SendCodeToEmail(to string, code string) error {
-	body := fmt.Sprintf("Your verification code is %s. It expires in %d minutes.", code, int(s.pendingTTL.Minutes()))
+// challengeEmailTemplates maps a challenge to the email that carries its
+// code.
+var challengeEmailTemplates = map[model.AuthChallengeType]string{
+	model.AuthChallengeTypeSignUp:        emailTemplateVerification,
+	model.AuthChallengeTypeLogin:         emailTemplateSignInCode,
+	model.AuthChallengeTypePasswordReset: emailTemplatePasswordReset,
+	model.AuthChallengeTypeEmailChange:   emailTemplateEmailChange,
+}
+
+// sendChallengeCode emails the challenge code in the locale stored with the
+// challenge.
+func (s *AuthService) sendChallengeCode(challenge model.PendingAuthChallenge) error {
+	message, err := renderEmail(challenge.Locale, challengeEmailTemplates[challenge.Type], challenge.Email, codeEmailData{
+		Code:             challenge.Code,
+		ExpiresInMinutes: int(s.pendingTTL.Minutes()),
+	})
+	if err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := sendSMTPTextEmail(ctx, to, "Sovpalo verification code", body); err != nil {
+	if err := s.mailer.Send(ctx, message); err != nil {
 		return fmt.Errorf("error sending email: %w", err)
 	}
 
@@ -290,44 +356,104 @@ func (s *AuthService) GenerateCode() string {
 }
 
 func (s *AuthService) GenerateToken(email, password string) (string, error) {
-	if len(s.jwtSecret) == 0 {
-		return "", errors.New("JWT_SECRET not set")
-	}
-	passwordHash, err := s.generatePasswordHash(password)
+	result, err := s.SignIn(model.SignInInput{Email: email, Password: password}, model.SessionMeta{})
 	if err != nil {
 		return "", err
 	}
-	return s.generateTokenForUser(email, passwordHash)
+	if result.AuthTokens == nil {
+		return "", ErrTwoFactorRequired
+	}
+	return result.Token, nil
 }
 
-func (s *AuthService) SignIn(input model.SignInInput) (string, error) {
-	passwordHash, err := s.generatePasswordHash(input.Password)
+func (s *AuthService) SignIn(input model.SignInInput, meta model.SessionMeta) (model.SignInResult, error) {
+	user, err := s.repo.GetUserByEmail(input.Email)
 	if err != nil {
-		return "", err
+		if errors.Is(err, pgx.ErrNoRows) {
+			_, _, _ = s.verifyPassword(input.Password, "")
+			s.audit(model.AuditEventSignIn, 0, meta, ErrInvalidCredentials, signInMethodPassword)
+			return model.SignInResult{}, ErrInvalidCredentials
+		}
+		return model.SignInResult{}, err
+	}
+
+	ok, needsRehash, err := s.verifyPassword(input.Password, user.Password)
+	if err != nil {
+		return model.SignInResult{}, err
+	}
+	if !ok {
+		s.audit(model.AuditEventSignIn, user.ID, meta, ErrInvalidCredentials, signInMethodPassword)
+		return model.SignInResult{}, ErrInvalidCredentials
+	}
+
+	if needsRehash {
+		if passwordHash, err := hashPassword(input.Password); err == nil {
+			_ = s.repo.UpdateUserPassword(user.Email, passwordHash)
+		}
+	}
+
+	return s.completeSignIn(user, signInMethodPassword, meta)
+}
+
+// StartCodeSignIn emails a one-time code that signs the user in without a
+// password. The answer is the same whether or not the email has an account,
+// and a new code can be requested once per resend cooldown.
+func (s *AuthService) StartCodeSignIn(input model.SignInCodeInput) error {
+	acquired, err := s.repo.AcquireAuthCooldown(fmt.Sprintf("email:%s:%s", model.AuthChallengeTypeLogin, input.Email), s.resendEmailCooldown)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return ErrResendTooSoon
 	}
 
-	if _, err := s.repo.GetUser(input.Email, passwordHash); err != nil {
+	user, err := s.repo.GetUserByEmail(input.Email)
+	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return "", ErrInvalidCredentials
+			return nil
 		}
-		return "", err
+		return err
 	}
 
-	return s.generateTokenForUser(input.Email, passwordHash)
+	return s.startChallenge(model.PendingAuthChallenge{
+		Type:   model.AuthChallengeTypeLogin,
+		Email:  input.Email,
+		Locale: user.Locale,
+	})
 }
 
-func (s *AuthService) generatePasswordHash(password string) (string, error) {
-	if s.passwordSalt == "" {
-		return "", errors.New("PASSWORD_SALT not set")
+func (s *AuthService) VerifyCodeSignIn(input model.SignInCodeVerifyInput, meta model.SessionMeta) (model.SignInResult, error) {
+	challenge, err := s.verifyChallenge(model.AuthChallengeTypeLogin, input.Email, input.Code)
+	if err != nil {
+		s.audit(model.AuditEventSignIn, s.userIDByEmail(input.Email), meta, err, signInMethodEmailCode)
+		// an email without an account never has a pending code
+		if errors.Is(err, ErrPendingRegistrationNotFound) {
+			return model.SignInResult{}, ErrIncorrectVerificationCode
+		}
+		return model.SignInResult{}, err
 	}
-	hash := sha1.New()
-	hash.Write([]byte(password))
 
-	return fmt.Sprintf("%x", hash.Sum([]byte(s.passwordSalt))), nil
+	if err := s.repo.DeletePendingAuthChallenge(model.AuthChallengeTypeLogin, input.Email); err != nil {
+		return model.SignInResult{}, err
+	}
+
+	user, err := s.repo.GetUserByEmail(challenge.Email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.SignInResult{}, ErrUserNotFound
+		}
+		return model.SignInResult{}, err
+	}
+
+	return s.completeSignIn(user, signInMethodEmailCode, meta)
+}
+
+func (s *AuthService) ResendSignInCode(email string, clientIP string) error {
+	return s.resendChallenge(model.AuthChallengeTypeLogin, email, clientIP)
 }
 
 func (s *AuthService) CreateUser(user model.User) (int, error) {
-	passwordHash, err := s.generatePasswordHash(user.Password)
+	passwordHash, err := hashPassword(user.Password)
 	if err != nil {
 		return 0, err
 	}
@@ -344,7 +470,7 @@ func (s *AuthService) StartRegistration(input model.SignUpInput) error {
 		return err
 	}
 
-	passwordHash, err := s.generatePasswordHash(input.Password)
+	passwordHash, err := hashPassword(input.Password)
 	if err != nil {
 		return err
 	}
@@ -354,40 +480,51 @@ func (s *AuthService) StartRegistration(input model.SignUpInput) error {
 		Email:        input.Email,
 		Username:     input.Username,
 		PasswordHash: passwordHash,
+		Locale:       normalizeLocale(input.Locale),
 	})
 }
 
-func (s *AuthService) VerifyRegistration(input model.SignUpVerifyInput) (string, error) {
+func (s *AuthService) VerifyRegistration(input model.SignUpVerifyInput, meta model.SessionMeta) (model.AuthTokens, error) {
 	challenge, err := s.verifyChallenge(model.AuthChallengeTypeSignUp, input.Email, input.Code)
 	if err != nil {
-		return "", err
+		s.audit(model.AuditEventSignUp, 0, meta, err, "")
+		return model.AuthTokens{}, err
 	}
 
 	if err := s.ensureEmailAndUsernameAvailable(challenge.Email, challenge.Username); err != nil {
-		return "", err
+		return model.AuthTokens{}, err
 	}
 
-	if _, err := s.repo.CreateUser(model.User{
+	userID, err := s.repo.CreateUser(model.User{
 		Email:    challenge.Email,
 		Username: challenge.Username,
 		Password: challenge.PasswordHash,
-	}); err != nil {
-		return "", err
+		Locale:   challenge.Locale,
+	})
+	if err != nil {
+		return model.AuthTokens{}, err
 	}
 
 	if err := s.repo.DeletePendingAuthChallenge(model.AuthChallengeTypeSignUp, input.Email); err != nil {
-		return "", err
+		return model.AuthTokens{}, err
 	}
+	s.claimEmailInvitations(int64(userID), challenge.Email)
 
-	return s.generateTokenForUser(challenge.Email, challenge.PasswordHash)
+	tokens, err := s.issueTokens(int64(userID), meta)
+	if err != nil {
+		return model.AuthTokens{}, err
+	}
+	s.audit(model.AuditEventSignUp, int64(userID), meta, nil, "")
+	return tokens, nil
 }
 
-func (s *AuthService) ResendRegistrationCode(email string) error {
-	return s.resendChallenge(model.AuthChallengeTypeSignUp, email)
+func (s *AuthService) ResendRegistrationCode(email string, clientIP string) error {
+	return s.resendChallenge(model.AuthChallengeTypeSignUp, email, clientIP)
 }
 
 func (s *AuthService) StartPasswordReset(email string) error {
-	if _, err := s.repo.GetUserByEmail(email); err != nil {
+	user, err := s.repo.GetUserByEmail(email)
+	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return ErrUserNotFound
 		}
@@ -395,22 +532,24 @@ func (s *AuthService) StartPasswordReset(email string) error {
 	}
 
 	return s.startChallenge(model.PendingAuthChallenge{
-		Type:  model.AuthChallengeTypePasswordReset,
-		Email: email,
+		Type:   model.AuthChallengeTypePasswordReset,
+		Email:  email,
+		Locale: user.Locale,
 	})
 }
 
-func (s *AuthService) VerifyPasswordReset(input model.ResetPasswordVerifyInput) error {
+func (s *AuthService) VerifyPasswordReset(input model.ResetPasswordVerifyInput, meta model.SessionMeta) error {
 	if err := validatePassword(input.NewPassword); err != nil {
 		return err
 	}
 
 	challenge, err := s.verifyChallenge(model.AuthChallengeTypePasswordReset, input.Email, input.Code)
 	if err != nil {
+		s.audit(model.AuditEventPasswordReset, s.userIDByEmail(input.Email), meta, err, "")
 		return err
 	}
 
-	passwordHash, err := s.generatePasswordHash(input.NewPassword)
+	passwordHash, err := hashPassword(input.NewPassword)
 	if err != nil {
 		return err
 	}
@@ -419,36 +558,93 @@ func (s *AuthService) VerifyPasswordReset(input model.ResetPasswordVerifyInput)
 		return err
 	}
 
-	return s.repo.DeletePendingAuthChallenge(model.AuthChallengeTypePasswordReset, input.Email)
+	if err := s.repo.DeletePendingAuthChallenge(model.AuthChallengeTypePasswordReset, input.Email); err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetUserByEmail(challenge.Email)
+	if err != nil {
+		return err
+	}
+	if err := s.revokeAllTokens(user.ID); err != nil {
+		return err
+	}
+
+	s.audit(model.AuditEventPasswordReset, user.ID, meta, nil, "")
+	return nil
 }
 
-func (s *AuthService) ResendPasswordResetCode(email string) error {
-	return s.resendChallenge(model.AuthChallengeTypePasswordReset, email)
+// ChangePassword replaces the password of a signed-in user. Every existing
+// session and access token of the user is revoked, and the caller gets a
+// fresh token pair so the current device stays signed in.
+func (s *AuthService) ChangePassword(userID int64, input model.ChangePasswordInput, meta model.SessionMeta) (model.AuthTokens, error) {
+	user, err := s.reauthenticate(userID, input.CurrentPassword)
+	if err != nil {
+		if errors.Is(err, ErrIncorrectCurrentPassword) {
+			s.audit(model.AuditEventPasswordChange, userID, meta, err, "")
+		}
+		return model.AuthTokens{}, err
+	}
+
+	if err := validatePassword(input.NewPassword); err != nil {
+		return model.AuthTokens{}, err
+	}
+
+	passwordHash, err := hashPassword(input.NewPassword)
+	if err != nil {
+		return model.AuthTokens{}, err
+	}
+
+	if err := s.repo.UpdateUserPassword(user.Email, passwordHash); err != nil {
+		return model.AuthTokens{}, err
+	}
+
+	if err := s.revokeAllTokens(userID); err != nil {
+		return model.AuthTokens{}, err
+	}
+	s.audit(model.AuditEventPasswordChange, userID, meta, nil, "")
+
+	return s.issueTokens(userID, meta)
+}
+
+func (s *AuthService) ResendPasswordResetCode(email string, clientIP string) error {
+	return s.resendChallenge(model.AuthChallengeTypePasswordReset, email, clientIP)
 }
 
 func (s *AuthService) PendingRegistrationTTL() time.Duration {
 	return s.pendingTTL
 }
 
-func (s *AuthService) generateTokenForUser(email, passwordHash string) (string, error) {
-	if len(s.jwtSecret) == 0 {
-		return "", errors.New("JWT_SECRET not set")
+// generateTokenForUser issues an access token. authTime is the time of the
+// sign-in the token is issued for, or zero for a refresh.
+func (s *AuthService) generateTokenForUser(userID int64, authTime time.Time) (string, error) {
+	if s.keys == nil {
+		return "", s.keysErr
 	}
 
-	user, err := s.repo.GetUser(email, passwordHash)
-	if err != nil {
-		return "", err
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &tokenClaims{
-		jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(720 * time.Hour).Unix(),
-			IssuedAt:  time.Now().Unix(),
+	now := time.Now()
+	claims := &tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			Audience:  s.keys.Audience(),
+			Subject:   strconv.FormatInt(userID, 10),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.accessTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
-		int(user.ID),
-	})
+		UserId: int(userID),
+	}
+	if !authTime.IsZero() {
+		claims.AuthTime = jwt.NewNumericDate(authTime)
+	}
+	return s.keys.Sign(claims)
+}
 
-	return token.SignedString(s.jwtSecret)
+// JWKS returns the public keys that verify access tokens.
+func (s *AuthService) JWKS() (model.JSONWebKeySet, error) {
+	if s.keys == nil {
+		return model.JSONWebKeySet{}, s.keysErr
+	}
+	return s.keys.JWKS(), nil
 }
 
 func (s *AuthService) ensureEmailAndUsernameAvailable(email, username string) error {
@@ -479,16 +675,31 @@ func (s *AuthService) startChallenge(challenge model.PendingAuthChallenge) error
 		return err
 	}
 
-	if err := s.SendCodeToEmail(challenge.Email, challenge.Code); err != nil {
-		_ = s.repo.DeletePendingAuthChallenge(challenge.Type, challenge.Email)
+	if err := s.sendChallengeCode(challenge); err != nil {
+		_ = s.repo.DeletePendingAuthChallenge(challenge.Type, challenge.Subject())
 		return err
 	}
 
 	return nil
 }
 
-func (s *AuthService) verifyChallenge(challengeType model.AuthChallengeType, email, code string) (model.PendingAuthChallenge, error) {
-	challenge, err := s.repo.GetPendingAuthChallenge(challengeType, email)
+// verifyChallenge counts the attempt before looking at the code, so parallel
+// requests cannot get past the attempt cap. subject is the challenge's
+// model.AuthChallengeSubject.
+func (s *AuthService) verifyChallenge(challengeType model.AuthChallengeType, subject, code string) (model.PendingAuthChallenge, error) {
+	attempts, err := s.repo.IncrementAuthChallengeAttempts(challengeType, subject, s.pendingTTL)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return model.PendingAuthChallenge{}, ErrPendingRegistrationNotFound
+		}
+		return model.PendingAuthChallenge{}, err
+	}
+	if attempts > s.maxCodeAttempts {
+		_ = s.repo.DeletePendingAuthChallenge(challengeType, subject)
+		return model.PendingAuthChallenge{}, ErrTooManyAttempts
+	}
+
+	challenge, err := s.repo.GetPendingAuthChallenge(challengeType, subject)
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return model.PendingAuthChallenge{}, ErrPendingRegistrationNotFound
@@ -497,21 +708,52 @@ func (s *AuthService) verifyChallenge(challengeType model.AuthChallengeType, ema
 	}
 
 	if time.Now().After(challenge.ExpiresAt) {
-		_ = s.repo.DeletePendingAuthChallenge(challengeType, email)
+		_ = s.repo.DeletePendingAuthChallenge(challengeType, subject)
 		return model.PendingAuthChallenge{}, ErrVerificationCodeExpired
 	}
 
-	if challenge.Code != code {
+	if subtle.ConstantTimeCompare([]byte(challenge.Code), []byte(code)) != 1 {
+		if attempts >= s.maxCodeAttempts {
+			_ = s.repo.DeletePendingAuthChallenge(challengeType, subject)
+			return model.PendingAuthChallenge{}, ErrTooManyAttempts
+		}
 		return model.PendingAuthChallenge{}, ErrIncorrectVerificationCode
 	}
 
+	if err := s.repo.ResetAuthChallengeAttempts(challengeType, subject); err != nil {
+		return model.PendingAuthChallenge{}, err
+	}
 	return challenge, nil
 }
 
-func (s *AuthService) resendChallenge(challengeType model.AuthChallengeType, email string) error {
+// resendChallenge sends a new code for a pending challenge. The cooldowns are
+// taken first, so that for sign-in codes an email without an account looks
+// the same as one with a pending code.
+func (s *AuthService) resendChallenge(challengeType model.AuthChallengeType, email string, clientIP string) error {
+	if clientIP != "" {
+		acquired, err := s.repo.AcquireAuthCooldown("ip:"+clientIP, s.resendIPCooldown)
+		if err != nil {
+			return err
+		}
+		if !acquired {
+			return ErrResendTooSoon
+		}
+	}
+
+	acquired, err := s.repo.AcquireAuthCooldown(fmt.Sprintf("email:%s:%s", challengeType, email), s.resendEmailCooldown)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return ErrResendTooSoon
+	}
+
 	challenge, err := s.repo.GetPendingAuthChallenge(challengeType, email)
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
+			if challengeType == model.AuthChallengeTypeLogin {
+				return nil
+			}
 			return ErrPendingRegistrationNotFound
 		}
 		return err
@@ -524,7 +766,7 @@ func (s *AuthService) resendChallenge(challengeType model.AuthChallengeType, ema
 		return err
 	}
 
-	return s.SendCodeToEmail(email, challenge.Code)
+	return s.sendChallengeCode(challenge)
 }
 
 func defaultString(value, fallback string) string {
@@ -538,6 +780,9 @@ func validatePassword(password string) error {
 	if len(password) < 8 {
 		return fmt.Errorf("%w: password must be at least 8 characters long", ErrInvalidPassword)
 	}
+	if len(password) > maxPasswordLength {
+		return fmt.Errorf("%w: password must be at most %d bytes long", ErrInvalidPassword, maxPasswordLength)
+	}
 
 	hasLower := false
 	hasUpper := false
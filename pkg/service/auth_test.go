@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/Sovpalo/sovpalo-backend/pkg/repository"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+type authRepoStub struct {
+	repository.Authorization
+	users      map[int64]model.User
+	validAfter map[int64]time.Time
+	challenges map[model.AuthChallengeType]model.PendingAuthChallenge
+	attempts   map[model.AuthChallengeType]int
+	cooldowns  map[string]bool
+}
+
+func newAuthRepoStub() *authRepoStub {
+	return &authRepoStub{
+		users:      map[int64]model.User{},
+		validAfter: map[int64]time.Time{},
+		challenges: map[model.AuthChallengeType]model.PendingAuthChallenge{},
+		attempts:   map[model.AuthChallengeType]int{},
+		cooldowns:  map[string]bool{},
+	}
+}
+
+func (s *authRepoStub) UserExists(email string) (bool, error) {
+	for _, user := range s.users {
+		if user.Email == email {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *authRepoStub) GetUserByID(userID int64) (model.User, error) {
+	user, ok := s.users[userID]
+	if !ok {
+		return model.User{}, pgx.ErrNoRows
+	}
+	return user, nil
+}
+
+func (s *authRepoStub) UpdateUserEmail(userID int64, email string) error {
+	user, ok := s.users[userID]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	user.Email = email
+	s.users[userID] = user
+	return nil
+}
+
+func (s *authRepoStub) GetTokensValidAfter(userID int64) (*time.Time, error) {
+	validAfter, ok := s.validAfter[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &validAfter, nil
+}
+
+func (s *authRepoStub) SavePendingAuthChallenge(challenge model.PendingAuthChallenge, ttl time.Duration) error {
+	s.challenges[challenge.Type] = challenge
+	delete(s.attempts, challenge.Type)
+	return nil
+}
+
+func (s *authRepoStub) GetPendingAuthChallenge(challengeType model.AuthChallengeType, email string) (model.PendingAuthChallenge, error) {
+	challenge, ok := s.challenges[challengeType]
+	if !ok {
+		return model.PendingAuthChallenge{}, redis.Nil
+	}
+	return challenge, nil
+}
+
+func (s *authRepoStub) DeletePendingAuthChallenge(challengeType model.AuthChallengeType, email string) error {
+	delete(s.challenges, challengeType)
+	return nil
+}
+
+func (s *authRepoStub) IncrementAuthChallengeAttempts(challengeType model.AuthChallengeType, email string, window time.Duration) (int, error) {
+	if _, ok := s.challenges[challengeType]; !ok {
+		return 0, redis.Nil
+	}
+	s.attempts[challengeType]++
+	return s.attempts[challengeType], nil
+}
+
+func (s *authRepoStub) ResetAuthChallengeAttempts(challengeType model.AuthChallengeType, email string) error {
+	delete(s.attempts, challengeType)
+	return nil
+}
+
+func (s *authRepoStub) AcquireAuthCooldown(key string, ttl time.Duration) (bool, error) {
+	if s.cooldowns[key] {
+		return false, nil
+	}
+	s.cooldowns[key] = true
+	return true, nil
+}
+
+func TestAuthServiceVerifyChallengeInvalidatesAfterMaxAttempts(t *testing.T) {
+	repo := newAuthRepoStub()
+	svc := NewAuthService(repo, nil, nil, nil, NewMemoryMailer())
+	repo.challenges[model.AuthChallengeTypeSignUp] = model.PendingAuthChallenge{
+		Type:      model.AuthChallengeTypeSignUp,
+		Email:     "alice@example.com",
+		Code:      "1234",
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	for i := 1; i < svc.maxCodeAttempts; i++ {
+		_, err := svc.verifyChallenge(model.AuthChallengeTypeSignUp, "alice@example.com", "0000")
+		if !errors.Is(err, ErrIncorrectVerificationCode) {
+			t.Fatalf("attempt %d: expected incorrect code error, got %v", i, err)
+		}
+	}
+
+	_, err := svc.verifyChallenge(model.AuthChallengeTypeSignUp, "alice@example.com", "0000")
+	if !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("expected too many attempts error, got %v", err)
+	}
+
+	_, err = svc.verifyChallenge(model.AuthChallengeTypeSignUp, "alice@example.com", "1234")
+	if !errors.Is(err, ErrPendingRegistrationNotFound) {
+		t.Fatalf("expected challenge to be invalidated, got %v", err)
+	}
+}
+
+func TestAuthServiceVerifyChallengeAcceptsCorrectCode(t *testing.T) {
+	repo := newAuthRepoStub()
+	svc := NewAuthService(repo, nil, nil, nil, NewMemoryMailer())
+	repo.challenges[model.AuthChallengeTypePasswordReset] = model.PendingAuthChallenge{
+		Type:      model.AuthChallengeTypePasswordReset,
+		Email:     "alice@example.com",
+		Code:      "1234",
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+	repo.attempts[model.AuthChallengeTypePasswordReset] = 1
+
+	challenge, err := svc.verifyChallenge(model.AuthChallengeTypePasswordReset, "alice@example.com", "1234")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if challenge.Email != "alice@example.com" {
+		t.Fatalf("unexpected challenge: %+v", challenge)
+	}
+}
+
+func TestAuthServiceVerifyChallengeCountsAttemptBeforeComparing(t *testing.T) {
+	repo := newAuthRepoStub()
+	svc := NewAuthService(repo, nil, nil, nil, NewMemoryMailer())
+	// Attempts already counted by requests that are still in flight.
+	repo.challenges[model.AuthChallengeTypeLogin] = model.PendingAuthChallenge{
+		Type:      model.AuthChallengeTypeLogin,
+		Email:     "alice@example.com",
+		Code:      "1234",
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+	repo.attempts[model.AuthChallengeTypeLogin] = svc.maxCodeAttempts
+
+	if _, err := svc.verifyChallenge(model.AuthChallengeTypeLogin, "alice@example.com", "1234"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("expected the correct code to be rejected past the cap, got %v", err)
+	}
+}
+
+func TestAuthServiceCodeSignInResetsAttemptsForNewCode(t *testing.T) {
+	repo := newAuthRepoStub()
+	repo.users[1] = model.User{ID: 1, Email: "alice@example.com", Locale: model.LocaleEN}
+	svc := NewAuthService(repo, nil, nil, nil, NewMemoryMailer())
+
+	if err := svc.StartCodeSignIn(model.SignInCodeInput{Email: "alice@example.com"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for i := 1; i < svc.maxCodeAttempts; i++ {
+		if _, err := svc.verifyChallenge(model.AuthChallengeTypeLogin, "alice@example.com", "wrong"); !errors.Is(err, ErrIncorrectVerificationCode) {
+			t.Fatalf("attempt %d: expected incorrect code error, got %v", i, err)
+		}
+	}
+	if _, err := svc.verifyChallenge(model.AuthChallengeTypeLogin, "alice@example.com", "wrong"); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("expected too many attempts, got %v", err)
+	}
+
+	if err := svc.StartCodeSignIn(model.SignInCodeInput{Email: "alice@example.com"}); !errors.Is(err, ErrResendTooSoon) {
+		t.Fatalf("expected start cooldown, got %v", err)
+	}
+
+	// a new code after the cooldown can be tried again
+	delete(repo.cooldowns, "email:login:alice@example.com")
+	if err := svc.StartCodeSignIn(model.SignInCodeInput{Email: "alice@example.com"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := svc.verifyChallenge(model.AuthChallengeTypeLogin, "alice@example.com", "wrong"); !errors.Is(err, ErrIncorrectVerificationCode) {
+		t.Fatalf("expected incorrect code error for the new code, got %v", err)
+	}
+}
+
+func TestAuthServiceStartCodeSignInDoesNotRevealUnknownEmails(t *testing.T) {
+	repo := newAuthRepoStub()
+	mailer := NewMemoryMailer()
+	svc := NewAuthService(repo, nil, nil, nil, mailer)
+
+	if err := svc.StartCodeSignIn(model.SignInCodeInput{Email: "nobody@example.com"}); err != nil {
+		t.Fatalf("expected the same answer as for a registered email, got %v", err)
+	}
+	if len(repo.challenges) != 0 {
+		t.Fatalf("expected no challenge for an unknown email, got %+v", repo.challenges)
+	}
+	if err := svc.ResendSignInCode("nobody@example.com", ""); !errors.Is(err, ErrResendTooSoon) {
+		t.Fatalf("expected the start cooldown to apply to unknown emails too, got %v", err)
+	}
+	if _, err := svc.VerifyCodeSignIn(model.SignInCodeVerifyInput{Email: "nobody@example.com", Code: "1234"}, model.SessionMeta{}); !errors.Is(err, ErrIncorrectVerificationCode) {
+		t.Fatalf("expected incorrect code error, got %v", err)
+	}
+}
+
+func TestAuthServiceResendChallengeEnforcesCooldown(t *testing.T) {
+	repo := newAuthRepoStub()
+	svc := NewAuthService(repo, nil, nil, nil, NewMemoryMailer())
+	repo.challenges[model.AuthChallengeTypeSignUp] = model.PendingAuthChallenge{
+		Type:      model.AuthChallengeTypeSignUp,
+		Email:     "alice@example.com",
+		Code:      "1234",
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+	repo.cooldowns["email:sign_up:alice@example.com"] = true
+
+	err := svc.ResendRegistrationCode("alice@example.com", "203.0.113.7")
+	if !errors.Is(err, ErrResendTooSoon) {
+		t.Fatalf("expected resend cooldown error, got %v", err)
+	}
+}
+
+func TestAuthServiceParseTokenRejectsTokensIssuedBeforeRevocation(t *testing.T) {
+	repo := newAuthRepoStub()
+	svc := NewAuthService(repo, nil, nil, nil, NewMemoryMailer())
+	keys, err := newKeyManager(nil, nil, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	svc.keys = keys
+
+	token, err := svc.generateTokenForUser(7, time.Time{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	identity, err := svc.ParseToken(token)
+	if err != nil || identity.UserID != 7 {
+		t.Fatalf("expected token to be accepted, got id=%d err=%v", identity.UserID, err)
+	}
+
+	repo.validAfter[7] = time.Now().Add(time.Second)
+	if _, err := svc.ParseToken(token); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatalf("expected ErrTokenRevoked, got %v", err)
+	}
+}
+
+type purgeRepoStub struct {
+	*authRepoStub
+	scheduled []int64
+	failing   map[int64]bool
+}
+
+func (s *purgeRepoStub) ListUsersScheduledForDeletion(requestedBefore time.Time, afterID int64, limit int) ([]int64, error) {
+	var ids []int64
+	for _, id := range s.scheduled {
+		if id > afterID && len(ids) < limit {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (s *purgeRepoStub) DeleteUser(userID int64) error {
+	if s.failing[userID] {
+		return errors.New("delete failed")
+	}
+	delete(s.users, userID)
+	return nil
+}
+
+func TestAuthServicePurgeDeletedUsersContinuesPastFailures(t *testing.T) {
+	repo := &purgeRepoStub{authRepoStub: newAuthRepoStub(), failing: map[int64]bool{1: true}}
+	for id := int64(1); id <= purgeBatchSize+1; id++ {
+		repo.users[id] = model.User{ID: id}
+		repo.scheduled = append(repo.scheduled, id)
+	}
+
+	svc := NewAuthService(repo, nil, nil, nil, NewMemoryMailer())
+	err := svc.PurgeDeletedUsers(context.Background())
+	if err == nil {
+		t.Fatalf("expected the failed purge to be reported")
+	}
+	if len(repo.users) != 1 {
+		t.Fatalf("expected every other account to be purged, %d left", len(repo.users))
+	}
+	if _, ok := repo.users[1]; !ok {
+		t.Fatalf("expected the failing account to be kept")
+	}
+}
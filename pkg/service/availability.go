@@ -10,18 +10,24 @@ import (
 )
 
 type AvailabilityService struct {
-	repo repository.Availability
+	repo     repository.Availability
+	activity repository.Activity
 }
 
-func NewAvailabilityService(repo repository.Availability) *AvailabilityService {
-	return &AvailabilityService{repo: repo}
+func NewAvailabilityService(repo repository.Availability, activity repository.Activity) *AvailabilityService {
+	return &AvailabilityService{repo: repo, activity: activity}
 }
 
 func (s *AvailabilityService) CreateAvailability(companyID int64, userID int64, input model.AvailabilityCreateInput) (int64, error) {
 	if input.EndTime.Before(input.StartTime) || input.EndTime.Equal(input.StartTime) {
 		return 0, errors.New("invalid time range")
 	}
-	return s.repo.CreateAvailability(companyID, userID, input)
+	id, err := s.repo.CreateAvailability(companyID, userID, input)
+	if err != nil {
+		return 0, err
+	}
+	recordActivity(s.activity, companyID, userID, model.ActivityAvailabilityCreated, id, "")
+	return id, nil
 }
 
 func (s *AvailabilityService) ListAvailability(companyID int64, userID int64) ([]model.UserAvailability, error) {
@@ -36,11 +42,19 @@ func (s *AvailabilityService) UpdateAvailability(companyID int64, userID int64,
 	if input.EndTime.Before(input.StartTime) || input.EndTime.Equal(input.StartTime) {
 		return errors.New("invalid time range")
 	}
-	return s.repo.UpdateAvailability(companyID, userID, availabilityID, input)
+	if err := s.repo.UpdateAvailability(companyID, userID, availabilityID, input); err != nil {
+		return err
+	}
+	recordActivity(s.activity, companyID, userID, model.ActivityAvailabilityUpdated, availabilityID, "")
+	return nil
 }
 
 func (s *AvailabilityService) DeleteAvailability(companyID int64, userID int64, availabilityID int64) error {
-	return s.repo.DeleteAvailability(companyID, userID, availabilityID)
+	if err := s.repo.DeleteAvailability(companyID, userID, availabilityID); err != nil {
+		return err
+	}
+	recordActivity(s.activity, companyID, userID, model.ActivityAvailabilityDeleted, availabilityID, "")
+	return nil
 }
 
 func (s *AvailabilityService) GetAvailabilityIntersections(companyID int64, userID int64, input model.AvailabilityRangeInput) ([]model.AvailabilityIntersection, error) {
@@ -56,7 +70,7 @@ func (s *AvailabilityService) GetAvailabilityIntersections(companyID int64, user
 		return nil, errors.New("company has no members")
 	}
 	if !containsID(memberIDs, userID) {
-		return nil, errors.New("user is not a member of the company")
+		return nil, ErrNotCompanyMember
 	}
 
 	availabilities, err := s.repo.ListAvailabilityInRange(companyID, input.StartTime, input.EndTime)
@@ -93,11 +107,17 @@ func (s *AvailabilityService) GetAvailabilityIntersections(companyID int64, user
 		}
 	}
 
+	settings, err := s.repo.GetCompanySettings(companyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	location := settings.Location()
+
 	result := make([]model.AvailabilityIntersection, 0, len(intersection))
 	for _, r := range intersection {
 		result = append(result, model.AvailabilityIntersection{
-			StartTime: r.Start,
-			EndTime:   r.End,
+			StartTime: r.Start.In(location),
+			EndTime:   r.End.In(location),
 		})
 	}
 	return result, nil
@@ -16,7 +16,7 @@ type availabilityRepoStub struct {
 }
 
 func (s availabilityRepoStub) CreateAvailability(companyID int64, userID int64, input model.AvailabilityCreateInput) (int64, error) {
-	return 0, nil
+	return 7, nil
 }
 
 func (s availabilityRepoStub) ListAvailability(companyID int64, userID int64) ([]model.UserAvailability, error) {
@@ -49,6 +49,10 @@ func (s availabilityRepoStub) ListAvailabilityInRange(companyID int64, start tim
 	return s.availabilities, nil
 }
 
+func (s availabilityRepoStub) GetCompanySettings(companyID int64, userID int64) (model.CompanySettings, error) {
+	return model.DefaultCompanySettings(companyID), nil
+}
+
 func TestAvailabilityServiceGetAvailabilityIntersectionsReturnsIntersection(t *testing.T) {
 	svc := NewAvailabilityService(availabilityRepoStub{
 		memberIDs: []int64{10, 20},
@@ -69,7 +73,7 @@ func TestAvailabilityServiceGetAvailabilityIntersectionsReturnsIntersection(t *t
 				EndTime:   time.Date(2026, 4, 8, 12, 0, 0, 0, time.UTC),
 			},
 		},
-	})
+	}, nil)
 
 	items, err := svc.GetAvailabilityIntersections(1, 10, model.AvailabilityRangeInput{
 		StartTime: time.Date(2026, 4, 8, 9, 0, 0, 0, time.UTC),
@@ -92,7 +96,7 @@ func TestAvailabilityServiceGetAvailabilityIntersectionsReturnsIntersection(t *t
 func TestAvailabilityServiceGetAvailabilityIntersectionsRejectsNonMember(t *testing.T) {
 	svc := NewAvailabilityService(availabilityRepoStub{
 		memberIDs: []int64{20, 30},
-	})
+	}, nil)
 
 	_, err := svc.GetAvailabilityIntersections(1, 10, model.AvailabilityRangeInput{
 		StartTime: time.Date(2026, 4, 8, 9, 0, 0, 0, time.UTC),
@@ -104,7 +108,7 @@ func TestAvailabilityServiceGetAvailabilityIntersectionsRejectsNonMember(t *test
 }
 
 func TestAvailabilityServiceGetAvailabilityIntersectionsRejectsInvalidRange(t *testing.T) {
-	svc := NewAvailabilityService(availabilityRepoStub{})
+	svc := NewAvailabilityService(availabilityRepoStub{}, nil)
 
 	_, err := svc.GetAvailabilityIntersections(1, 10, model.AvailabilityRangeInput{
 		StartTime: time.Date(2026, 4, 8, 10, 0, 0, 0, time.UTC),
@@ -119,7 +123,7 @@ func TestAvailabilityServiceGetAvailabilityIntersectionsPropagatesRepoError(t *t
 	expectedErr := errors.New("repo failure")
 	svc := NewAvailabilityService(availabilityRepoStub{
 		memberErr: expectedErr,
-	})
+	}, nil)
 
 	_, err := svc.GetAvailabilityIntersections(1, 10, model.AvailabilityRangeInput{
 		StartTime: time.Date(2026, 4, 8, 9, 0, 0, 0, time.UTC),
@@ -2,17 +2,62 @@ package service
 
 import (
 	"errors"
+	"os"
+	"time"
 
 	"github.com/Sovpalo/sovpalo-backend/pkg/model"
 	"github.com/Sovpalo/sovpalo-backend/pkg/repository"
 )
 
+var (
+	ErrNotCompanyMember        = repository.ErrNotCompanyMember
+	ErrCompanyPermissionDenied = repository.ErrCompanyPermissionDenied
+	ErrAlreadyCompanyMember    = repository.ErrAlreadyCompanyMember
+	ErrNewOwnerIsCurrentOwner  = repository.ErrNewOwnerIsCurrentOwner
+	ErrNewOwnerNotMember       = repository.ErrNewOwnerNotMember
+	ErrInvalidCompanyRole      = errors.New("role must be admin or member")
+	ErrInvalidVisibility       = errors.New("visibility must be private or listed")
+	ErrInviteLinkNotFound      = repository.ErrInviteLinkNotFound
+	ErrInviteLinkExpired       = repository.ErrInviteLinkExpired
+	ErrInviteLinkUsedUp        = repository.ErrInviteLinkUsedUp
+	ErrInvalidInviteLinkParams = errors.New("invalid invite link parameters")
+
+	ErrEmailInvitationNotFound     = repository.ErrEmailInvitationNotFound
+	ErrEmailInvitationSentRecently = repository.ErrEmailInvitationSentRecently
+
+	ErrCompanyNotFound        = repository.ErrCompanyNotFound
+	ErrJoinRequestNotFound    = repository.ErrJoinRequestNotFound
+	ErrJoinRequestAlreadySent = repository.ErrJoinRequestAlreadySent
+
+	ErrJoinRequestRejectedRecently = repository.ErrJoinRequestRejectedRecently
+
+	ErrInvitationNotFound         = repository.ErrInvitationNotFound
+	ErrInvitationExpired          = repository.ErrInvitationExpired
+	ErrInvitationDeclinedRecently = repository.ErrInvitationDeclinedRecently
+
+	ErrRSVPDeadlinePassed = repository.ErrRSVPDeadlinePassed
+)
+
 type CompanyService struct {
-	repo repository.Company
+	repo               repository.Company
+	activity           repository.Activity
+	invitationTTL      time.Duration
+	reinviteCooldown   time.Duration
+	rerequestCooldown  time.Duration
+	emailInvitationTTL time.Duration
+	signUpURL          string
 }
 
-func NewCompanyService(repo repository.Company) *CompanyService {
-	return &CompanyService{repo: repo}
+func NewCompanyService(repo repository.Company, activity repository.Activity) *CompanyService {
+	return &CompanyService{
+		repo:               repo,
+		activity:           activity,
+		invitationTTL:      durationFromEnv("COMPANY_INVITATION_TTL", defaultInvitationTTL, minInvitationTTL),
+		reinviteCooldown:   durationFromEnv("INVITATION_REINVITE_COOLDOWN", defaultReinviteCooldown, 0),
+		rerequestCooldown:  durationFromEnv("JOIN_REQUEST_REREQUEST_COOLDOWN", defaultRerequestCooldown, 0),
+		emailInvitationTTL: durationFromEnv("EMAIL_INVITATION_TTL", defaultEmailInvitationTTL, minInvitationTTL),
+		signUpURL:          defaultString(os.Getenv("SIGN_UP_URL"), defaultSignUpURL),
+	}
 }
 
 func (s *CompanyService) CreateCompany(userID int64, name string, description *string, avatarURL *string) (int64, error) {
@@ -23,7 +68,8 @@ func (s *CompanyService) CreateCompany(userID int64, name string, description *s
 		Name:        name,
 		Description: description,
 		AvatarURL:   avatarURL,
-		CreatedBy:   userID,
+		CreatedBy:   &userID,
+		OwnerID:     userID,
 	}
 	return s.repo.CreateCompany(company)
 }
@@ -37,6 +83,10 @@ func (s *CompanyService) ListCompanies(userID int64) ([]model.Company, error) {
 }
 
 func (s *CompanyService) UpdateCompany(companyID int64, userID int64, input model.CompanyUpdateInput, avatarFileName string, avatarFileData []byte) error {
+	if input.Visibility != nil && *input.Visibility != model.CompanyVisibilityPrivate && *input.Visibility != model.CompanyVisibilityListed {
+		return ErrInvalidVisibility
+	}
+
 	company, err := s.repo.GetCompany(companyID, userID)
 	if err != nil {
 		return err
@@ -62,6 +112,7 @@ func (s *CompanyService) UpdateCompany(companyID int64, userID int64, input mode
 		_ = removeAvatarByURL(*company.AvatarURL)
 	}
 
+	recordActivity(s.activity, companyID, userID, model.ActivityCompanyUpdated, 0, "")
 	return nil
 }
 
@@ -70,14 +121,27 @@ func (s *CompanyService) DeleteCompany(companyID int64, userID int64) error {
 }
 
 func (s *CompanyService) LeaveCompany(companyID int64, userID int64, newOwnerID *int64) error {
-	return s.repo.LeaveCompany(companyID, userID, newOwnerID)
+	if err := s.repo.LeaveCompany(companyID, userID, newOwnerID); err != nil {
+		return err
+	}
+	recordActivity(s.activity, companyID, userID, model.ActivityMemberLeft, userID, "")
+	return nil
+}
+
+func (s *CompanyService) TransferOwnership(companyID int64, userID int64, newOwnerID int64) error {
+	if err := s.repo.TransferOwnership(companyID, userID, newOwnerID); err != nil {
+		return err
+	}
+	recordActivity(s.activity, companyID, userID, model.ActivityOwnershipTransferred, newOwnerID, "")
+	return nil
 }
 
 func (s *CompanyService) InviteUser(companyID int64, invitedBy int64, username string) (model.CompanyInvitation, error) {
 	if username == "" {
 		return model.CompanyInvitation{}, errors.New("username is required")
 	}
-	return s.repo.CreateInvitation(companyID, invitedBy, username)
+	now := time.Now()
+	return s.repo.CreateInvitation(companyID, invitedBy, username, now.Add(s.invitationTTL), now.Add(-s.reinviteCooldown))
 }
 
 func (s *CompanyService) ListInvitations(userID int64) ([]model.CompanyInvitationView, error) {
@@ -85,7 +149,12 @@ func (s *CompanyService) ListInvitations(userID int64) ([]model.CompanyInvitatio
 }
 
 func (s *CompanyService) AcceptInvitation(inviteID int64, userID int64) error {
-	return s.repo.AcceptInvitation(inviteID, userID)
+	companyID, err := s.repo.AcceptInvitation(inviteID, userID)
+	if err != nil {
+		return err
+	}
+	recordActivity(s.activity, companyID, userID, model.ActivityMemberJoined, userID, "")
+	return nil
 }
 
 func (s *CompanyService) DeclineInvitation(inviteID int64, userID int64) error {
@@ -96,6 +165,21 @@ func (s *CompanyService) ListCompanyMembers(companyID int64, userID int64) ([]mo
 	return s.repo.ListCompanyMembers(companyID, userID)
 }
 
-func (s *CompanyService) RemoveCompanyMember(companyID int64, ownerID int64, memberUserID int64) error {
-	return s.repo.RemoveCompanyMember(companyID, ownerID, memberUserID)
+func (s *CompanyService) RemoveCompanyMember(companyID int64, userID int64, memberUserID int64) error {
+	if err := s.repo.RemoveCompanyMember(companyID, userID, memberUserID); err != nil {
+		return err
+	}
+	recordActivity(s.activity, companyID, userID, model.ActivityMemberRemoved, memberUserID, "")
+	return nil
+}
+
+func (s *CompanyService) UpdateCompanyMemberRole(companyID int64, userID int64, memberUserID int64, role string) error {
+	if role != model.CompanyRoleAdmin && role != model.CompanyRoleMember {
+		return ErrInvalidCompanyRole
+	}
+	if err := s.repo.UpdateCompanyMemberRole(companyID, userID, memberUserID, role); err != nil {
+		return err
+	}
+	recordActivity(s.activity, companyID, userID, model.ActivityMemberRoleChanged, memberUserID, role)
+	return nil
 }
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+)
+
+// maxSettingsMinutes bounds the default event duration and RSVP deadline to
+// one week.
+const maxSettingsMinutes = 7 * 24 * 60
+
+var ErrInvalidCompanySettings = errors.New("invalid company settings")
+
+func (s *CompanyService) GetCompanySettings(companyID int64, userID int64) (model.CompanySettings, error) {
+	return s.repo.GetCompanySettings(companyID, userID)
+}
+
+// UpdateCompanySettings applies the given fields on top of the current
+// settings.
+func (s *CompanyService) UpdateCompanySettings(companyID int64, userID int64, input model.CompanySettingsUpdateInput) (model.CompanySettings, error) {
+	settings, err := s.repo.GetCompanySettings(companyID, userID)
+	if err != nil {
+		return model.CompanySettings{}, err
+	}
+
+	if input.Timezone != nil {
+		if *input.Timezone == "" || *input.Timezone == "Local" {
+			return model.CompanySettings{}, fmt.Errorf("%w: timezone must be an IANA name like Europe/Moscow", ErrInvalidCompanySettings)
+		}
+		if _, err := time.LoadLocation(*input.Timezone); err != nil {
+			return model.CompanySettings{}, fmt.Errorf("%w: timezone must be an IANA name like Europe/Moscow", ErrInvalidCompanySettings)
+		}
+		settings.Timezone = *input.Timezone
+	}
+	if input.DefaultEventDurationMinutes != nil {
+		if *input.DefaultEventDurationMinutes < 1 || *input.DefaultEventDurationMinutes > maxSettingsMinutes {
+			return model.CompanySettings{}, fmt.Errorf("%w: default_event_duration_minutes must be between 1 and %d", ErrInvalidCompanySettings, maxSettingsMinutes)
+		}
+		settings.DefaultEventDurationMinutes = *input.DefaultEventDurationMinutes
+	}
+	if input.DefaultRSVPDeadlineMinutes != nil {
+		if *input.DefaultRSVPDeadlineMinutes < 0 || *input.DefaultRSVPDeadlineMinutes > maxSettingsMinutes {
+			return model.CompanySettings{}, fmt.Errorf("%w: default_rsvp_deadline_minutes must be between 0 and %d", ErrInvalidCompanySettings, maxSettingsMinutes)
+		}
+		settings.DefaultRSVPDeadlineMinutes = *input.DefaultRSVPDeadlineMinutes
+	}
+	if input.EventCreation != nil {
+		settings.EventCreation = *input.EventCreation
+	}
+	if input.IdeaCreation != nil {
+		settings.IdeaCreation = *input.IdeaCreation
+	}
+	if input.MembersCanInvite != nil {
+		settings.MembersCanInvite = *input.MembersCanInvite
+	}
+
+	updated, err := s.repo.UpdateCompanySettings(companyID, userID, settings)
+	if err != nil {
+		return model.CompanySettings{}, err
+	}
+	recordActivity(s.activity, companyID, userID, model.ActivitySettingsUpdated, 0, "")
+	return updated, nil
+}
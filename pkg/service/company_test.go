@@ -0,0 +1,293 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/Sovpalo/sovpalo-backend/pkg/repository"
+)
+
+type companyRepoStub struct {
+	repository.Company
+	roles map[int64]string
+
+	emailInvitation model.CompanyEmailInvitation
+	emailMessage    model.EmailMessage
+
+	invitationExpiresAt time.Time
+	declinedBefore      time.Time
+	rejectedBefore      time.Time
+
+	discoverSearch string
+	discoverLimit  int
+
+	settings model.CompanySettings
+}
+
+func (s *companyRepoStub) GetCompanySettings(companyID int64, userID int64) (model.CompanySettings, error) {
+	if s.settings.CompanyID == 0 {
+		return model.DefaultCompanySettings(companyID), nil
+	}
+	return s.settings, nil
+}
+
+func (s *companyRepoStub) UpdateCompanySettings(companyID int64, userID int64, settings model.CompanySettings) (model.CompanySettings, error) {
+	s.settings = settings
+	return settings, nil
+}
+
+func (s *companyRepoStub) DiscoverCompanies(userID int64, search string, limit int, offset int) ([]model.CompanyDiscoverView, error) {
+	s.discoverSearch = search
+	s.discoverLimit = limit
+	return nil, nil
+}
+
+func (s *companyRepoStub) CreateInvitation(companyID int64, invitedBy int64, username string, expiresAt time.Time, declinedBefore time.Time) (model.CompanyInvitation, error) {
+	s.invitationExpiresAt = expiresAt
+	s.declinedBefore = declinedBefore
+	return model.CompanyInvitation{CompanyID: companyID, InvitedBy: invitedBy, Status: "pending", ExpiresAt: &expiresAt}, nil
+}
+
+func (s *companyRepoStub) CreateJoinRequest(companyID int64, userID int64, message *string, rejectedBefore time.Time) (model.CompanyJoinRequest, error) {
+	s.rejectedBefore = rejectedBefore
+	return model.CompanyJoinRequest{CompanyID: companyID, UserID: userID, Message: message, Status: "pending"}, nil
+}
+
+func (s *companyRepoStub) UpdateCompanyMemberRole(companyID int64, userID int64, memberUserID int64, role string) error {
+	s.roles[memberUserID] = role
+	return nil
+}
+
+func (s *companyRepoStub) TransferOwnership(companyID int64, userID int64, newOwnerID int64) error {
+	return nil
+}
+
+func (s *companyRepoStub) CreateInviteLink(userID int64, link model.CompanyInviteLink) (model.CompanyInviteLink, error) {
+	link.CreatedBy = userID
+	return link, nil
+}
+
+func (s *companyRepoStub) GetInvitationSender(companyID int64, userID int64) (model.CompanyInvitationSender, error) {
+	return model.CompanyInvitationSender{CompanyName: "Friends", InviterName: "alice", InviterLocale: model.LocaleEN}, nil
+}
+
+func (s *companyRepoStub) CreateEmailInvitation(invitation model.CompanyEmailInvitation, sentBefore time.Time, message model.EmailMessage) (model.CompanyEmailInvitation, error) {
+	s.emailInvitation = invitation
+	s.emailMessage = message
+	return invitation, nil
+}
+
+func TestCompanyServiceInviteByEmail(t *testing.T) {
+	repo := &companyRepoStub{}
+	svc := NewCompanyService(repo, nil)
+
+	invitation, err := svc.InviteByEmail(1, 2, "  Bob+friends@Example.com ")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if invitation.Email != "bob+friends@example.com" || invitation.InvitedBy != 2 || !invitation.ExpiresAt.After(time.Now()) {
+		t.Fatalf("unexpected invitation: %+v", invitation)
+	}
+	if repo.emailMessage.To != "bob+friends@example.com" {
+		t.Fatalf("expected email to the invited address, got %q", repo.emailMessage.To)
+	}
+	if !strings.Contains(repo.emailMessage.Text, "?email=bob%2Bfriends%40example.com") || !strings.Contains(repo.emailMessage.Text, "Friends") {
+		t.Fatalf("expected sign-up link and company name in email, got %q", repo.emailMessage.Text)
+	}
+}
+
+func TestCompanyServiceInviteUserUsesConfiguredLifetimes(t *testing.T) {
+	t.Setenv("COMPANY_INVITATION_TTL", "48h")
+	t.Setenv("INVITATION_REINVITE_COOLDOWN", "not-a-duration")
+	repo := &companyRepoStub{}
+	svc := NewCompanyService(repo, nil)
+
+	before := time.Now()
+	if _, err := svc.InviteUser(1, 1, "bob"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := repo.invitationExpiresAt.Sub(before); got < 48*time.Hour || got > 48*time.Hour+time.Minute {
+		t.Fatalf("expected invitation to expire in 48h, got %s", got)
+	}
+	if got := before.Sub(repo.declinedBefore); got < defaultReinviteCooldown-time.Minute || got > defaultReinviteCooldown {
+		t.Fatalf("expected default re-invite cooldown, got %s", got)
+	}
+}
+
+func TestCompanyServiceCreateJoinRequestUsesRerequestCooldown(t *testing.T) {
+	t.Setenv("JOIN_REQUEST_REREQUEST_COOLDOWN", "24h")
+	repo := &companyRepoStub{}
+	svc := NewCompanyService(repo, nil)
+
+	before := time.Now()
+	if _, err := svc.CreateJoinRequest(1, 2, nil); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := before.Sub(repo.rejectedBefore); got < 24*time.Hour-time.Minute || got > 24*time.Hour {
+		t.Fatalf("expected a 24h re-request cooldown, got %s", got)
+	}
+}
+
+func TestCompanyServiceDiscoverCompaniesClampsLimit(t *testing.T) {
+	repo := &companyRepoStub{}
+	svc := NewCompanyService(repo, nil)
+
+	cases := map[int]int{0: defaultDiscoverLimit, 10: 10, 1000: maxDiscoverLimit}
+	for limit, want := range cases {
+		if _, err := svc.DiscoverCompanies(1, "  hiking  ", limit, 0); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if repo.discoverLimit != want || repo.discoverSearch != "hiking" {
+			t.Fatalf("limit %d: got limit %d and search %q", limit, repo.discoverLimit, repo.discoverSearch)
+		}
+	}
+}
+
+func TestCompanyServiceUpdateCompanyValidatesVisibility(t *testing.T) {
+	svc := NewCompanyService(&companyRepoStub{}, nil)
+
+	visibility := "public"
+	err := svc.UpdateCompany(1, 1, model.CompanyUpdateInput{Visibility: &visibility}, "", nil)
+	if !errors.Is(err, ErrInvalidVisibility) {
+		t.Fatalf("expected invalid visibility error, got %v", err)
+	}
+}
+
+func TestCompanyServiceCreateInviteLink(t *testing.T) {
+	svc := NewCompanyService(&companyRepoStub{}, nil)
+
+	past := time.Now().Add(-time.Minute)
+	if _, err := svc.CreateInviteLink(1, 1, model.CompanyInviteLinkCreateInput{ExpiresAt: &past}); !errors.Is(err, ErrInvalidInviteLinkParams) {
+		t.Fatalf("expected invalid params for past expiry, got %v", err)
+	}
+
+	maxUses := 5
+	first, err := svc.CreateInviteLink(1, 1, model.CompanyInviteLinkCreateInput{MaxUses: &maxUses})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	second, err := svc.CreateInviteLink(1, 1, model.CompanyInviteLinkCreateInput{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(first.Code) != 16 || first.Code == second.Code {
+		t.Fatalf("expected distinct 16-character codes, got %q and %q", first.Code, second.Code)
+	}
+	if first.CompanyID != 1 || first.MaxUses == nil || *first.MaxUses != 5 {
+		t.Fatalf("unexpected link: %+v", first)
+	}
+}
+
+func TestCompanyServiceUpdateMemberRoleValidatesRole(t *testing.T) {
+	repo := &companyRepoStub{roles: map[int64]string{}}
+	svc := NewCompanyService(repo, nil)
+
+	for _, role := range []string{model.CompanyRoleOwner, "moderator", ""} {
+		if err := svc.UpdateCompanyMemberRole(1, 1, 2, role); !errors.Is(err, ErrInvalidCompanyRole) {
+			t.Fatalf("role %q: expected invalid role error, got %v", role, err)
+		}
+	}
+
+	if err := svc.UpdateCompanyMemberRole(1, 1, 2, model.CompanyRoleAdmin); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if repo.roles[2] != model.CompanyRoleAdmin {
+		t.Fatalf("expected member to become admin, got %q", repo.roles[2])
+	}
+}
+
+func TestCompanyServiceUpdateCompanySettings(t *testing.T) {
+	repo := &companyRepoStub{}
+	svc := NewCompanyService(repo, nil)
+
+	for _, timezone := range []string{"", "Local", "Mars/Olympus"} {
+		tz := timezone
+		if _, err := svc.UpdateCompanySettings(1, 1, model.CompanySettingsUpdateInput{Timezone: &tz}); !errors.Is(err, ErrInvalidCompanySettings) {
+			t.Fatalf("timezone %q: expected invalid settings error, got %v", timezone, err)
+		}
+	}
+	duration := 0
+	if _, err := svc.UpdateCompanySettings(1, 1, model.CompanySettingsUpdateInput{DefaultEventDurationMinutes: &duration}); !errors.Is(err, ErrInvalidCompanySettings) {
+		t.Fatalf("expected invalid settings error for zero duration, got %v", err)
+	}
+
+	timezone := "Europe/Moscow"
+	creation := model.CompanyCreationAdmins
+	settings, err := svc.UpdateCompanySettings(1, 1, model.CompanySettingsUpdateInput{Timezone: &timezone, EventCreation: &creation})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if settings.Timezone != timezone || settings.EventCreation != creation || settings.IdeaCreation != model.CompanyCreationEveryone {
+		t.Fatalf("unexpected settings: %+v", settings)
+	}
+	if settings.MembersAllowed(model.CompanyPermissionCreateEvents) || !settings.MembersAllowed(model.CompanyPermissionCreateIdeas) {
+		t.Fatalf("expected only event creation to be restricted: %+v", settings)
+	}
+}
+
+func TestApplyEventDefaults(t *testing.T) {
+	settings := model.DefaultCompanySettings(1)
+	settings.DefaultEventDurationMinutes = 90
+	settings.DefaultRSVPDeadlineMinutes = 60
+	now := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	start := now.Add(3 * time.Hour)
+	end, deadline := applyEventDefaults(settings, start, nil, now)
+	if end == nil || !end.Equal(start.Add(90*time.Minute)) {
+		t.Fatalf("expected default end time, got %v", end)
+	}
+	if deadline == nil || !deadline.Equal(start.Add(-time.Hour)) {
+		t.Fatalf("expected deadline an hour before start, got %v", deadline)
+	}
+
+	soon := now.Add(30 * time.Minute)
+	explicitEnd := soon.Add(time.Hour)
+	end, deadline = applyEventDefaults(settings, soon, &explicitEnd, now)
+	if end != &explicitEnd || deadline != nil {
+		t.Fatalf("expected explicit end and no past deadline, got %v and %v", end, deadline)
+	}
+}
+
+func TestCompanyRolePermissions(t *testing.T) {
+	cases := []struct {
+		role       string
+		permission model.CompanyPermission
+		allowed    bool
+	}{
+		{model.CompanyRoleOwner, model.CompanyPermissionManageRoles, true},
+		{model.CompanyRoleOwner, model.CompanyPermissionDeleteCompany, true},
+		{model.CompanyRoleAdmin, model.CompanyPermissionEditCompany, true},
+		{model.CompanyRoleAdmin, model.CompanyPermissionManageRoles, false},
+		{model.CompanyRoleAdmin, model.CompanyPermissionDeleteCompany, false},
+		{model.CompanyRoleAdmin, model.CompanyPermissionTransferOwnership, false},
+		{model.CompanyRoleOwner, model.CompanyPermissionTransferOwnership, true},
+		{model.CompanyRoleOwner, model.CompanyPermissionManageSettings, true},
+		{model.CompanyRoleAdmin, model.CompanyPermissionManageSettings, false},
+		{model.CompanyRoleMember, model.CompanyPermissionInvite, true},
+		{model.CompanyRoleMember, model.CompanyPermissionModerateIdeas, false},
+		{model.CompanyRoleMember, model.CompanyPermissionRemoveMembers, false},
+		{"", model.CompanyPermissionView, false},
+	}
+	for _, tc := range cases {
+		if got := model.CompanyRoleAllows(tc.role, tc.permission); got != tc.allowed {
+			t.Fatalf("CompanyRoleAllows(%q, %q) = %v, want %v", tc.role, tc.permission, got, tc.allowed)
+		}
+	}
+}
+
+func TestCompanyServiceRejectsZeroInvitationTTL(t *testing.T) {
+	t.Setenv("COMPANY_INVITATION_TTL", "0")
+	t.Setenv("EMAIL_INVITATION_TTL", "-1h")
+	t.Setenv("INVITATION_REINVITE_COOLDOWN", "0")
+	svc := NewCompanyService(&companyRepoStub{}, nil)
+
+	if svc.invitationTTL != defaultInvitationTTL || svc.emailInvitationTTL != defaultEmailInvitationTTL {
+		t.Fatalf("expected default TTLs, got %s and %s", svc.invitationTTL, svc.emailInvitationTTL)
+	}
+	if svc.reinviteCooldown != 0 {
+		t.Fatalf("expected the cooldown to be turned off, got %s", svc.reinviteCooldown)
+	}
+}
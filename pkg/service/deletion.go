@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultDeletionGracePeriod = 30 * 24 * time.Hour
+	purgeBatchSize             = 100
+)
+
+// DeleteUser deactivates the account and schedules it for removal once the
+// grace period is over. All sessions and access tokens are revoked; signing
+// in again before the deadline cancels the deletion. It returns the time after
+// which the account is purged.
+func (s *AuthService) DeleteUser(userID int64, meta model.SessionMeta) (time.Time, error) {
+	requestedAt, err := s.repo.ScheduleUserDeletion(userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, ErrUserNotFound
+		}
+		return time.Time{}, err
+	}
+
+	if err := s.revokeAllTokens(userID); err != nil {
+		return time.Time{}, err
+	}
+	s.audit(model.AuditEventAccountDeletion, userID, meta, nil, "")
+
+	return requestedAt.Add(s.deletionGracePeriod), nil
+}
+
+// PurgeDeletedUsers permanently removes accounts whose grace period is over.
+// An account that fails to purge is logged and skipped until the next run,
+// so it does not hold up the rest; the failures are returned together.
+func (s *AuthService) PurgeDeletedUsers(ctx context.Context) error {
+	requestedBefore := time.Now().Add(-s.deletionGracePeriod)
+	var errs []error
+	var afterID int64
+	for {
+		ids, err := s.repo.ListUsersScheduledForDeletion(requestedBefore, afterID, purgeBatchSize)
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+
+		for _, userID := range ids {
+			if err := ctx.Err(); err != nil {
+				return errors.Join(append(errs, err)...)
+			}
+			afterID = userID
+			if err := s.purgeUser(userID); err != nil {
+				logrus.Errorf("failed to purge account %d: %s", userID, err.Error())
+				errs = append(errs, fmt.Errorf("purge account %d: %w", userID, err))
+				continue
+			}
+			logrus.Infof("purged account %d after deletion grace period", userID)
+		}
+
+		if len(ids) < purgeBatchSize {
+			return errors.Join(errs...)
+		}
+	}
+}
+
+func (s *AuthService) purgeUser(userID int64) error {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	if err := s.repo.DeleteUser(userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	if user.AvatarURL != nil {
+		_ = removeAvatarByURL(*user.AvatarURL)
+	}
+	return nil
+}
+
+// cancelScheduledDeletion reactivates an account the user signed back in to.
+func (s *AuthService) cancelScheduledDeletion(userID int64) error {
+	cancelled, err := s.repo.CancelUserDeletion(userID)
+	if err != nil {
+		return err
+	}
+	if cancelled {
+		logrus.Infof("account %d signed in again, deletion cancelled", userID)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultEmailInvitationTTL     = 7 * 24 * time.Hour
+	defaultSignUpURL              = "http://localhost:3000/sign-up"
+	emailInvitationResendCooldown = time.Minute
+)
+
+// InviteByEmail invites someone by email: the invitation is stored by email
+// and a sign-up link is sent. The response is the same whether or not the
+// email already has an account. Inviting the same email again renews the
+// invitation and sends the email once more.
+func (s *CompanyService) InviteByEmail(companyID int64, userID int64, email string) (model.CompanyEmailInvitation, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	sender, err := s.repo.GetInvitationSender(companyID, userID)
+	if err != nil {
+		return model.CompanyEmailInvitation{}, err
+	}
+
+	expiresAt := time.Now().Add(s.emailInvitationTTL)
+	message, err := renderEmail(sender.InviterLocale, emailTemplateInvitation, email, invitationEmailData{
+		InviterName: sender.InviterName,
+		CompanyName: sender.CompanyName,
+		AcceptURL:   s.signUpLink(email),
+		ExpiresAt:   expiresAt.UTC().Format("2006-01-02 15:04 MST"),
+	})
+	if err != nil {
+		return model.CompanyEmailInvitation{}, err
+	}
+
+	return s.repo.CreateEmailInvitation(model.CompanyEmailInvitation{
+		CompanyID: companyID,
+		Email:     email,
+		InvitedBy: userID,
+		ExpiresAt: expiresAt,
+	}, time.Now().Add(-emailInvitationResendCooldown), message)
+}
+
+// ResendEmailInvitation sends the invitation email again and extends its
+// expiry.
+func (s *CompanyService) ResendEmailInvitation(companyID int64, userID int64, inviteID int64) (model.CompanyEmailInvitation, error) {
+	invitation, err := s.repo.GetEmailInvitation(companyID, userID, inviteID)
+	if err != nil {
+		return model.CompanyEmailInvitation{}, err
+	}
+	return s.InviteByEmail(companyID, userID, invitation.Email)
+}
+
+// claimEmailInvitations turns the email invitations of a user who has just
+// signed up or signed in into regular invitations. A failure must not break
+// the sign-in, so it is only logged.
+func (s *AuthService) claimEmailInvitations(userID int64, email string) {
+	if _, err := s.repo.ConvertEmailInvitations(userID, email, time.Now().Add(-s.reinviteCooldown)); err != nil {
+		logrus.Errorf("failed to convert email invitations of user %d: %v", userID, err)
+	}
+}
+
+func (s *CompanyService) signUpLink(email string) string {
+	separator := "?"
+	if strings.Contains(s.signUpURL, "?") {
+		separator = "&"
+	}
+	return s.signUpURL + separator + url.Values{"email": {email}}.Encode()
+}
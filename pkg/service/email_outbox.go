@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/Sovpalo/sovpalo-backend/pkg/repository"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	outboxBatchSize     = 20
+	outboxMaxAttempts   = 8
+	outboxBaseBackoff   = 30 * time.Second
+	outboxMaxBackoff    = time.Hour
+	outboxSendTimeout   = 30 * time.Second
+	outboxSentRetention = 7 * 24 * time.Hour
+	outboxLeaseDuration = outboxBatchSize * outboxSendTimeout
+)
+
+// EmailOutboxService queues emails in Postgres and delivers them in the
+// background. It implements Mailer, so services send through it without
+// waiting for the mail server: Send only stores the message. Failed
+// deliveries are retried with exponential backoff; after outboxMaxAttempts
+// the email is moved to the dead state.
+type EmailOutboxService struct {
+	repo   repository.EmailOutbox
+	mailer Mailer
+
+	delivered    atomic.Int64
+	failed       atomic.Int64
+	deadLettered atomic.Int64
+}
+
+func NewEmailOutboxService(repo repository.EmailOutbox, mailer Mailer) *EmailOutboxService {
+	return &EmailOutboxService{repo: repo, mailer: mailer}
+}
+
+func (s *EmailOutboxService) Send(ctx context.Context, message model.EmailMessage) error {
+	return s.repo.EnqueueEmail(message)
+}
+
+func (s *EmailOutboxService) Check(ctx context.Context) error {
+	return s.mailer.Check(ctx)
+}
+
+// DeliverPendingEmails sends every email that is due, batch by batch.
+func (s *EmailOutboxService) DeliverPendingEmails(ctx context.Context) error {
+	for ctx.Err() == nil {
+		emails, err := s.repo.ClaimPendingEmails(outboxBatchSize, outboxLeaseDuration)
+		if err != nil {
+			return err
+		}
+
+		for _, email := range emails {
+			if ctx.Err() != nil {
+				// The claim expires with the lease and another run picks it up.
+				return nil
+			}
+			if err := s.deliver(ctx, email); err != nil {
+				return err
+			}
+		}
+
+		if len(emails) < outboxBatchSize {
+			return nil
+		}
+	}
+	return nil
+}
+
+// PurgeSentEmails removes delivered emails after the retention period.
+func (s *EmailOutboxService) PurgeSentEmails(ctx context.Context) error {
+	_, err := s.repo.DeleteSentEmails(time.Now().Add(-outboxSentRetention))
+	return err
+}
+
+func (s *EmailOutboxService) EmailOutboxStats() (model.EmailOutboxStats, error) {
+	stats, err := s.repo.GetEmailOutboxStats()
+	if err != nil {
+		return model.EmailOutboxStats{}, err
+	}
+
+	stats.Delivered = s.delivered.Load()
+	stats.Failed = s.failed.Load()
+	stats.DeadLettered = s.deadLettered.Load()
+	return stats, nil
+}
+
+func (s *EmailOutboxService) deliver(ctx context.Context, email model.OutboxEmail) error {
+	sendCtx, cancel := context.WithTimeout(ctx, outboxSendTimeout)
+	sendErr := s.mailer.Send(sendCtx, email.EmailMessage)
+	cancel()
+
+	if sendErr == nil {
+		s.delivered.Add(1)
+		return s.repo.MarkEmailSent(email.ID)
+	}
+
+	attempt := email.Attempts + 1
+	s.failed.Add(1)
+	if attempt >= outboxMaxAttempts {
+		s.deadLettered.Add(1)
+		logrus.Errorf("email %d to %s failed %d times, giving up: %s", email.ID, email.To, attempt, sendErr.Error())
+		return s.repo.MarkEmailFailed(email.ID, sendErr.Error(), nil)
+	}
+
+	nextAttemptAt := time.Now().Add(outboxBackoff(attempt))
+	logrus.Warnf("email %d to %s failed (attempt %d), retrying at %s: %s", email.ID, email.To, attempt, nextAttemptAt.Format(time.RFC3339), sendErr.Error())
+	return s.repo.MarkEmailFailed(email.ID, sendErr.Error(), &nextAttemptAt)
+}
+
+// outboxBackoff returns the delay before the next delivery after the given
+// number of failed attempts: 30s, 1m, 2m, ... capped at one hour.
+func outboxBackoff(attempt int) time.Duration {
+	delay := outboxBaseBackoff
+	for i := 1; i < attempt && delay < outboxMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > outboxMaxBackoff {
+		delay = outboxMaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/Sovpalo/sovpalo-backend/pkg/repository"
+)
+
+type outboxRepoStub struct {
+	repository.EmailOutbox
+	emails map[int64]*outboxRow
+	nextID int64
+}
+
+type outboxRow struct {
+	email         model.OutboxEmail
+	status        string
+	nextAttemptAt time.Time
+	lastError     string
+}
+
+func newOutboxRepoStub() *outboxRepoStub {
+	return &outboxRepoStub{emails: map[int64]*outboxRow{}}
+}
+
+func (s *outboxRepoStub) EnqueueEmail(message model.EmailMessage) error {
+	s.nextID++
+	s.emails[s.nextID] = &outboxRow{
+		email:         model.OutboxEmail{ID: s.nextID, EmailMessage: message},
+		status:        model.OutboxStatusPending,
+		nextAttemptAt: time.Now(),
+	}
+	return nil
+}
+
+func (s *outboxRepoStub) ClaimPendingEmails(limit int, lease time.Duration) ([]model.OutboxEmail, error) {
+	var emails []model.OutboxEmail
+	for id := int64(1); id <= s.nextID && len(emails) < limit; id++ {
+		row, ok := s.emails[id]
+		if ok && row.status == model.OutboxStatusPending && !row.nextAttemptAt.After(time.Now()) {
+			emails = append(emails, row.email)
+		}
+	}
+	return emails, nil
+}
+
+func (s *outboxRepoStub) MarkEmailSent(emailID int64) error {
+	s.emails[emailID].status = model.OutboxStatusSent
+	return nil
+}
+
+func (s *outboxRepoStub) MarkEmailFailed(emailID int64, lastError string, nextAttemptAt *time.Time) error {
+	row := s.emails[emailID]
+	row.email.Attempts++
+	row.lastError = lastError
+	if nextAttemptAt == nil {
+		row.status = model.OutboxStatusDead
+		return nil
+	}
+	row.nextAttemptAt = *nextAttemptAt
+	return nil
+}
+
+type failingMailer struct{}
+
+func (failingMailer) Send(ctx context.Context, message model.EmailMessage) error {
+	return errors.New("smtp: connection refused")
+}
+
+func (failingMailer) Check(ctx context.Context) error {
+	return nil
+}
+
+func TestEmailOutboxDeliversQueuedEmails(t *testing.T) {
+	repo := newOutboxRepoStub()
+	mailer := NewMemoryMailer()
+	outbox := NewEmailOutboxService(repo, mailer)
+
+	if err := outbox.Send(context.Background(), model.EmailMessage{To: "alice@example.com", Subject: "Hi", Text: "Hello"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(mailer.Messages()) != 0 {
+		t.Fatal("expected Send to only queue the email")
+	}
+
+	if err := outbox.DeliverPendingEmails(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(mailer.Messages()) != 1 || repo.emails[1].status != model.OutboxStatusSent {
+		t.Fatalf("expected email to be delivered, got %+v", repo.emails[1])
+	}
+}
+
+func TestEmailOutboxRetriesWithBackoffAndDeadLetters(t *testing.T) {
+	repo := newOutboxRepoStub()
+	outbox := NewEmailOutboxService(repo, failingMailer{})
+	_ = outbox.Send(context.Background(), model.EmailMessage{To: "alice@example.com", Subject: "Hi", Text: "Hello"})
+
+	before := time.Now()
+	if err := outbox.DeliverPendingEmails(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	row := repo.emails[1]
+	if row.status != model.OutboxStatusPending || row.email.Attempts != 1 || row.lastError == "" {
+		t.Fatalf("expected email to be scheduled for retry, got %+v", row)
+	}
+	if row.nextAttemptAt.Before(before.Add(outboxBaseBackoff)) {
+		t.Fatalf("expected retry after backoff, got %s", row.nextAttemptAt)
+	}
+
+	row.email.Attempts = outboxMaxAttempts - 1
+	row.nextAttemptAt = time.Now()
+	if err := outbox.DeliverPendingEmails(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if row.status != model.OutboxStatusDead {
+		t.Fatalf("expected email to be dead-lettered, got %+v", row)
+	}
+	if outbox.failed.Load() != 2 || outbox.deadLettered.Load() != 1 {
+		t.Fatalf("unexpected counters: failed=%d dead=%d", outbox.failed.Load(), outbox.deadLettered.Load())
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		7:  32 * time.Minute,
+		10: time.Hour,
+	}
+	for attempt, want := range cases {
+		if got := outboxBackoff(attempt); got != want {
+			t.Fatalf("attempt %d: expected %s, got %s", attempt, want, got)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+)
+
+// Email templates live in templates/email/<locale>/<name>.txt and .html. The
+// text file defines the "subject" and "text" templates, the HTML file defines
+// "content", which is wrapped in the shared layout.html.
+const (
+	emailTemplateVerification  = "verification"
+	emailTemplateEmailChange   = "email_change"
+	emailTemplateSignInCode    = "sign_in_code"
+	emailTemplatePasswordReset = "password_reset"
+	emailTemplateNewSignIn     = "new_sign_in"
+	emailTemplateInvitation    = "invitation"
+	emailTemplateReminder      = "reminder"
+)
+
+var emailTemplateNames = []string{
+	emailTemplateVerification,
+	emailTemplateEmailChange,
+	emailTemplateSignInCode,
+	emailTemplatePasswordReset,
+	emailTemplateNewSignIn,
+	emailTemplateInvitation,
+	emailTemplateReminder,
+}
+
+//go:embed templates/email
+var emailTemplateFS embed.FS
+
+type emailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+var emailTemplates = mustParseEmailTemplates()
+
+type codeEmailData struct {
+	Code             string
+	ExpiresInMinutes int
+}
+
+type newSignInEmailData struct {
+	Time      string
+	IPAddress string
+	Device    string
+}
+
+type invitationEmailData struct {
+	InviterName string
+	CompanyName string
+	AcceptURL   string
+	ExpiresAt   string
+}
+
+type reminderEmailData struct {
+	EventTitle  string
+	CompanyName string
+	StartTime   string
+	EventURL    string
+}
+
+func mustParseEmailTemplates() map[string]emailTemplate {
+	templates := make(map[string]emailTemplate)
+	for _, locale := range model.SupportedLocales {
+		for _, name := range emailTemplateNames {
+			dir := "templates/email/" + locale + "/"
+
+			text, err := texttemplate.ParseFS(emailTemplateFS, dir+name+".txt")
+			if err != nil {
+				panic(fmt.Sprintf("parse email template %s/%s.txt: %s", locale, name, err))
+			}
+			html, err := htmltemplate.ParseFS(emailTemplateFS, "templates/email/layout.html", dir+"base.html", dir+name+".html")
+			if err != nil {
+				panic(fmt.Sprintf("parse email template %s/%s.html: %s", locale, name, err))
+			}
+
+			templates[locale+"/"+name] = emailTemplate{text: text, html: html}
+		}
+	}
+	return templates
+}
+
+// renderEmail builds a multipart message addressed to to from the named
+// template in the given locale, falling back to the default locale.
+func renderEmail(locale string, name string, to string, data interface{}) (model.EmailMessage, error) {
+	tmpl, ok := emailTemplates[normalizeLocale(locale)+"/"+name]
+	if !ok {
+		return model.EmailMessage{}, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return model.EmailMessage{}, err
+	}
+	if err := tmpl.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return model.EmailMessage{}, err
+	}
+	if err := tmpl.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return model.EmailMessage{}, err
+	}
+
+	return model.EmailMessage{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+// normalizeLocale maps a locale such as "en-US" to a supported one, or to
+// the default locale.
+func normalizeLocale(locale string) string {
+	if supported, ok := supportedLocale(locale); ok {
+		return supported
+	}
+	return model.DefaultLocale
+}
+
+func supportedLocale(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	for _, supported := range model.SupportedLocales {
+		if tag == supported {
+			return supported, true
+		}
+	}
+	return "", false
+}
+
+// PreferredLocale picks the supported locale with the highest weight from an
+// Accept-Language header, e.g. "en-GB,en;q=0.9,ru;q=0.8".
+func PreferredLocale(acceptLanguage string) string {
+	best := model.DefaultLocale
+	bestWeight := 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		locale, ok := supportedLocale(tag)
+		if !ok {
+			continue
+		}
+
+		weight := 1.0
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+		if weight > bestWeight {
+			best, bestWeight = locale, weight
+		}
+	}
+	return best
+}
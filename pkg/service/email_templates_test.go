@@ -0,0 +1,61 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+)
+
+func TestRenderEmailAllTemplates(t *testing.T) {
+	data := map[string]interface{}{
+		emailTemplateVerification:  codeEmailData{Code: "1234", ExpiresInMinutes: 10},
+		emailTemplateEmailChange:   codeEmailData{Code: "1234", ExpiresInMinutes: 10},
+		emailTemplateSignInCode:    codeEmailData{Code: "1234", ExpiresInMinutes: 10},
+		emailTemplatePasswordReset: codeEmailData{Code: "1234", ExpiresInMinutes: 10},
+		emailTemplateNewSignIn:     newSignInEmailData{Time: "2026-01-02 15:04 UTC", IPAddress: "203.0.113.7", Device: "curl/8.0"},
+		emailTemplateInvitation:    invitationEmailData{InviterName: "alice", CompanyName: "Friends", AcceptURL: "https://example.com/invite", ExpiresAt: "2026-01-09"},
+		emailTemplateReminder:      reminderEmailData{EventTitle: "Picnic", CompanyName: "Friends", StartTime: "2026-01-02 15:04", EventURL: "https://example.com/event"},
+	}
+
+	for _, locale := range model.SupportedLocales {
+		for _, name := range emailTemplateNames {
+			message, err := renderEmail(locale, name, "alice@example.com", data[name])
+			if err != nil {
+				t.Fatalf("%s/%s: expected no error, got %v", locale, name, err)
+			}
+			if message.To != "alice@example.com" || message.Subject == "" || strings.Contains(message.Subject, "\n") {
+				t.Fatalf("%s/%s: unexpected message header: %+v", locale, name, message)
+			}
+			if message.Text == "" || !strings.Contains(message.HTML, `lang="`+locale+`"`) {
+				t.Fatalf("%s/%s: unexpected message body: %+v", locale, name, message)
+			}
+		}
+	}
+}
+
+func TestRenderEmailEscapesHTML(t *testing.T) {
+	message, err := renderEmail(model.LocaleEN, emailTemplateNewSignIn, "alice@example.com", newSignInEmailData{Device: "<script>"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if strings.Contains(message.HTML, "<script>") || !strings.Contains(message.Text, "<script>") {
+		t.Fatalf("expected device to be escaped in HTML only, got %q / %q", message.HTML, message.Text)
+	}
+}
+
+func TestPreferredLocale(t *testing.T) {
+	cases := map[string]string{
+		"":                           model.LocaleRU,
+		"de":                         model.LocaleRU,
+		"en":                         model.LocaleEN,
+		"en-GB,en;q=0.9,ru;q=0.8":    model.LocaleEN,
+		"ru;q=0.5,en-US;q=0.7,de":    model.LocaleEN,
+		"de-DE,ru-RU;q=0.9,en;q=0.3": model.LocaleRU,
+	}
+	for header, want := range cases {
+		if got := PreferredLocale(header); got != want {
+			t.Fatalf("PreferredLocale(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
@@ -2,17 +2,20 @@ package service
 
 import (
 	"errors"
+	"time"
 
 	"github.com/Sovpalo/sovpalo-backend/pkg/model"
 	"github.com/Sovpalo/sovpalo-backend/pkg/repository"
 )
 
 type EventService struct {
-	repo repository.Event
+	repo      repository.Event
+	companies repository.Company
+	activity  repository.Activity
 }
 
-func NewEventService(repo repository.Event) *EventService {
-	return &EventService{repo: repo}
+func NewEventService(repo repository.Event, companies repository.Company, activity repository.Activity) *EventService {
+	return &EventService{repo: repo, companies: companies, activity: activity}
 }
 
 func (s *EventService) CreateEvent(userID int64, input model.EventCreateInput, photoFileName string, photoFileData []byte) (int64, error) {
@@ -23,6 +26,15 @@ func (s *EventService) CreateEvent(userID int64, input model.EventCreateInput, p
 		return 0, errors.New("start_time is required")
 	}
 
+	var rsvpDeadline *time.Time
+	if input.CompanyID != nil {
+		settings, err := s.companies.GetCompanySettings(*input.CompanyID, userID)
+		if err != nil {
+			return 0, err
+		}
+		input.EndTime, rsvpDeadline = applyEventDefaults(settings, *input.StartTime, input.EndTime, time.Now())
+	}
+
 	var newPhotoURL string
 	if len(photoFileData) > 0 {
 		var err error
@@ -34,13 +46,14 @@ func (s *EventService) CreateEvent(userID int64, input model.EventCreateInput, p
 	}
 
 	event := model.Event{
-		CompanyID:   input.CompanyID,
-		CreatedBy:   userID,
-		Title:       input.Title,
-		Description: input.Description,
-		PhotoURL:    input.PhotoURL,
-		StartTime:   input.StartTime,
-		EndTime:     input.EndTime,
+		CompanyID:    input.CompanyID,
+		CreatedBy:    userID,
+		Title:        input.Title,
+		Description:  input.Description,
+		PhotoURL:     input.PhotoURL,
+		StartTime:    input.StartTime,
+		EndTime:      input.EndTime,
+		RSVPDeadline: rsvpDeadline,
 	}
 	id, err := s.repo.CreateEvent(event)
 	if err != nil {
@@ -49,19 +62,90 @@ func (s *EventService) CreateEvent(userID int64, input model.EventCreateInput, p
 		}
 		return 0, err
 	}
+	if input.CompanyID != nil {
+		recordActivity(s.activity, *input.CompanyID, userID, model.ActivityEventCreated, id, input.Title)
+	}
 	return id, nil
 }
 
+// applyEventDefaults fills in what the company settings say for a new
+// event: the end time when it is missing, and the RSVP deadline unless it
+// would already have passed.
+func applyEventDefaults(settings model.CompanySettings, start time.Time, end *time.Time, now time.Time) (*time.Time, *time.Time) {
+	if end == nil {
+		defaultEnd := start.Add(time.Duration(settings.DefaultEventDurationMinutes) * time.Minute)
+		end = &defaultEnd
+	}
+
+	var deadline *time.Time
+	if settings.DefaultRSVPDeadlineMinutes > 0 {
+		value := start.Add(-time.Duration(settings.DefaultRSVPDeadlineMinutes) * time.Minute)
+		if value.After(now) {
+			deadline = &value
+		}
+	}
+	return end, deadline
+}
+
 func (s *EventService) GetEvent(eventID int64, userID int64) (model.Event, error) {
-	return s.repo.GetEvent(eventID, userID)
+	event, err := s.repo.GetEvent(eventID, userID)
+	if err != nil {
+		return model.Event{}, err
+	}
+	events := []model.Event{event}
+	if err := s.inCompanyTimezones(events, userID); err != nil {
+		return model.Event{}, err
+	}
+	return events[0], nil
 }
 
 func (s *EventService) ListEvents(userID int64) ([]model.Event, error) {
-	return s.repo.ListEvents(userID)
+	events, err := s.repo.ListEvents(userID)
+	if err != nil {
+		return nil, err
+	}
+	return events, s.inCompanyTimezones(events, userID)
 }
 
 func (s *EventService) ListCompanyEvents(companyID int64, userID int64) ([]model.Event, error) {
-	return s.repo.ListCompanyEvents(companyID, userID)
+	events, err := s.repo.ListCompanyEvents(companyID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return events, s.inCompanyTimezones(events, userID)
+}
+
+// inCompanyTimezones renders the times of company events in the timezone
+// of their company.
+func (s *EventService) inCompanyTimezones(events []model.Event, userID int64) error {
+	locations := make(map[int64]*time.Location)
+	for i := range events {
+		if events[i].CompanyID == nil {
+			continue
+		}
+		companyID := *events[i].CompanyID
+		location, ok := locations[companyID]
+		if !ok {
+			settings, err := s.companies.GetCompanySettings(companyID, userID)
+			if err != nil {
+				return err
+			}
+			location = settings.Location()
+			locations[companyID] = location
+		}
+		inLocation(&events[i].StartTime, location)
+		inLocation(&events[i].EndTime, location)
+		inLocation(&events[i].RSVPDeadline, location)
+	}
+	return nil
+}
+
+func inLocation(value **time.Time, location *time.Location) {
+	if *value == nil {
+		return
+	}
+	converted := (*value).In(location)
+	*value = &converted
 }
 
 func (s *EventService) UpdateEvent(eventID int64, userID int64, input model.EventUpdateInput, photoFileName string, photoFileData []byte) error {
@@ -100,20 +184,42 @@ func (s *EventService) UpdateEvent(eventID int64, userID int64, input model.Even
 		_ = removeAvatarByURL(*event.PhotoURL)
 	}
 
+	if event.CompanyID != nil {
+		title := event.Title
+		if input.Title != nil {
+			title = *input.Title
+		}
+		recordActivity(s.activity, *event.CompanyID, userID, model.ActivityEventUpdated, eventID, title)
+	}
 	return nil
 }
 
 func (s *EventService) DeleteEvent(eventID int64, userID int64) error {
-	return s.repo.DeleteEvent(eventID, userID)
+	event, err := s.repo.GetEvent(eventID, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteEvent(eventID, userID); err != nil {
+		return err
+	}
+	if event.CompanyID != nil {
+		recordActivity(s.activity, *event.CompanyID, userID, model.ActivityEventDeleted, eventID, event.Title)
+	}
+	return nil
 }
 
 func (s *EventService) SetCompanyEventAttendance(companyID int64, eventID int64, userID int64, status string) error {
 	switch status {
 	case "unknown", "going", "not_going":
-		return s.repo.SetCompanyEventAttendance(companyID, eventID, userID, status)
 	default:
 		return errors.New("invalid status")
 	}
+
+	if err := s.repo.SetCompanyEventAttendance(companyID, eventID, userID, status); err != nil {
+		return err
+	}
+	recordActivity(s.activity, companyID, userID, model.ActivityEventRSVP, eventID, status)
+	return nil
 }
 
 func (s *EventService) ListCompanyEventAttendance(companyID int64, eventID int64, userID int64) ([]model.EventAttendanceView, error) {
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/Sovpalo/sovpalo-backend/pkg/repository"
+	"github.com/jackc/pgx/v5"
+)
+
+type ExportService struct {
+	repo  repository.Export
+	users repository.Authorization
+}
+
+func NewExportService(repo repository.Export, users repository.Authorization) *ExportService {
+	return &ExportService{repo: repo, users: users}
+}
+
+// GetUserDataExport collects everything stored about the user. It is separate
+// from WriteUserDataExport so that errors can still be reported before the
+// archive starts streaming.
+func (s *ExportService) GetUserDataExport(userID int64) (model.UserDataExport, error) {
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.UserDataExport{}, ErrUserNotFound
+		}
+		return model.UserDataExport{}, err
+	}
+
+	export := model.UserDataExport{Profile: userProfile(user)}
+	if export.Memberships, err = s.repo.ListUserMemberships(userID); err != nil {
+		return model.UserDataExport{}, err
+	}
+	if export.Events, err = s.repo.ListUserEvents(userID); err != nil {
+		return model.UserDataExport{}, err
+	}
+	if export.RSVPs, err = s.repo.ListUserRSVPs(userID); err != nil {
+		return model.UserDataExport{}, err
+	}
+	if export.Ideas, err = s.repo.ListUserIdeas(userID); err != nil {
+		return model.UserDataExport{}, err
+	}
+	if export.Likes, err = s.repo.ListUserIdeaLikes(userID); err != nil {
+		return model.UserDataExport{}, err
+	}
+	if export.Availability, err = s.repo.ListUserAvailability(userID); err != nil {
+		return model.UserDataExport{}, err
+	}
+	if export.Media, err = s.repo.ListUserMedia(userID); err != nil {
+		return model.UserDataExport{}, err
+	}
+
+	return export, nil
+}
+
+// WriteUserDataExport writes the export as a ZIP archive with one JSON file
+// per section and the user's uploaded images under images/.
+func (s *ExportService) WriteUserDataExport(export model.UserDataExport, w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	sections := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"memberships.json", emptyIfNil(export.Memberships)},
+		{"events.json", emptyIfNil(export.Events)},
+		{"rsvps.json", emptyIfNil(export.RSVPs)},
+		{"ideas.json", emptyIfNil(export.Ideas)},
+		{"likes.json", emptyIfNil(export.Likes)},
+		{"availability.json", emptyIfNil(export.Availability)},
+		{"media.json", emptyIfNil(export.Media)},
+	}
+	for _, section := range sections {
+		file, err := archive.Create(section.name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(section.data); err != nil {
+			return err
+		}
+	}
+
+	for _, url := range exportImageURLs(export) {
+		if err := addUploadToArchive(archive, url); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func exportImageURLs(export model.UserDataExport) []string {
+	var urls []string
+	seen := map[string]bool{}
+	add := func(url *string) {
+		if url != nil && *url != "" && !seen[*url] {
+			seen[*url] = true
+			urls = append(urls, *url)
+		}
+	}
+
+	add(export.Profile.AvatarURL)
+	for _, event := range export.Events {
+		add(event.PhotoURL)
+	}
+	for _, idea := range export.Ideas {
+		add(idea.PhotoURL)
+	}
+	for _, media := range export.Media {
+		add(&media.FileURL)
+		add(media.ThumbnailURL)
+	}
+	return urls
+}
+
+// addUploadToArchive copies a file uploaded to this server into the archive.
+// External URLs and files that no longer exist are skipped.
+func addUploadToArchive(archive *zip.Writer, url string) error {
+	filePath, ok := avatarURLToPath(url)
+	if !ok {
+		return nil
+	}
+
+	src, err := os.Open(filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer src.Close()
+
+	dst, err := archive.Create("images/" + path.Base(url))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+func emptyIfNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
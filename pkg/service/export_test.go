@@ -0,0 +1,70 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+)
+
+func TestWriteUserDataExportIncludesSectionsAndUploads(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AVATAR_UPLOAD_DIR", dir)
+	if err := os.WriteFile(filepath.Join(dir, "user-1-avatar.png"), []byte("png"), 0o644); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "media-3.jpg"), []byte("jpg"), 0o644); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	avatarURL := "/uploads/avatars/user-1-avatar.png"
+	missingURL := "/uploads/avatars/event-2-missing.png"
+	export := model.UserDataExport{
+		Profile: model.UserProfile{Username: "alice", AvatarURL: &avatarURL},
+		Events:  []model.Event{{ID: 2, Title: "Picnic", PhotoURL: &missingURL}},
+		Media:   []model.MediaArchive{{ID: 3, FileName: "beach.jpg", FileURL: "/uploads/avatars/media-3.jpg"}},
+	}
+
+	var buf bytes.Buffer
+	if err := NewExportService(nil, nil).WriteUserDataExport(export, &buf); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("expected a valid zip, got %v", err)
+	}
+
+	files := map[string]string{}
+	for _, file := range archive.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[file.Name] = string(data)
+	}
+
+	for _, name := range []string{"profile.json", "memberships.json", "events.json", "rsvps.json", "ideas.json", "likes.json", "availability.json", "media.json"} {
+		if _, ok := files[name]; !ok {
+			t.Fatalf("expected %s in archive, got %v", name, files)
+		}
+	}
+	if files["likes.json"] != "[]\n" {
+		t.Fatalf("expected empty list for likes, got %q", files["likes.json"])
+	}
+	if files["images/user-1-avatar.png"] != "png" {
+		t.Fatalf("expected uploaded avatar in archive, got %v", files)
+	}
+	if files["images/media-3.jpg"] != "jpg" {
+		t.Fatalf("expected uploaded media file in archive, got %v", files)
+	}
+	if _, ok := files["images/event-2-missing.png"]; ok {
+		t.Fatal("expected missing upload to be skipped")
+	}
+}
@@ -13,11 +13,12 @@ type HealthService interface {
 }
 
 type Health struct {
-	repo repository.HealthRepository
+	repo   repository.HealthRepository
+	mailer Mailer
 }
 
-func NewHealthService(repo repository.HealthRepository) *Health {
-	return &Health{repo: repo}
+func NewHealthService(repo repository.HealthRepository, mailer Mailer) *Health {
+	return &Health{repo: repo, mailer: mailer}
 }
 
 func (s *Health) Status(ctx context.Context) (string, error) {
@@ -31,7 +32,7 @@ func (s *Health) SMTPStatus(ctx context.Context) (string, error) {
 	healthCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	if err := smtpAuthCheck(healthCtx); err != nil {
+	if err := s.mailer.Check(healthCtx); err != nil {
 		return "smtp_error", err
 	}
 
@@ -8,11 +8,12 @@ import (
 )
 
 type IdeaService struct {
-	repo repository.Idea
+	repo     repository.Idea
+	activity repository.Activity
 }
 
-func NewIdeaService(repo repository.Idea) *IdeaService {
-	return &IdeaService{repo: repo}
+func NewIdeaService(repo repository.Idea, activity repository.Activity) *IdeaService {
+	return &IdeaService{repo: repo, activity: activity}
 }
 
 func (s *IdeaService) CreateCompanyIdea(companyID int64, userID int64, input model.IdeaCreateInput, photoFileName string, photoFileData []byte) (int64, error) {
@@ -37,6 +38,7 @@ func (s *IdeaService) CreateCompanyIdea(companyID int64, userID int64, input mod
 		}
 		return 0, err
 	}
+	recordActivity(s.activity, companyID, userID, model.ActivityIdeaCreated, id, input.Title)
 	return id, nil
 }
 
@@ -84,13 +86,26 @@ func (s *IdeaService) UpdateCompanyIdea(companyID int64, userID int64, ideaID in
 		_ = removeAvatarByURL(*idea.PhotoURL)
 	}
 
+	title := idea.Title
+	if input.Title != nil {
+		title = *input.Title
+	}
+	recordActivity(s.activity, companyID, userID, model.ActivityIdeaUpdated, ideaID, title)
 	return nil
 }
 
 func (s *IdeaService) LikeCompanyIdea(companyID int64, userID int64, ideaID int64) error {
-	return s.repo.LikeCompanyIdea(companyID, userID, ideaID)
+	if err := s.repo.LikeCompanyIdea(companyID, userID, ideaID); err != nil {
+		return err
+	}
+	recordActivity(s.activity, companyID, userID, model.ActivityIdeaLiked, ideaID, "")
+	return nil
 }
 
 func (s *IdeaService) UnlikeCompanyIdea(companyID int64, userID int64, ideaID int64) error {
-	return s.repo.UnlikeCompanyIdea(companyID, userID, ideaID)
+	if err := s.repo.UnlikeCompanyIdea(companyID, userID, ideaID); err != nil {
+		return err
+	}
+	recordActivity(s.activity, companyID, userID, model.ActivityIdeaUnliked, ideaID, "")
+	return nil
 }
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultInvitationTTL    = 7 * 24 * time.Hour
+	defaultReinviteCooldown = 3 * 24 * time.Hour
+
+	// minInvitationTTL keeps invitations from expiring as soon as they are
+	// sent. Cooldowns and the deletion grace period can be turned off with 0.
+	minInvitationTTL = time.Minute
+)
+
+// durationFromEnv reads the variable as a Go duration of at least min, e.g.
+// "168h", falling back to the default when it is unset or invalid.
+func durationFromEnv(name string, fallback time.Duration, min time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < min {
+		logrus.Errorf("invalid %s %q, must be at least %s, using %s", name, value, min, fallback)
+		return fallback
+	}
+	return duration
+}
+
+func (s *CompanyService) ListSentInvitations(companyID int64, userID int64) ([]model.CompanySentInvitationView, error) {
+	return s.repo.ListSentInvitations(companyID, userID)
+}
+
+func (s *CompanyService) RevokeInvitation(companyID int64, userID int64, inviteID int64) error {
+	return s.repo.RevokeInvitation(companyID, userID, inviteID)
+}
+
+// ExpireInvitations is run periodically to expire pending invitations and
+// drop expired email invitations.
+func (s *CompanyService) ExpireInvitations(ctx context.Context) error {
+	expired, removed, err := s.repo.ExpireInvitations()
+	if err != nil {
+		return err
+	}
+	if expired > 0 || removed > 0 {
+		logrus.Infof("expired %d company invitations and %d email invitations", expired, removed)
+	}
+	return nil
+}
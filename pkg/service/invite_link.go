@@ -0,0 +1,63 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+)
+
+// inviteLinkCodeBytes gives 16-character URL-safe codes.
+const inviteLinkCodeBytes = 12
+
+func (s *CompanyService) CreateInviteLink(companyID int64, userID int64, input model.CompanyInviteLinkCreateInput) (model.CompanyInviteLink, error) {
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return model.CompanyInviteLink{}, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidInviteLinkParams)
+	}
+	if input.MaxUses != nil && *input.MaxUses < 1 {
+		return model.CompanyInviteLink{}, fmt.Errorf("%w: max_uses must be positive", ErrInvalidInviteLinkParams)
+	}
+
+	code, err := newInviteLinkCode()
+	if err != nil {
+		return model.CompanyInviteLink{}, err
+	}
+
+	return s.repo.CreateInviteLink(userID, model.CompanyInviteLink{
+		CompanyID: companyID,
+		Code:      code,
+		ExpiresAt: input.ExpiresAt,
+		MaxUses:   input.MaxUses,
+	})
+}
+
+func (s *CompanyService) ListInviteLinks(companyID int64, userID int64) ([]model.CompanyInviteLink, error) {
+	return s.repo.ListInviteLinks(companyID, userID)
+}
+
+func (s *CompanyService) RevokeInviteLink(companyID int64, userID int64, linkID int64) error {
+	return s.repo.RevokeInviteLink(companyID, userID, linkID)
+}
+
+func (s *CompanyService) PreviewInviteLink(code string, userID int64) (model.CompanyInvitePreview, error) {
+	return s.repo.GetInvitePreview(code, userID)
+}
+
+func (s *CompanyService) JoinCompanyByInviteLink(code string, userID int64) (int64, error) {
+	companyID, err := s.repo.JoinCompanyByInviteLink(code, userID)
+	if err != nil {
+		return 0, err
+	}
+	recordActivity(s.activity, companyID, userID, model.ActivityMemberJoined, userID, "")
+	return companyID, nil
+}
+
+func newInviteLinkCode() (string, error) {
+	buf := make([]byte, inviteLinkCodeBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
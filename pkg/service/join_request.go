@@ -0,0 +1,57 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+)
+
+const (
+	defaultDiscoverLimit     = 20
+	maxDiscoverLimit         = 50
+	defaultRerequestCooldown = 3 * 24 * time.Hour
+)
+
+// DiscoverCompanies searches listed companies by name and description.
+func (s *CompanyService) DiscoverCompanies(userID int64, search string, limit int, offset int) ([]model.CompanyDiscoverView, error) {
+	if limit <= 0 {
+		limit = defaultDiscoverLimit
+	}
+	if limit > maxDiscoverLimit {
+		limit = maxDiscoverLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.repo.DiscoverCompanies(userID, strings.TrimSpace(search), limit, offset)
+}
+
+func (s *CompanyService) CreateJoinRequest(companyID int64, userID int64, message *string) (model.CompanyJoinRequest, error) {
+	if message != nil {
+		trimmed := strings.TrimSpace(*message)
+		message = &trimmed
+		if trimmed == "" {
+			message = nil
+		}
+	}
+	return s.repo.CreateJoinRequest(companyID, userID, message, time.Now().Add(-s.rerequestCooldown))
+}
+
+func (s *CompanyService) ListJoinRequests(companyID int64, userID int64) ([]model.CompanyJoinRequestView, error) {
+	return s.repo.ListJoinRequests(companyID, userID)
+}
+
+func (s *CompanyService) ApproveJoinRequest(companyID int64, userID int64, requestID int64) error {
+	requesterID, err := s.repo.RespondToJoinRequest(companyID, userID, requestID, true)
+	if err != nil {
+		return err
+	}
+	recordActivity(s.activity, companyID, requesterID, model.ActivityMemberJoined, requesterID, "")
+	return nil
+}
+
+func (s *CompanyService) RejectJoinRequest(companyID int64, userID int64, requestID int64) error {
+	_, err := s.repo.RespondToJoinRequest(companyID, userID, requestID, false)
+	return err
+}
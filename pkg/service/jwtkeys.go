@@ -0,0 +1,314 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+)
+
+var errUnknownSigningKey = errors.New("token signed with an unknown key")
+
+// KeyManager signs access tokens with the current private key and verifies
+// them against every configured public key, selected by the kid header. Keys
+// are rotated by moving the old key to JWT_PREVIOUS_KEY_FILES and pointing
+// JWT_PRIVATE_KEY_FILE at a new one: tokens signed with the old key stay
+// valid until they expire.
+type KeyManager struct {
+	signingKeyID  string
+	signingMethod jwt.SigningMethod
+	signingKey    crypto.Signer
+	verification  map[string]verificationKey
+	legacySecret  []byte
+	audience      string
+}
+
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    crypto.PublicKey
+}
+
+// NewKeyManagerFromEnv loads the signing key from JWT_PRIVATE_KEY_FILE and
+// retired keys from the comma-separated JWT_PREVIOUS_KEY_FILES. A missing
+// private key is an error unless JWT_ALLOW_EPHEMERAL_KEY=true, which
+// generates an Ed25519 key for local development: access tokens stop
+// verifying after a restart. JWT_SECRET, if set, keeps accepting HS256 tokens
+// issued before the switch to asymmetric keys. JWT_AUDIENCE, if set, is put
+// into every token and required when verifying.
+func NewKeyManagerFromEnv() (*KeyManager, error) {
+	var signingPEM []byte
+	if path := os.Getenv("JWT_PRIVATE_KEY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read JWT_PRIVATE_KEY_FILE: %w", err)
+		}
+		signingPEM = data
+	}
+
+	var previousPEMs [][]byte
+	for _, path := range strings.Split(os.Getenv("JWT_PREVIOUS_KEY_FILES"), ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read JWT_PREVIOUS_KEY_FILES: %w", err)
+		}
+		previousPEMs = append(previousPEMs, data)
+	}
+
+	if signingPEM == nil {
+		if os.Getenv("JWT_ALLOW_EPHEMERAL_KEY") != "true" {
+			return nil, errors.New("JWT_PRIVATE_KEY_FILE is not set, set JWT_ALLOW_EPHEMERAL_KEY=true to use a temporary key for local development")
+		}
+		logrus.Warn("JWT_PRIVATE_KEY_FILE not set, signing access tokens with an ephemeral key")
+	}
+
+	manager, err := newKeyManager(signingPEM, previousPEMs, []byte(os.Getenv("JWT_SECRET")))
+	if err != nil {
+		return nil, err
+	}
+	manager.audience = strings.TrimSpace(os.Getenv("JWT_AUDIENCE"))
+	return manager, nil
+}
+
+func newKeyManager(signingPEM []byte, previousPEMs [][]byte, legacySecret []byte) (*KeyManager, error) {
+	var signer crypto.Signer
+	if signingPEM == nil {
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		signer = key
+	} else {
+		key, err := parsePrivateKeyPEM(signingPEM)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE: %w", err)
+		}
+		signer = key
+	}
+
+	manager := &KeyManager{
+		signingKey:   signer,
+		verification: map[string]verificationKey{},
+		legacySecret: legacySecret,
+	}
+
+	method, kid, err := manager.addVerificationKey(signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("JWT_PRIVATE_KEY_FILE: %w", err)
+	}
+	manager.signingMethod = method
+	manager.signingKeyID = kid
+
+	for _, data := range previousPEMs {
+		public, err := parsePublicKeyPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_PREVIOUS_KEY_FILES: %w", err)
+		}
+		if _, _, err := manager.addVerificationKey(public); err != nil {
+			return nil, fmt.Errorf("JWT_PREVIOUS_KEY_FILES: %w", err)
+		}
+	}
+
+	return manager, nil
+}
+
+func (m *KeyManager) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(m.signingMethod, claims)
+	token.Header["kid"] = m.signingKeyID
+	return token.SignedString(m.signingKey)
+}
+
+// Parse verifies the token signature with the key named by its kid header,
+// checks the issuer and, if one is configured, the audience, and fills
+// claims. HS256 tokens without a kid predate the issuer and audience claims:
+// with a legacy secret they are checked for the signature and expiry only.
+func (m *KeyManager) Parse(tokenString string, claims jwt.Claims) error {
+	if len(m.legacySecret) > 0 && isLegacyToken(tokenString) {
+		_, err := jwt.ParseWithClaims(tokenString, claims, func(*jwt.Token) (interface{}, error) {
+			return m.legacySecret, nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+		return err
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer(tokenIssuer),
+	}
+	if m.audience != "" {
+		options = append(options, jwt.WithAudience(m.audience))
+	}
+	_, err := jwt.ParseWithClaims(tokenString, claims, m.keyFunc, options...)
+	return err
+}
+
+// isLegacyToken reports whether the token is an HS256 token issued before
+// the switch to asymmetric keys, which had no kid header.
+func isLegacyToken(tokenString string) bool {
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		return false
+	}
+	kid, _ := token.Header["kid"].(string)
+	return kid == "" && token.Method.Alg() == jwt.SigningMethodHS256.Alg()
+}
+
+// Audience returns the audience put into access tokens, if any.
+func (m *KeyManager) Audience() jwt.ClaimStrings {
+	if m.audience == "" {
+		return nil
+	}
+	return jwt.ClaimStrings{m.audience}
+}
+
+// JWKS returns the public keys in JSON Web Key Set format, with the current
+// signing key first.
+func (m *KeyManager) JWKS() model.JSONWebKeySet {
+	ids := make([]string, 0, len(m.verification))
+	for kid := range m.verification {
+		if kid != m.signingKeyID {
+			ids = append(ids, kid)
+		}
+	}
+	sort.Strings(ids)
+	ids = append([]string{m.signingKeyID}, ids...)
+
+	keys := make([]model.JSONWebKey, 0, len(ids))
+	for _, kid := range ids {
+		entry := m.verification[kid]
+		jwk := publicJWK(entry.key)
+		jwk.Kid = kid
+		jwk.Alg = entry.method.Alg()
+		jwk.Use = "sig"
+		keys = append(keys, jwk)
+	}
+	return model.JSONWebKeySet{Keys: keys}
+}
+
+func (m *KeyManager) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	entry, ok := m.verification[kid]
+	if !ok || entry.method.Alg() != token.Method.Alg() {
+		return nil, errUnknownSigningKey
+	}
+	return entry.key, nil
+}
+
+func (m *KeyManager) addVerificationKey(public crypto.PublicKey) (jwt.SigningMethod, string, error) {
+	var method jwt.SigningMethod
+	switch key := public.(type) {
+	case ed25519.PublicKey:
+		method = jwt.SigningMethodEdDSA
+	case *rsa.PublicKey:
+		if key.N.BitLen() < 2048 {
+			return nil, "", errors.New("RSA keys must be at least 2048 bits")
+		}
+		method = jwt.SigningMethodRS256
+	default:
+		return nil, "", fmt.Errorf("unsupported key type %T, use Ed25519 or RSA", public)
+	}
+
+	kid := jwkThumbprint(publicJWK(public))
+	m.verification[kid] = verificationKey{method: method, key: public}
+	return method, kid, nil
+}
+
+func parsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+// parsePublicKeyPEM accepts a public key or a whole private key, so a retired
+// key file can be kept as is.
+func parsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		signer, err := parsePrivateKeyPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		return signer.Public(), nil
+	}
+}
+
+func publicJWK(public crypto.PublicKey) model.JSONWebKey {
+	switch key := public.(type) {
+	case ed25519.PublicKey:
+		return model.JSONWebKey{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key),
+		}
+	case *rsa.PublicKey:
+		return model.JSONWebKey{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}
+	default:
+		return model.JSONWebKey{}
+	}
+}
+
+// jwkThumbprint computes the RFC 7638 thumbprint used as the key ID, so the
+// same key always gets the same kid on every instance.
+func jwkThumbprint(jwk model.JSONWebKey) string {
+	var members map[string]string
+	switch jwk.Kty {
+	case "OKP":
+		members = map[string]string{"crv": jwk.Crv, "kty": jwk.Kty, "x": jwk.X}
+	case "RSA":
+		members = map[string]string{"e": jwk.E, "kty": jwk.Kty, "n": jwk.N}
+	}
+
+	// encoding/json writes map keys in sorted order, as RFC 7638 requires
+	canonical, _ := json.Marshal(members)
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
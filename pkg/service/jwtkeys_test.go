@@ -0,0 +1,172 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func testPrivateKeyPEM(t *testing.T, key interface{}) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func testClaims() *tokenClaims {
+	now := time.Now()
+	return &tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+		UserId: 7,
+	}
+}
+
+func TestKeyManagerAcceptsTokensFromRotatedKey(t *testing.T) {
+	_, oldKey, _ := ed25519.GenerateKey(rand.Reader)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	oldPEM := testPrivateKeyPEM(t, oldKey)
+
+	before, err := newKeyManager(oldPEM, nil, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	oldToken, err := before.Sign(testClaims())
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	after, err := newKeyManager(testPrivateKeyPEM(t, newKey), [][]byte{oldPEM}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var claims tokenClaims
+	if err := after.Parse(oldToken, &claims); err != nil || claims.UserId != 7 {
+		t.Fatalf("expected token from the previous key to verify, got id=%d err=%v", claims.UserId, err)
+	}
+
+	newToken, err := after.Sign(testClaims())
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if err := before.Parse(newToken, &tokenClaims{}); !errors.Is(err, errUnknownSigningKey) {
+		t.Fatalf("expected token from an unknown key to be rejected, got %v", err)
+	}
+
+	keySet := after.JWKS()
+	if len(keySet.Keys) != 2 {
+		t.Fatalf("expected 2 published keys, got %d", len(keySet.Keys))
+	}
+	if keySet.Keys[0].Kid != after.signingKeyID || keySet.Keys[0].Alg != "RS256" || keySet.Keys[1].Alg != "EdDSA" {
+		t.Fatalf("unexpected key set: %+v", keySet.Keys)
+	}
+}
+
+func TestKeyManagerLegacySecretIsOptIn(t *testing.T) {
+	now := time.Now()
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp":     now.Add(time.Minute).Unix(),
+		"iat":     now.Unix(),
+		"user_id": 7,
+	})
+	legacyToken, err := legacy.SignedString([]byte("old-secret"))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	strict, err := newKeyManager(nil, nil, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := strict.Parse(legacyToken, &tokenClaims{}); err == nil {
+		t.Fatalf("expected HS256 token to be rejected without a legacy secret")
+	}
+
+	compatible, err := newKeyManager(nil, nil, []byte("old-secret"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var claims tokenClaims
+	if err := compatible.Parse(legacyToken, &claims); err != nil || claims.UserId != 7 {
+		t.Fatalf("expected HS256 token to be accepted with the legacy secret, got id=%d err=%v", claims.UserId, err)
+	}
+
+	compatible.audience = "sovpalo-api"
+	if err := compatible.Parse(legacyToken, &tokenClaims{}); err != nil {
+		t.Fatalf("expected HS256 token to be accepted without an audience, got %v", err)
+	}
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims())
+	forgedToken, err := forged.SignedString([]byte("another-secret"))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if err := compatible.Parse(forgedToken, &tokenClaims{}); err == nil {
+		t.Fatalf("expected HS256 token signed with another secret to be rejected")
+	}
+}
+
+func TestKeyManagerChecksIssuerAndAudience(t *testing.T) {
+	manager, err := newKeyManager(nil, nil, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	foreign := testClaims()
+	foreign.Issuer = "someone-else"
+	token, err := manager.Sign(foreign)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if err := manager.Parse(token, &tokenClaims{}); !errors.Is(err, jwt.ErrTokenInvalidIssuer) {
+		t.Fatalf("expected a foreign issuer to be rejected, got %v", err)
+	}
+
+	manager.audience = "sovpalo-api"
+	token, err = manager.Sign(testClaims())
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if err := manager.Parse(token, &tokenClaims{}); !errors.Is(err, jwt.ErrTokenRequiredClaimMissing) {
+		t.Fatalf("expected a token without the audience to be rejected, got %v", err)
+	}
+
+	claims := testClaims()
+	claims.Audience = manager.Audience()
+	token, err = manager.Sign(claims)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if err := manager.Parse(token, &tokenClaims{}); err != nil {
+		t.Fatalf("expected a token with the audience to verify, got %v", err)
+	}
+}
+
+func TestNewKeyManagerFromEnvRequiresKeyOutsideDevelopment(t *testing.T) {
+	t.Setenv("JWT_PRIVATE_KEY_FILE", "")
+	t.Setenv("JWT_ALLOW_EPHEMERAL_KEY", "")
+	if _, err := NewKeyManagerFromEnv(); err == nil {
+		t.Fatalf("expected an error without JWT_PRIVATE_KEY_FILE")
+	}
+
+	t.Setenv("JWT_ALLOW_EPHEMERAL_KEY", "true")
+	if _, err := NewKeyManagerFromEnv(); err != nil {
+		t.Fatalf("expected an ephemeral key in development, got %v", err)
+	}
+}
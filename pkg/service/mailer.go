@@ -0,0 +1,155 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/sirupsen/logrus"
+)
+
+// Mailer delivers emails. Check reports whether the mailer is able to send,
+// it backs the /health/smtp endpoint.
+type Mailer interface {
+	Send(ctx context.Context, message model.EmailMessage) error
+	Check(ctx context.Context) error
+}
+
+const (
+	MailDriverSMTP   = "smtp"
+	MailDriverFile   = "file"
+	MailDriverMemory = "memory"
+)
+
+const defaultMailFileDir = "mail"
+
+// NewMailerFromEnv picks the driver from MAIL_DRIVER: smtp (default), file,
+// which writes .eml files to MAIL_FILE_DIR, or memory.
+func NewMailerFromEnv() Mailer {
+	switch driver := strings.ToLower(defaultString(os.Getenv("MAIL_DRIVER"), MailDriverSMTP)); driver {
+	case MailDriverSMTP:
+		return NewSMTPMailer()
+	case MailDriverFile:
+		return NewFileMailer(defaultString(os.Getenv("MAIL_FILE_DIR"), defaultMailFileDir))
+	case MailDriverMemory:
+		return NewMemoryMailer()
+	default:
+		logrus.Errorf("unknown MAIL_DRIVER %q, using %s", driver, MailDriverSMTP)
+		return NewSMTPMailer()
+	}
+}
+
+// FileMailer writes every message as an .eml file instead of sending it. It
+// is meant for local development.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir string) *FileMailer {
+	return &FileMailer{
+		dir:  dir,
+		from: defaultString(os.Getenv("SMTP_FROM"), "no-reply@sovpalo.local"),
+	}
+}
+
+func (m *FileMailer) Send(ctx context.Context, message model.EmailMessage) error {
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+
+	randomPart, err := randomHex(4)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	fileName := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), randomPart)
+	return os.WriteFile(filepath.Join(m.dir, fileName), buildEmail(m.from, message, now), 0o644)
+}
+
+func (m *FileMailer) Check(ctx context.Context) error {
+	return os.MkdirAll(m.dir, 0o755)
+}
+
+// MemoryMailer keeps sent messages in memory so tests can inspect them.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []model.EmailMessage
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(ctx context.Context, message model.EmailMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = append(m.messages, message)
+	return nil
+}
+
+func (m *MemoryMailer) Check(ctx context.Context) error {
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (m *MemoryMailer) Messages() []model.EmailMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]model.EmailMessage(nil), m.messages...)
+}
+
+// buildEmail renders the message in RFC 5322 format. A message with an HTML
+// body becomes multipart/alternative with the plain-text part first, as
+// clients show the last part they support.
+func buildEmail(from string, message model.EmailMessage, date time.Time) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	if message.HTML == "" {
+		writeQuotedPrintablePart(&buf, "text/plain; charset=utf-8", message.Text)
+		return buf.Bytes()
+	}
+
+	parts := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", parts.Boundary())
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", message.Text},
+		{"text/html; charset=utf-8", message.HTML},
+	} {
+		w, _ := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		qp := quotedprintable.NewWriter(w)
+		_, _ = qp.Write([]byte(part.body))
+		_ = qp.Close()
+	}
+	_ = parts.Close()
+	return buf.Bytes()
+}
+
+func writeQuotedPrintablePart(buf *bytes.Buffer, contentType string, body string) {
+	fmt.Fprintf(buf, "Content-Type: %s\r\n", contentType)
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(buf)
+	_, _ = qp.Write([]byte(body))
+	_ = qp.Close()
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+)
+
+func TestAuthServiceStartCodeSignInSendsCode(t *testing.T) {
+	repo := newAuthRepoStub()
+	repo.users[1] = model.User{ID: 1, Email: "alice@example.com"}
+	mailer := NewMemoryMailer()
+	svc := NewAuthService(repo, nil, nil, nil, mailer)
+
+	if err := svc.StartCodeSignIn(model.SignInCodeInput{Email: "alice@example.com"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	messages := mailer.Messages()
+	if len(messages) != 1 || messages[0].To != "alice@example.com" {
+		t.Fatalf("unexpected messages: %+v", messages)
+	}
+	code := repo.challenges[model.AuthChallengeTypeLogin].Code
+	if !strings.Contains(messages[0].Text, code) {
+		t.Fatalf("expected message to contain code %s, got %q", code, messages[0].Text)
+	}
+}
+
+func TestFileMailerWritesEML(t *testing.T) {
+	dir := t.TempDir()
+	mailer := NewFileMailer(dir)
+
+	err := mailer.Send(context.Background(), model.EmailMessage{
+		To:      "alice@example.com",
+		Subject: "Код подтверждения",
+		Text:    "Your code is 1234.",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one .eml file, got %v (err=%v)", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	content := string(data)
+	for _, want := range []string{"To: alice@example.com\r\n", "Subject: =?utf-8?q?", "quoted-printable\r\n\r\nYour code is 1234."} {
+		if !strings.Contains(content, want) {
+			t.Fatalf("expected %q in message:\n%s", want, content)
+		}
+	}
+}
@@ -0,0 +1,599 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrOIDCProviderNotFound    = errors.New("oidc provider not found")
+	ErrOIDCProviderUnavailable = errors.New("oidc provider unavailable")
+	ErrInvalidOIDCState        = errors.New("invalid or expired oidc state")
+	ErrOIDCSignInFailed        = errors.New("oidc sign-in failed")
+)
+
+const (
+	oidcHTTPTimeout         = 10 * time.Second
+	oidcMaxResponseSize     = 1 << 20
+	oidcJWKSRefreshInterval = time.Minute
+	oidcClockSkew           = time.Minute
+)
+
+var oidcSigningMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// oidcProvider talks to one OpenID Connect provider. Its discovery document
+// and signing keys are fetched on first use and cached; the keys are fetched
+// again when a token names an unknown kid, so provider key rotation is picked
+// up without a restart.
+type oidcProvider struct {
+	name         string
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	scopes       []string
+	client       *http.Client
+
+	mu            sync.Mutex
+	metadata      *oidcMetadata
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcIDTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string   `json:"nonce"`
+	AuthorizedParty   string   `json:"azp"`
+	Email             string   `json:"email"`
+	EmailVerified     oidcBool `json:"email_verified"`
+	PreferredUsername string   `json:"preferred_username"`
+	GivenName         string   `json:"given_name"`
+	FamilyName        string   `json:"family_name"`
+	Picture           string   `json:"picture"`
+	Locale            string   `json:"locale"`
+}
+
+// oidcBool accepts both true and "true": some providers send email_verified
+// as a string.
+type oidcBool bool
+
+func (b *oidcBool) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case bool:
+		*b = oidcBool(v)
+	case string:
+		*b = oidcBool(strings.EqualFold(v, "true"))
+	default:
+		*b = false
+	}
+	return nil
+}
+
+// oidcProvidersFromEnv reads the comma-separated OIDC_PROVIDERS list and, for
+// each name, OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _REDIRECT_URL and
+// the optional _SCOPES.
+func oidcProvidersFromEnv() map[string]*oidcProvider {
+	providers := map[string]*oidcProvider{}
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		provider := newOIDCProvider(
+			name,
+			os.Getenv(prefix+"ISSUER"),
+			os.Getenv(prefix+"CLIENT_ID"),
+			os.Getenv(prefix+"CLIENT_SECRET"),
+			os.Getenv(prefix+"REDIRECT_URL"),
+			strings.Fields(os.Getenv(prefix+"SCOPES")),
+		)
+		if provider.issuer == "" || provider.clientID == "" || provider.redirectURL == "" {
+			logrus.Errorf("OIDC provider %q needs %sISSUER, %sCLIENT_ID and %sREDIRECT_URL, skipping it", name, prefix, prefix, prefix)
+			continue
+		}
+		providers[name] = provider
+	}
+	return providers
+}
+
+func newOIDCProvider(name, issuer, clientID, clientSecret, redirectURL string, scopes []string) *oidcProvider {
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+	hasOpenID := false
+	for _, scope := range scopes {
+		if scope == "openid" {
+			hasOpenID = true
+		}
+	}
+	if !hasOpenID {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+
+	return &oidcProvider{
+		name:         name,
+		issuer:       issuer,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		scopes:       scopes,
+		client:       &http.Client{Timeout: oidcHTTPTimeout},
+	}
+}
+
+// StartOIDCSignIn returns the provider URL to send the browser to. The state,
+// nonce and PKCE verifier are kept in Redis until the callback.
+func (s *AuthService) StartOIDCSignIn(providerName string) (string, error) {
+	provider, ok := s.oidcProviders[providerName]
+	if !ok {
+		return "", ErrOIDCProviderNotFound
+	}
+
+	state, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	verifier, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := provider.authCodeURL(context.Background(), state, nonce, pkceChallenge(verifier))
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrOIDCProviderUnavailable, err.Error())
+	}
+
+	if err := s.repo.SaveOIDCState(hashToken(state), model.OIDCAuthState{
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+	}, s.pendingTTL); err != nil {
+		return "", err
+	}
+
+	return authURL, nil
+}
+
+// CompleteOIDCSignIn exchanges the authorization code, verifies the ID token
+// and signs in the linked user. On first sign-in the identity is linked to the
+// account with the same verified email, or a new account is created.
+func (s *AuthService) CompleteOIDCSignIn(providerName string, code string, state string, meta model.SessionMeta) (model.SignInResult, error) {
+	provider, ok := s.oidcProviders[providerName]
+	if !ok {
+		return model.SignInResult{}, ErrOIDCProviderNotFound
+	}
+	if code == "" || state == "" {
+		return model.SignInResult{}, ErrInvalidOIDCState
+	}
+
+	authState, err := s.repo.TakeOIDCState(hashToken(state))
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return model.SignInResult{}, ErrInvalidOIDCState
+		}
+		return model.SignInResult{}, err
+	}
+	if authState.Provider != providerName {
+		return model.SignInResult{}, ErrInvalidOIDCState
+	}
+
+	claims, err := provider.signIn(context.Background(), code, authState.CodeVerifier, authState.Nonce)
+	if err != nil {
+		logrus.Warnf("oidc sign-in with %s failed: %s", providerName, err.Error())
+		s.audit(model.AuditEventSignIn, 0, meta, ErrOIDCSignInFailed, "oidc:"+providerName)
+		return model.SignInResult{}, ErrOIDCSignInFailed
+	}
+
+	user, err := s.oidcUser(providerName, claims, meta.Locale)
+	if err != nil {
+		return model.SignInResult{}, err
+	}
+
+	return s.completeSignIn(user, "oidc:"+providerName, meta)
+}
+
+func (s *AuthService) oidcUser(providerName string, claims oidcIDTokenClaims, locale string) (model.User, error) {
+	user, err := s.repo.GetUserByIdentity(providerName, claims.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return model.User{}, err
+	}
+
+	identity := model.UserIdentity{
+		Provider: providerName,
+		Subject:  claims.Subject,
+	}
+	if claims.Email != "" {
+		email := claims.Email
+		identity.Email = &email
+	}
+
+	if claims.Email != "" && claims.EmailVerified {
+		existing, err := s.repo.GetUserByEmail(claims.Email)
+		if err == nil {
+			identity.UserID = existing.ID
+			if err := s.repo.CreateUserIdentity(identity); err != nil {
+				return model.User{}, err
+			}
+			return existing, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return model.User{}, err
+		}
+	}
+
+	preferred := claims.PreferredUsername
+	if preferred == "" {
+		preferred = claims.Email
+	}
+	if at := strings.Index(preferred, "@"); at >= 0 {
+		preferred = preferred[:at]
+	}
+	suffix, err := randomHex(4)
+	if err != nil {
+		return model.User{}, err
+	}
+	username, err := s.availableUsername(preferred, providerName+"_"+suffix)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	newUser := model.User{Username: username, Locale: normalizeLocale(locale)}
+	if claimLocale, ok := supportedLocale(claims.Locale); ok {
+		newUser.Locale = claimLocale
+	}
+	if claims.EmailVerified {
+		newUser.Email = claims.Email
+	}
+	if firstName, err := normalizeDisplayName("first_name", claims.GivenName); err == nil && firstName != "" {
+		newUser.FirstName = &firstName
+	}
+	if secondName, err := normalizeDisplayName("second_name", claims.FamilyName); err == nil && secondName != "" {
+		newUser.SecondName = &secondName
+	}
+	if claims.Picture != "" {
+		picture := claims.Picture
+		newUser.AvatarURL = &picture
+	}
+
+	userID, err := s.repo.CreateUserWithIdentity(newUser, identity)
+	if err != nil {
+		return model.User{}, err
+	}
+	newUser.ID = userID
+	if newUser.Email != "" {
+		s.claimEmailInvitations(userID, newUser.Email)
+	}
+	return newUser, nil
+}
+
+func (p *oidcProvider) authCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.clientID)
+	query.Set("redirect_uri", p.redirectURL)
+	query.Set("scope", strings.Join(p.scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), nil
+}
+
+// signIn exchanges the authorization code for tokens and returns the verified
+// ID token claims.
+func (p *oidcProvider) signIn(ctx context.Context, code, codeVerifier, nonce string) (oidcIDTokenClaims, error) {
+	idToken, err := p.exchange(ctx, code, codeVerifier)
+	if err != nil {
+		return oidcIDTokenClaims{}, err
+	}
+	return p.verifyIDToken(ctx, idToken, nonce)
+}
+
+func (p *oidcProvider) exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.redirectURL)
+	form.Set("code_verifier", codeVerifier)
+	if p.clientSecret == "" {
+		form.Set("client_id", p.clientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.clientID), url.QueryEscape(p.clientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, oidcMaxResponseSize))
+	if err != nil {
+		return "", err
+	}
+
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return "", fmt.Errorf("token endpoint returned %d: %s %s", resp.StatusCode, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return "", errors.New("token response has no id_token")
+	}
+	return token.IDToken, nil
+}
+
+func (p *oidcProvider) verifyIDToken(ctx context.Context, rawIDToken, nonce string) (oidcIDTokenClaims, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return oidcIDTokenClaims{}, err
+	}
+
+	var claims oidcIDTokenClaims
+	_, err = jwt.ParseWithClaims(rawIDToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(ctx, kid)
+	},
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(p.clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(oidcClockSkew),
+	)
+	if err != nil {
+		return oidcIDTokenClaims{}, err
+	}
+
+	if claims.Subject == "" {
+		return oidcIDTokenClaims{}, errors.New("id token has no subject")
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.clientID {
+		return oidcIDTokenClaims{}, errors.New("id token was issued to another client")
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return oidcIDTokenClaims{}, errors.New("id token nonce mismatch")
+	}
+	return claims, nil
+}
+
+func (p *oidcProvider) discover(ctx context.Context) (oidcMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return *p.metadata, nil
+	}
+
+	var metadata oidcMetadata
+	if err := p.getJSON(ctx, strings.TrimSuffix(p.issuer, "/")+"/.well-known/openid-configuration", &metadata); err != nil {
+		return oidcMetadata{}, err
+	}
+	if metadata.Issuer != p.issuer {
+		return oidcMetadata{}, fmt.Errorf("discovery document issuer %q does not match %q", metadata.Issuer, p.issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return oidcMetadata{}, errors.New("discovery document is missing endpoints")
+	}
+
+	p.metadata = &metadata
+	return metadata, nil
+}
+
+func (p *oidcProvider) publicKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	metadata, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if p.keys != nil && time.Since(p.keysFetchedAt) < oidcJWKSRefreshInterval {
+		return nil, errUnknownSigningKey
+	}
+
+	var set model.JSONWebKeySet
+	if err := p.getJSON(ctx, metadata.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwkPublicKey(jwk)
+		if err != nil {
+			logrus.Warnf("skipping key %q of OIDC provider %s: %s", jwk.Kid, p.name, err.Error())
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, errUnknownSigningKey
+}
+
+// lookupKey finds the key by kid. A token without kid is accepted only when
+// the provider publishes a single key.
+func (p *oidcProvider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *oidcProvider) getJSON(ctx context.Context, endpoint string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %d", endpoint, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponseSize)).Decode(dst)
+}
+
+// jwkPublicKey is the inverse of publicJWK, extended with the EC keys that
+// providers commonly publish.
+func jwkPublicKey(jwk model.JSONWebKey) (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decode(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
+
+// pkceChallenge derives the S256 code challenge from the code verifier (RFC 7636).
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockOIDCServer is a minimal OpenID Connect provider: discovery, JWKS and a
+// token endpoint that enforces PKCE. authorize plays the part of the user
+// approving the sign-in and returns the authorization code.
+type mockOIDCServer struct {
+	*httptest.Server
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]mockOIDCGrant
+}
+
+type mockOIDCGrant struct {
+	challenge string
+	nonce     string
+}
+
+func newMockOIDCServer(t *testing.T) *mockOIDCServer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	m := &mockOIDCServer{key: key, codes: map[string]mockOIDCGrant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"jwks_uri":               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(model.JSONWebKeySet{Keys: []model.JSONWebKey{{
+			Kty: "RSA",
+			Kid: "test-key",
+			Alg: "RS256",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, secret, ok := r.BasicAuth()
+		if !ok || clientID != "sovpalo" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+
+		m.mu.Lock()
+		grant, found := m.codes[r.FormValue("code")]
+		delete(m.codes, r.FormValue("code"))
+		m.mu.Unlock()
+		if !found || pkceChallenge(r.FormValue("code_verifier")) != grant.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "opaque",
+			"token_type":   "Bearer",
+			"id_token":     m.idToken(t, grant.nonce),
+		})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *mockOIDCServer) authorize(t *testing.T, authURL string) (string, string) {
+	t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("redirect_uri") != "https://app.example/callback" {
+		t.Fatalf("unexpected authorization request: %s", authURL)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.codes["code-1"] = mockOIDCGrant{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	return "code-1", query.Get("nonce")
+}
+
+func (m *mockOIDCServer) idToken(t *testing.T, nonce string) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            m.URL,
+		"sub":            "subject-42",
+		"aud":            "sovpalo",
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          nonce,
+		"email":          "alice@example.com",
+		"email_verified": "true",
+	})
+	token.Header["kid"] = "test-key"
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		t.Errorf("failed to sign id token: %v", err)
+	}
+	return signed
+}
+
+func TestOIDCProviderSignInWithPKCE(t *testing.T) {
+	server := newMockOIDCServer(t)
+	provider := newOIDCProvider("mock", server.URL, "sovpalo", "secret", "https://app.example/callback", nil)
+	ctx := context.Background()
+
+	verifier := "verifier-0123456789-0123456789-0123456789"
+	authURL, err := provider.authCodeURL(ctx, "state", "nonce-1", pkceChallenge(verifier))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	code, nonce := server.authorize(t, authURL)
+
+	claims, err := provider.signIn(ctx, code, verifier, nonce)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if claims.Subject != "subject-42" || claims.Email != "alice@example.com" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+}
+
+func TestOIDCProviderRejectsWrongVerifierAndNonce(t *testing.T) {
+	server := newMockOIDCServer(t)
+	provider := newOIDCProvider("mock", server.URL, "sovpalo", "secret", "https://app.example/callback", nil)
+	ctx := context.Background()
+
+	verifier := "verifier-0123456789-0123456789-0123456789"
+	authURL, err := provider.authCodeURL(ctx, "state", "nonce-1", pkceChallenge(verifier))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	code, nonce := server.authorize(t, authURL)
+	if _, err := provider.signIn(ctx, code, "another-verifier-0123456789-0123456789", nonce); err == nil {
+		t.Fatal("expected code exchange with a wrong PKCE verifier to fail")
+	}
+
+	code, _ = server.authorize(t, authURL)
+	if _, err := provider.signIn(ctx, code, verifier, "another-nonce"); err == nil {
+		t.Fatal("expected id token with a different nonce to be rejected")
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"crypto/sha1"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordHashCost is the bcrypt work factor for newly created hashes.
+// Stored hashes with a lower cost are upgraded on the next successful sign-in.
+const passwordHashCost = 12
+
+// maxPasswordLength is the longest input bcrypt accepts.
+const maxPasswordLength = 72
+
+// dummyPasswordHash is compared against when the email is unknown so that
+// sign-in takes the same time whether or not the account exists.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("sovpalo-dummy-password"), passwordHashCost)
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+// verifyPassword checks password against a stored hash. Besides bcrypt it
+// accepts the legacy salted SHA-1 format; needsRehash reports whether the
+// stored hash should be replaced with a fresh one.
+func (s *AuthService) verifyPassword(password, storedHash string) (ok bool, needsRehash bool, err error) {
+	if storedHash == "" {
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return false, false, nil
+	}
+
+	if isBcryptHash(storedHash) {
+		if err := bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, false, nil
+			}
+			return false, false, err
+		}
+		cost, err := bcrypt.Cost([]byte(storedHash))
+		if err != nil {
+			return true, true, nil
+		}
+		return true, cost < passwordHashCost, nil
+	}
+
+	legacyHash, err := s.legacyPasswordHash(password)
+	if err != nil {
+		return false, false, err
+	}
+	if subtle.ConstantTimeCompare([]byte(legacyHash), []byte(storedHash)) != 1 {
+		return false, false, nil
+	}
+	return true, true, nil
+}
+
+// legacyPasswordHash reproduces the old SHA-1 + PASSWORD_SALT format. It is
+// only used to verify hashes that have not been upgraded yet.
+func (s *AuthService) legacyPasswordHash(password string) (string, error) {
+	if s.passwordSalt == "" {
+		return "", errors.New("PASSWORD_SALT not set")
+	}
+	hash := sha1.New()
+	hash.Write([]byte(password))
+
+	return fmt.Sprintf("%x", hash.Sum([]byte(s.passwordSalt))), nil
+}
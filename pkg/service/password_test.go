@@ -0,0 +1,68 @@
+package service
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestVerifyPasswordAcceptsBcryptHash(t *testing.T) {
+	svc := &AuthService{}
+
+	hash, err := hashPassword("StrongPass1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	ok, needsRehash, err := svc.verifyPassword("StrongPass1", hash)
+	if err != nil || !ok {
+		t.Fatalf("expected password to match, got ok=%v err=%v", ok, err)
+	}
+	if needsRehash {
+		t.Fatalf("expected fresh hash not to need rehash")
+	}
+
+	ok, _, err = svc.verifyPassword("WrongPass1", hash)
+	if err != nil || ok {
+		t.Fatalf("expected mismatch, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestVerifyPasswordUpgradesLegacyHash(t *testing.T) {
+	svc := &AuthService{passwordSalt: "salt"}
+
+	legacyHash, err := svc.legacyPasswordHash("StrongPass1")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	ok, needsRehash, err := svc.verifyPassword("StrongPass1", legacyHash)
+	if err != nil || !ok {
+		t.Fatalf("expected legacy password to match, got ok=%v err=%v", ok, err)
+	}
+	if !needsRehash {
+		t.Fatalf("expected legacy hash to need rehash")
+	}
+
+	ok, _, err = svc.verifyPassword("WrongPass1", legacyHash)
+	if err != nil || ok {
+		t.Fatalf("expected mismatch, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestVerifyPasswordRehashesLowCostBcrypt(t *testing.T) {
+	svc := &AuthService{}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("StrongPass1"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	ok, needsRehash, err := svc.verifyPassword("StrongPass1", string(hash))
+	if err != nil || !ok {
+		t.Fatalf("expected password to match, got ok=%v err=%v", ok, err)
+	}
+	if !needsRehash {
+		t.Fatalf("expected low-cost hash to need rehash")
+	}
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvalidProfile   = errors.New("invalid profile")
+	ErrNoProfileChanges = errors.New("no fields to update")
+)
+
+// maxProfileFieldLength matches the VARCHAR(100) username and display-name columns.
+const maxProfileFieldLength = 100
+
+// UpdateProfile changes the username and display names right away. A new
+// email is not applied here: a code is sent to the new address and the email
+// is swapped by VerifyEmailChange.
+func (s *AuthService) UpdateProfile(userID int64, input model.UpdateUserInput) (model.UserProfile, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.UserProfile{}, ErrUserNotFound
+		}
+		return model.UserProfile{}, err
+	}
+
+	var update model.UpdateUserInput
+	if input.Username != nil {
+		username := strings.TrimSpace(*input.Username)
+		if username == "" {
+			return model.UserProfile{}, fmt.Errorf("%w: username is required", ErrInvalidProfile)
+		}
+		if utf8.RuneCountInString(username) > maxProfileFieldLength {
+			return model.UserProfile{}, fmt.Errorf("%w: username must be at most %d characters long", ErrInvalidProfile, maxProfileFieldLength)
+		}
+		if username != user.Username {
+			// a case-only change would otherwise collide with the user's own name
+			if !strings.EqualFold(username, user.Username) {
+				exists, err := s.repo.UsernameExists(username)
+				if err != nil {
+					return model.UserProfile{}, err
+				}
+				if exists {
+					return model.UserProfile{}, ErrUsernameAlreadyExists
+				}
+			}
+			update.Username = &username
+		}
+	}
+
+	if input.FirstName != nil {
+		firstName, err := normalizeDisplayName("first_name", *input.FirstName)
+		if err != nil {
+			return model.UserProfile{}, err
+		}
+		update.FirstName = &firstName
+	}
+	if input.SecondName != nil {
+		secondName, err := normalizeDisplayName("second_name", *input.SecondName)
+		if err != nil {
+			return model.UserProfile{}, err
+		}
+		update.SecondName = &secondName
+	}
+
+	locale := user.Locale
+	if input.Locale != nil {
+		supported, ok := supportedLocale(*input.Locale)
+		if !ok {
+			return model.UserProfile{}, fmt.Errorf("%w: locale must be one of %s", ErrInvalidProfile, strings.Join(model.SupportedLocales, ", "))
+		}
+		if supported != user.Locale {
+			update.Locale = &supported
+			locale = supported
+		}
+	}
+
+	var pendingEmail *string
+	if input.Email != nil {
+		email := strings.TrimSpace(*input.Email)
+		if !strings.EqualFold(email, user.Email) {
+			exists, err := s.repo.UserExists(email)
+			if err != nil {
+				return model.UserProfile{}, err
+			}
+			if exists {
+				return model.UserProfile{}, ErrUserAlreadyExists
+			}
+			pendingEmail = &email
+		}
+	}
+
+	hasUpdates := update.Username != nil || update.FirstName != nil || update.SecondName != nil || update.Locale != nil
+	if !hasUpdates && pendingEmail == nil {
+		return model.UserProfile{}, ErrNoProfileChanges
+	}
+
+	if hasUpdates {
+		if err := s.repo.UpdateUserProfile(userID, update); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return model.UserProfile{}, ErrUserNotFound
+			}
+			return model.UserProfile{}, err
+		}
+		if update.Username != nil || update.FirstName != nil || update.SecondName != nil {
+			recordUserActivity(s.activity, userID, model.ActivityProfileUpdated)
+		}
+	}
+
+	if pendingEmail != nil {
+		if err := s.startEmailChange(userID, *pendingEmail, locale); err != nil {
+			return model.UserProfile{}, err
+		}
+	}
+
+	profile, err := s.GetProfile(userID)
+	if err != nil {
+		return model.UserProfile{}, err
+	}
+	profile.PendingEmail = pendingEmail
+	return profile, nil
+}
+
+// VerifyEmailChange checks the code sent to the new address and makes it the
+// user's email. The challenge is looked up by the user and the new address.
+func (s *AuthService) VerifyEmailChange(userID int64, input model.EmailChangeVerifyInput, meta model.SessionMeta) (model.UserProfile, error) {
+	subject := model.AuthChallengeSubject(model.AuthChallengeTypeEmailChange, userID, input.Email)
+	challenge, err := s.verifyChallenge(model.AuthChallengeTypeEmailChange, subject, input.Code)
+	if err != nil {
+		s.audit(model.AuditEventEmailChange, userID, meta, err, "")
+		return model.UserProfile{}, err
+	}
+	if challenge.UserID != userID {
+		return model.UserProfile{}, ErrPendingRegistrationNotFound
+	}
+
+	exists, err := s.repo.UserExists(challenge.Email)
+	if err != nil {
+		return model.UserProfile{}, err
+	}
+	if exists {
+		_ = s.repo.DeletePendingAuthChallenge(model.AuthChallengeTypeEmailChange, subject)
+		return model.UserProfile{}, ErrUserAlreadyExists
+	}
+
+	if err := s.repo.UpdateUserEmail(userID, challenge.Email); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.UserProfile{}, ErrUserNotFound
+		}
+		return model.UserProfile{}, err
+	}
+
+	if err := s.repo.DeletePendingAuthChallenge(model.AuthChallengeTypeEmailChange, subject); err != nil {
+		return model.UserProfile{}, err
+	}
+	s.audit(model.AuditEventEmailChange, userID, meta, nil, "")
+
+	return s.GetProfile(userID)
+}
+
+func (s *AuthService) startEmailChange(userID int64, email string, locale string) error {
+	acquired, err := s.repo.AcquireAuthCooldown(fmt.Sprintf("user:%s:%d", model.AuthChallengeTypeEmailChange, userID), s.resendEmailCooldown)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return ErrResendTooSoon
+	}
+
+	return s.startChallenge(model.PendingAuthChallenge{
+		Type:   model.AuthChallengeTypeEmailChange,
+		Email:  email,
+		UserID: userID,
+		Locale: locale,
+	})
+}
+
+func normalizeDisplayName(field, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if utf8.RuneCountInString(value) > maxProfileFieldLength {
+		return "", fmt.Errorf("%w: %s must be at most %d characters long", ErrInvalidProfile, field, maxProfileFieldLength)
+	}
+	return value, nil
+}
+
+func userProfile(user model.User) model.UserProfile {
+	return model.UserProfile{
+		Email:      user.Email,
+		Username:   user.Username,
+		FirstName:  user.FirstName,
+		SecondName: user.SecondName,
+		AvatarURL:  user.AvatarURL,
+		TelegramID: user.TelegramID,
+		TwoFactor:  user.TOTPEnabledAt != nil,
+		Locale:     normalizeLocale(user.Locale),
+	}
+}
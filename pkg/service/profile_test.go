@@ -0,0 +1,99 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestAuthServiceVerifyEmailChange(t *testing.T) {
+	repo := newAuthRepoStub()
+	svc := NewAuthService(repo, nil, nil, nil, NewMemoryMailer())
+	repo.users[1] = model.User{ID: 1, Email: "alice@example.com", Username: "alice"}
+	repo.users[2] = model.User{ID: 2, Email: "bob@example.com", Username: "bob"}
+	repo.challenges[model.AuthChallengeTypeEmailChange] = model.PendingAuthChallenge{
+		Type:      model.AuthChallengeTypeEmailChange,
+		Email:     "alice@new.example.com",
+		UserID:    1,
+		Code:      "1234",
+		ExpiresAt: time.Now().Add(time.Minute),
+	}
+
+	_, err := svc.VerifyEmailChange(2, model.EmailChangeVerifyInput{Email: "alice@new.example.com", Code: "1234"}, model.SessionMeta{})
+	if !errors.Is(err, ErrPendingRegistrationNotFound) {
+		t.Fatalf("expected another user's challenge to be rejected, got %v", err)
+	}
+	if repo.users[2].Email != "bob@example.com" {
+		t.Fatalf("expected other user's email to stay unchanged, got %q", repo.users[2].Email)
+	}
+
+	profile, err := svc.VerifyEmailChange(1, model.EmailChangeVerifyInput{Email: "alice@new.example.com", Code: "1234"}, model.SessionMeta{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if profile.Email != "alice@new.example.com" {
+		t.Fatalf("expected email to be swapped, got %q", profile.Email)
+	}
+	if _, ok := repo.challenges[model.AuthChallengeTypeEmailChange]; ok {
+		t.Fatalf("expected challenge to be deleted after verification")
+	}
+}
+
+// subjectAuthRepoStub stores challenges by type and subject, like the redis
+// repository does.
+type subjectAuthRepoStub struct {
+	*authRepoStub
+	byKey map[string]model.PendingAuthChallenge
+}
+
+func (s *subjectAuthRepoStub) SavePendingAuthChallenge(challenge model.PendingAuthChallenge, ttl time.Duration) error {
+	s.byKey[string(challenge.Type)+":"+challenge.Subject()] = challenge
+	return nil
+}
+
+func (s *subjectAuthRepoStub) GetPendingAuthChallenge(challengeType model.AuthChallengeType, subject string) (model.PendingAuthChallenge, error) {
+	challenge, ok := s.byKey[string(challengeType)+":"+subject]
+	if !ok {
+		return model.PendingAuthChallenge{}, redis.Nil
+	}
+	return challenge, nil
+}
+
+func (s *subjectAuthRepoStub) DeletePendingAuthChallenge(challengeType model.AuthChallengeType, subject string) error {
+	delete(s.byKey, string(challengeType)+":"+subject)
+	return nil
+}
+
+func (s *subjectAuthRepoStub) IncrementAuthChallengeAttempts(challengeType model.AuthChallengeType, subject string, window time.Duration) (int, error) {
+	if _, ok := s.byKey[string(challengeType)+":"+subject]; !ok {
+		return 0, redis.Nil
+	}
+	return 1, nil
+}
+
+func TestAuthServiceEmailChangeIsKeptPerUser(t *testing.T) {
+	repo := &subjectAuthRepoStub{authRepoStub: newAuthRepoStub(), byKey: map[string]model.PendingAuthChallenge{}}
+	svc := NewAuthService(repo, nil, nil, nil, NewMemoryMailer())
+	repo.users[1] = model.User{ID: 1, Email: "alice@example.com", Username: "alice"}
+	repo.users[2] = model.User{ID: 2, Email: "bob@example.com", Username: "bob"}
+
+	newEmail := "shared@example.com"
+	if err := svc.startEmailChange(1, newEmail, ""); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	aliceCode := repo.byKey[string(model.AuthChallengeTypeEmailChange)+":"+model.AuthChallengeSubject(model.AuthChallengeTypeEmailChange, 1, newEmail)].Code
+	if err := svc.startEmailChange(2, newEmail, ""); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	profile, err := svc.VerifyEmailChange(1, model.EmailChangeVerifyInput{Email: newEmail, Code: aliceCode}, model.SessionMeta{})
+	if err != nil {
+		t.Fatalf("expected the first user's code to survive the second request, got %v", err)
+	}
+	if profile.Email != newEmail {
+		t.Fatalf("expected email to be swapped, got %q", profile.Email)
+	}
+}
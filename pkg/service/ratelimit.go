@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/Sovpalo/sovpalo-backend/pkg/repository"
+	"github.com/sirupsen/logrus"
+)
+
+// Rate limit policies. Each one can be overridden with a RATE_LIMIT_<POLICY>
+// environment variable in the "<limit>/<window>" format, e.g. "10/1m", or
+// disabled with "off".
+const (
+	RateLimitPolicySignIn = "sign_in"
+	RateLimitPolicyEmail  = "email"
+	RateLimitPolicyAuth   = "auth"
+	RateLimitPolicyVerify = "verify"
+	RateLimitPolicyWrite  = "write"
+)
+
+var defaultRateLimitRules = map[string]model.RateLimitRule{
+	RateLimitPolicySignIn: {Limit: 10, Window: time.Minute},
+	RateLimitPolicyEmail:  {Limit: 5, Window: 10 * time.Minute},
+	RateLimitPolicyAuth:   {Limit: 30, Window: time.Minute},
+	RateLimitPolicyVerify: {Limit: 10, Window: 10 * time.Minute},
+	RateLimitPolicyWrite:  {Limit: 120, Window: time.Minute},
+}
+
+type RateLimitService struct {
+	repo  repository.RateLimit
+	rules map[string]model.RateLimitRule
+}
+
+func NewRateLimitService(repo repository.RateLimit) *RateLimitService {
+	rules := make(map[string]model.RateLimitRule, len(defaultRateLimitRules))
+	for policy, fallback := range defaultRateLimitRules {
+		name := "RATE_LIMIT_" + strings.ToUpper(policy)
+		value := os.Getenv(name)
+		rule, err := parseRateLimitRule(value)
+		if err != nil {
+			if strings.TrimSpace(value) != "" {
+				logrus.Errorf("invalid %s, using the default %d/%s: %s", name, fallback.Limit, fallback.Window, err.Error())
+			}
+			rule = fallback
+		}
+		rules[policy] = rule
+	}
+
+	return &RateLimitService{
+		repo:  repo,
+		rules: rules,
+	}
+}
+
+// Allow registers a request from subject under policy. A zero Limit in the
+// result means the policy is disabled and the request is always allowed.
+func (s *RateLimitService) Allow(ctx context.Context, policy string, subject string) (model.RateLimitResult, error) {
+	rule, ok := s.rules[policy]
+	if !ok || rule.Limit <= 0 {
+		return model.RateLimitResult{Allowed: true}, nil
+	}
+
+	return s.repo.Allow(ctx, policy+":"+subject, rule)
+}
+
+func parseRateLimitRule(value string) (model.RateLimitRule, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return model.RateLimitRule{}, fmt.Errorf("rate limit is not set")
+	}
+	if strings.EqualFold(value, "off") {
+		return model.RateLimitRule{}, nil
+	}
+
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return model.RateLimitRule{}, fmt.Errorf("invalid rate limit %q", value)
+	}
+
+	limit, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || limit <= 0 {
+		return model.RateLimitRule{}, fmt.Errorf("invalid rate limit %q", value)
+	}
+
+	window, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || window <= 0 {
+		return model.RateLimitRule{}, fmt.Errorf("invalid rate limit window %q", value)
+	}
+
+	return model.RateLimitRule{Limit: limit, Window: window}, nil
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRateLimitRule(t *testing.T) {
+	rule, err := parseRateLimitRule("10/1m")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if rule.Limit != 10 || rule.Window != time.Minute {
+		t.Fatalf("unexpected rule: %+v", rule)
+	}
+
+	rule, err = parseRateLimitRule("off")
+	if err != nil || rule.Limit != 0 {
+		t.Fatalf("expected disabled rule, got %+v, %v", rule, err)
+	}
+
+	for _, value := range []string{"", "10", "ten/1m", "10/soon", "0/1m", "10/-1m"} {
+		if _, err := parseRateLimitRule(value); err == nil {
+			t.Fatalf("expected error for %q", value)
+		}
+	}
+}
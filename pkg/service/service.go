@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+	"io"
 	"time"
 
 	"github.com/Sovpalo/sovpalo-backend/pkg/model"
@@ -9,42 +11,84 @@ import (
 
 type Service struct {
 	Authorization
+	AccessToken
 	Company
 	Event
 	Availability
 	Idea
+	Activity
+	Export
+	EmailOutbox
+	RateLimiter
 }
 
-func NewService(repos *repository.Repository) *Service {
+// NewService wires the services together. Emails are queued in the outbox
+// and delivered through mailer by the EmailOutbox worker.
+func NewService(repos *repository.Repository, mailer Mailer) *Service {
+	outbox := NewEmailOutboxService(repos.EmailOutbox, mailer)
+
 	return &Service{
-		Authorization: NewAuthService(repos.Authorization),
-		Company:       NewCompanyService(repos.Company),
-		Event:         NewEventService(repos.Event),
-		Availability:  NewAvailabilityService(repos.Availability),
-		Idea:          NewIdeaService(repos.Idea),
+		Authorization: NewAuthService(repos.Authorization, repos.Session, repos.AuditLog, repos.Activity, outbox),
+		AccessToken:   NewAccessTokenService(repos.AccessToken),
+		Company:       NewCompanyService(repos.Company, repos.Activity),
+		Event:         NewEventService(repos.Event, repos.Company, repos.Activity),
+		Availability:  NewAvailabilityService(repos.Availability, repos.Activity),
+		Idea:          NewIdeaService(repos.Idea, repos.Activity),
+		Activity:      NewActivityService(repos.Activity),
+		Export:        NewExportService(repos.Export, repos.Authorization),
+		EmailOutbox:   outbox,
+		RateLimiter:   NewRateLimitService(repos.RateLimit),
 	}
 }
 
 type Authorization interface {
 	CreateUser(user model.User) (int, error)
-	ParseToken(token string) (int, error)
+	ParseToken(token string) (model.TokenIdentity, error)
+	JWKS() (model.JSONWebKeySet, error)
 	UserExists(email string) (bool, error)
 	UsernameExists(username string) (bool, error)
 	GetProfile(userID int64) (model.UserProfile, error)
-	UpdateAvatar(userID int64, fileName string, fileData []byte) (model.UserProfile, error)
-	DeleteAvatar(userID int64) (model.UserProfile, error)
-	DeleteUser(userID int64) error
-	SendCodeToEmail(to string, code string) error
+	UpdateAvatar(userID int64, fileName string, fileData []byte, meta model.SessionMeta) (model.UserProfile, error)
+	DeleteAvatar(userID int64, meta model.SessionMeta) (model.UserProfile, error)
+	UpdateProfile(userID int64, input model.UpdateUserInput) (model.UserProfile, error)
+	VerifyEmailChange(userID int64, input model.EmailChangeVerifyInput, meta model.SessionMeta) (model.UserProfile, error)
+	DeleteUser(userID int64, meta model.SessionMeta) (time.Time, error)
+	PurgeDeletedUsers(ctx context.Context) error
 	GenerateCode() string
 	GenerateToken(email, password string) (string, error)
-	SignIn(input model.SignInInput) (string, error)
+	SignIn(input model.SignInInput, meta model.SessionMeta) (model.SignInResult, error)
+	StartCodeSignIn(input model.SignInCodeInput) error
+	VerifyCodeSignIn(input model.SignInCodeVerifyInput, meta model.SessionMeta) (model.SignInResult, error)
+	ResendSignInCode(email string, clientIP string) error
+	VerifyTwoFactorSignIn(input model.TwoFactorSignInInput, meta model.SessionMeta) (model.AuthTokens, error)
+	StartTOTPEnrollment(userID int64, input model.TOTPEnrollInput, meta model.SessionMeta) (model.TOTPEnrollment, error)
+	ConfirmTOTPEnrollment(userID int64, input model.TOTPConfirmInput, meta model.SessionMeta) (model.RecoveryCodes, error)
+	DisableTOTP(userID int64, input model.TOTPDisableInput, meta model.SessionMeta) error
 	StartRegistration(input model.SignUpInput) error
-	VerifyRegistration(input model.SignUpVerifyInput) (string, error)
-	ResendRegistrationCode(email string) error
+	VerifyRegistration(input model.SignUpVerifyInput, meta model.SessionMeta) (model.AuthTokens, error)
+	ResendRegistrationCode(email string, clientIP string) error
 	StartPasswordReset(email string) error
-	VerifyPasswordReset(input model.ResetPasswordVerifyInput) error
-	ResendPasswordResetCode(email string) error
+	VerifyPasswordReset(input model.ResetPasswordVerifyInput, meta model.SessionMeta) error
+	ResendPasswordResetCode(email string, clientIP string) error
+	ChangePassword(userID int64, input model.ChangePasswordInput, meta model.SessionMeta) (model.AuthTokens, error)
 	PendingRegistrationTTL() time.Duration
+	RefreshSession(refreshToken string, meta model.SessionMeta) (model.AuthTokens, error)
+	Logout(refreshToken string) error
+	ListSessions(userID int64) ([]model.UserSession, error)
+	RevokeSession(userID int64, sessionID int64) error
+	ListSecurityLog(userID int64, beforeID int64, limit int) ([]model.AuthAuditEntry, error)
+	SignInWithTelegram(data map[string]string, meta model.SessionMeta) (model.SignInResult, error)
+	LinkTelegram(userID int64, data map[string]string, meta model.SessionMeta) (model.UserProfile, error)
+	UnlinkTelegram(userID int64, meta model.SessionMeta) (model.UserProfile, error)
+	StartOIDCSignIn(providerName string) (string, error)
+	CompleteOIDCSignIn(providerName string, code string, state string, meta model.SessionMeta) (model.SignInResult, error)
+}
+
+type AccessToken interface {
+	CreateAccessToken(userID int64, input model.PersonalAccessTokenCreateInput) (model.PersonalAccessTokenCreated, error)
+	ListAccessTokens(userID int64) ([]model.PersonalAccessToken, error)
+	RevokeAccessToken(userID int64, tokenID int64) error
+	AuthenticateAccessToken(token string) (model.AccessTokenIdentity, error)
 }
 
 type Company interface {
@@ -54,13 +98,32 @@ type Company interface {
 	UpdateCompany(companyID int64, userID int64, input model.CompanyUpdateInput, avatarFileName string, avatarFileData []byte) error
 	DeleteCompany(companyID int64, userID int64) error
 	LeaveCompany(companyID int64, userID int64, newOwnerID *int64) error
+	TransferOwnership(companyID int64, userID int64, newOwnerID int64) error
+	GetCompanySettings(companyID int64, userID int64) (model.CompanySettings, error)
+	UpdateCompanySettings(companyID int64, userID int64, input model.CompanySettingsUpdateInput) (model.CompanySettings, error)
+	DiscoverCompanies(userID int64, search string, limit int, offset int) ([]model.CompanyDiscoverView, error)
+	CreateJoinRequest(companyID int64, userID int64, message *string) (model.CompanyJoinRequest, error)
+	ListJoinRequests(companyID int64, userID int64) ([]model.CompanyJoinRequestView, error)
+	ApproveJoinRequest(companyID int64, userID int64, requestID int64) error
+	RejectJoinRequest(companyID int64, userID int64, requestID int64) error
 
 	InviteUser(companyID int64, invitedBy int64, username string) (model.CompanyInvitation, error)
 	ListInvitations(userID int64) ([]model.CompanyInvitationView, error)
 	AcceptInvitation(inviteID int64, userID int64) error
 	DeclineInvitation(inviteID int64, userID int64) error
+	ListSentInvitations(companyID int64, userID int64) ([]model.CompanySentInvitationView, error)
+	RevokeInvitation(companyID int64, userID int64, inviteID int64) error
+	ExpireInvitations(ctx context.Context) error
+	CreateInviteLink(companyID int64, userID int64, input model.CompanyInviteLinkCreateInput) (model.CompanyInviteLink, error)
+	ListInviteLinks(companyID int64, userID int64) ([]model.CompanyInviteLink, error)
+	RevokeInviteLink(companyID int64, userID int64, linkID int64) error
+	PreviewInviteLink(code string, userID int64) (model.CompanyInvitePreview, error)
+	JoinCompanyByInviteLink(code string, userID int64) (int64, error)
+	InviteByEmail(companyID int64, userID int64, email string) (model.CompanyEmailInvitation, error)
+	ResendEmailInvitation(companyID int64, userID int64, inviteID int64) (model.CompanyEmailInvitation, error)
 	ListCompanyMembers(companyID int64, userID int64) ([]model.CompanyMemberView, error)
-	RemoveCompanyMember(companyID int64, ownerID int64, memberUserID int64) error
+	RemoveCompanyMember(companyID int64, userID int64, memberUserID int64) error
+	UpdateCompanyMemberRole(companyID int64, userID int64, memberUserID int64, role string) error
 }
 
 type Event interface {
@@ -91,3 +154,22 @@ type Idea interface {
 	LikeCompanyIdea(companyID int64, userID int64, ideaID int64) error
 	UnlikeCompanyIdea(companyID int64, userID int64, ideaID int64) error
 }
+
+type Activity interface {
+	ListCompanyActivity(companyID int64, userID int64, beforeID int64, types []string, limit int) ([]model.CompanyActivityView, error)
+}
+
+type Export interface {
+	GetUserDataExport(userID int64) (model.UserDataExport, error)
+	WriteUserDataExport(export model.UserDataExport, w io.Writer) error
+}
+
+type EmailOutbox interface {
+	DeliverPendingEmails(ctx context.Context) error
+	PurgeSentEmails(ctx context.Context) error
+	EmailOutboxStats() (model.EmailOutboxStats, error)
+}
+
+type RateLimiter interface {
+	Allow(ctx context.Context, policy string, subject string) (model.RateLimitResult, error)
+}
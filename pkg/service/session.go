@@ -0,0 +1,169 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+// issueTokens starts a new session family for the user and returns an access
+// token together with the first refresh token of that family. Signing in
+// cancels a pending account deletion.
+func (s *AuthService) issueTokens(userID int64, meta model.SessionMeta) (model.AuthTokens, error) {
+	if err := s.cancelScheduledDeletion(userID); err != nil {
+		return model.AuthTokens{}, err
+	}
+
+	accessToken, err := s.generateTokenForUser(userID, time.Now())
+	if err != nil {
+		return model.AuthTokens{}, err
+	}
+
+	familyID, err := randomHex(16)
+	if err != nil {
+		return model.AuthTokens{}, err
+	}
+
+	refreshToken, session, err := s.newSession(userID, familyID, meta)
+	if err != nil {
+		return model.AuthTokens{}, err
+	}
+	if _, err := s.sessions.CreateSession(session); err != nil {
+		return model.AuthTokens{}, err
+	}
+
+	return s.authTokens(accessToken, refreshToken), nil
+}
+
+func (s *AuthService) RefreshSession(refreshToken string, meta model.SessionMeta) (model.AuthTokens, error) {
+	session, err := s.sessions.GetSessionByTokenHash(hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.AuthTokens{}, ErrInvalidRefreshToken
+		}
+		return model.AuthTokens{}, err
+	}
+
+	if session.RevokedAt != nil {
+		return model.AuthTokens{}, ErrInvalidRefreshToken
+	}
+	if session.RotatedAt != nil {
+		if err := s.sessions.RevokeSessionFamily(session.FamilyID); err != nil {
+			return model.AuthTokens{}, err
+		}
+		return model.AuthTokens{}, ErrRefreshTokenReused
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return model.AuthTokens{}, ErrInvalidRefreshToken
+	}
+
+	accessToken, err := s.generateTokenForUser(session.UserID, time.Time{})
+	if err != nil {
+		return model.AuthTokens{}, err
+	}
+
+	nextToken, next, err := s.newSession(session.UserID, session.FamilyID, meta)
+	if err != nil {
+		return model.AuthTokens{}, err
+	}
+	if _, err := s.sessions.RotateSession(session.ID, next); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			if err := s.sessions.RevokeSessionFamily(session.FamilyID); err != nil {
+				return model.AuthTokens{}, err
+			}
+			return model.AuthTokens{}, ErrRefreshTokenReused
+		}
+		return model.AuthTokens{}, err
+	}
+
+	return s.authTokens(accessToken, nextToken), nil
+}
+
+func (s *AuthService) Logout(refreshToken string) error {
+	session, err := s.sessions.GetSessionByTokenHash(hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	return s.sessions.RevokeSessionFamily(session.FamilyID)
+}
+
+func (s *AuthService) ListSessions(userID int64) ([]model.UserSession, error) {
+	return s.sessions.ListActiveSessions(userID)
+}
+
+func (s *AuthService) RevokeSession(userID int64, sessionID int64) error {
+	if err := s.sessions.RevokeUserSession(userID, sessionID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// revokeAllTokens signs the user out everywhere: refresh tokens of all
+// sessions and personal access tokens are revoked, and access tokens issued
+// up to now stop being accepted.
+func (s *AuthService) revokeAllTokens(userID int64) error {
+	if err := s.repo.SetTokensValidAfter(userID, time.Now()); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if err := s.repo.DeleteUserAccessTokens(userID); err != nil {
+		return err
+	}
+
+	return s.sessions.RevokeAllUserSessions(userID)
+}
+
+func (s *AuthService) newSession(userID int64, familyID string, meta model.SessionMeta) (string, model.UserSession, error) {
+	refreshToken, err := randomHex(32)
+	if err != nil {
+		return "", model.UserSession{}, err
+	}
+
+	return refreshToken, model.UserSession{
+		UserID:           userID,
+		FamilyID:         familyID,
+		RefreshTokenHash: hashToken(refreshToken),
+		UserAgent:        optionalString(meta.UserAgent),
+		IPAddress:        optionalString(meta.IPAddress),
+		ExpiresAt:        time.Now().Add(s.refreshTTL),
+	}, nil
+}
+
+func (s *AuthService) authTokens(accessToken, refreshToken string) model.AuthTokens {
+	return model.AuthTokens{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresInSec: int(s.accessTTL.Seconds()),
+	}
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -11,6 +11,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
 )
 
 type smtpConfig struct {
@@ -55,7 +57,16 @@ func loadSMTPConfig() (smtpConfig, error) {
 	return cfg, nil
 }
 
-func smtpAuthCheck(ctx context.Context) error {
+// SMTPMailer sends emails through the server configured by the SMTP_*
+// variables. The configuration is read on every send.
+type SMTPMailer struct{}
+
+func NewSMTPMailer() *SMTPMailer {
+	return &SMTPMailer{}
+}
+
+// Check connects and authenticates without sending anything.
+func (m *SMTPMailer) Check(ctx context.Context) error {
 	cfg, err := loadSMTPConfig()
 	if err != nil {
 		return err
@@ -70,7 +81,7 @@ func smtpAuthCheck(ctx context.Context) error {
 	return client.Quit()
 }
 
-func sendSMTPTextEmail(ctx context.Context, to, subject, body string) error {
+func (m *SMTPMailer) Send(ctx context.Context, message model.EmailMessage) error {
 	cfg, err := loadSMTPConfig()
 	if err != nil {
 		return err
@@ -85,7 +96,7 @@ func sendSMTPTextEmail(ctx context.Context, to, subject, body string) error {
 	if err := client.Mail(cfg.from); err != nil {
 		return err
 	}
-	if err := client.Rcpt(to); err != nil {
+	if err := client.Rcpt(message.To); err != nil {
 		return err
 	}
 
@@ -94,8 +105,7 @@ func sendSMTPTextEmail(ctx context.Context, to, subject, body string) error {
 		return err
 	}
 
-	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n", cfg.from, to, subject, body)
-	if _, err := writer.Write([]byte(message)); err != nil {
+	if _, err := writer.Write(buildEmail(cfg.from, message, time.Now())); err != nil {
 		_ = writer.Close()
 		return err
 	}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvalidTelegramAuth   = errors.New("invalid telegram authorization")
+	ErrTelegramAuthExpired   = errors.New("telegram authorization expired")
+	ErrTelegramAlreadyLinked = errors.New("telegram account is already linked to another user")
+	ErrTelegramNotLinked     = errors.New("telegram account is not linked")
+	ErrLastSignInMethod      = errors.New("cannot remove the only sign-in method")
+)
+
+var errTelegramNotConfigured = errors.New("TELEGRAM_BOT_TOKEN not set")
+
+// maxUsernameLength matches users.username VARCHAR(100).
+const maxUsernameLength = 100
+
+// SignInWithTelegram verifies a Telegram Login Widget payload and signs in the
+// user linked to that Telegram account, creating a new account on first use.
+// Users with TOTP enabled get a two-factor challenge instead of tokens.
+func (s *AuthService) SignInWithTelegram(data map[string]string, meta model.SessionMeta) (model.SignInResult, error) {
+	auth, err := s.verifyTelegramAuth(data)
+	if err != nil {
+		if !errors.Is(err, errTelegramNotConfigured) {
+			s.audit(model.AuditEventSignIn, 0, meta, err, signInMethodTelegram)
+		}
+		return model.SignInResult{}, err
+	}
+
+	user, err := s.repo.GetUserByTelegramID(auth.ID)
+	if err == nil {
+		return s.completeSignIn(user, signInMethodTelegram, meta)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return model.SignInResult{}, err
+	}
+
+	username, err := s.availableUsername(auth.Username, fmt.Sprintf("tg%d", auth.ID))
+	if err != nil {
+		return model.SignInResult{}, err
+	}
+
+	telegramID := auth.ID
+	newUser := model.User{
+		Username:   username,
+		TelegramID: &telegramID,
+		Locale:     normalizeLocale(meta.Locale),
+	}
+	if auth.PhotoURL != "" {
+		newUser.AvatarURL = &auth.PhotoURL
+	}
+
+	userID, err := s.repo.CreateUser(newUser)
+	if err != nil {
+		return model.SignInResult{}, err
+	}
+
+	tokens, err := s.issueTokens(int64(userID), meta)
+	if err != nil {
+		return model.SignInResult{}, err
+	}
+	s.audit(model.AuditEventSignUp, int64(userID), meta, nil, signInMethodTelegram)
+	return model.SignInResult{AuthTokens: &tokens}, nil
+}
+
+func (s *AuthService) LinkTelegram(userID int64, data map[string]string, meta model.SessionMeta) (model.UserProfile, error) {
+	auth, err := s.verifyTelegramAuth(data)
+	if err != nil {
+		if !errors.Is(err, errTelegramNotConfigured) {
+			s.audit(model.AuditEventTelegramLink, userID, meta, err, "")
+		}
+		return model.UserProfile{}, err
+	}
+
+	linked, err := s.repo.GetUserByTelegramID(auth.ID)
+	if err == nil && linked.ID != userID {
+		s.audit(model.AuditEventTelegramLink, userID, meta, ErrTelegramAlreadyLinked, "")
+		return model.UserProfile{}, ErrTelegramAlreadyLinked
+	}
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return model.UserProfile{}, err
+	}
+
+	telegramID := auth.ID
+	if err := s.repo.UpdateUserTelegramID(userID, &telegramID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.UserProfile{}, ErrUserNotFound
+		}
+		return model.UserProfile{}, err
+	}
+	s.audit(model.AuditEventTelegramLink, userID, meta, nil, "")
+
+	return s.GetProfile(userID)
+}
+
+func (s *AuthService) UnlinkTelegram(userID int64, meta model.SessionMeta) (model.UserProfile, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.UserProfile{}, ErrUserNotFound
+		}
+		return model.UserProfile{}, err
+	}
+	if user.TelegramID == nil {
+		return model.UserProfile{}, ErrTelegramNotLinked
+	}
+	if user.Email == "" || user.Password == "" {
+		return model.UserProfile{}, ErrLastSignInMethod
+	}
+
+	if err := s.repo.UpdateUserTelegramID(userID, nil); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.UserProfile{}, ErrUserNotFound
+		}
+		return model.UserProfile{}, err
+	}
+	s.audit(model.AuditEventTelegramUnlink, userID, meta, nil, "")
+
+	return s.GetProfile(userID)
+}
+
+func (s *AuthService) verifyTelegramAuth(data map[string]string) (model.TelegramAuthData, error) {
+	if s.telegramBotToken == "" {
+		return model.TelegramAuthData{}, errTelegramNotConfigured
+	}
+	return verifyTelegramAuthData(data, s.telegramBotToken, s.telegramAuthMaxAge, time.Now())
+}
+
+// verifyTelegramAuthData checks the widget hash as described in
+// https://core.telegram.org/widgets/login#checking-authorization: the
+// HMAC-SHA256 of the sorted "key=value" lines, keyed with SHA256(bot token).
+func verifyTelegramAuthData(data map[string]string, botToken string, maxAge time.Duration, now time.Time) (model.TelegramAuthData, error) {
+	receivedHash, ok := data["hash"]
+	if !ok || receivedHash == "" {
+		return model.TelegramAuthData{}, ErrInvalidTelegramAuth
+	}
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		if key != "hash" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lines = append(lines, key+"="+data[key])
+	}
+
+	secret := sha256.Sum256([]byte(botToken))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	expectedHash := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expectedHash), []byte(strings.ToLower(receivedHash))) {
+		return model.TelegramAuthData{}, ErrInvalidTelegramAuth
+	}
+
+	id, err := strconv.ParseInt(data["id"], 10, 64)
+	if err != nil || id <= 0 {
+		return model.TelegramAuthData{}, ErrInvalidTelegramAuth
+	}
+	authDateUnix, err := strconv.ParseInt(data["auth_date"], 10, 64)
+	if err != nil {
+		return model.TelegramAuthData{}, ErrInvalidTelegramAuth
+	}
+	authDate := time.Unix(authDateUnix, 0)
+	if now.Sub(authDate) > maxAge || authDate.After(now.Add(time.Minute)) {
+		return model.TelegramAuthData{}, ErrTelegramAuthExpired
+	}
+
+	return model.TelegramAuthData{
+		ID:        id,
+		FirstName: data["first_name"],
+		LastName:  data["last_name"],
+		Username:  data["username"],
+		PhotoURL:  data["photo_url"],
+		AuthDate:  authDate,
+	}, nil
+}
+
+// availableUsername picks a free username for an account created through an
+// external sign-in, preferring base and falling back to fallback when base is
+// empty.
+func (s *AuthService) availableUsername(base string, fallback string) (string, error) {
+	if base == "" {
+		base = fallback
+	}
+	if len(base) > maxUsernameLength-9 {
+		base = base[:maxUsernameLength-9]
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		exists, err := s.repo.UsernameExists(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+
+		suffix, err := randomHex(4)
+		if err != nil {
+			return "", err
+		}
+		candidate = base + "_" + suffix
+	}
+
+	return "", ErrUsernameAlreadyExists
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/jackc/pgx/v5"
+)
+
+const testTelegramBotToken = "123456:TEST-bot-token"
+
+func signTelegramPayload(data map[string]string, botToken string) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lines = append(lines, key+"="+data[key])
+	}
+
+	secret := sha256.Sum256([]byte(botToken))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	data["hash"] = hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyTelegramAuthDataAcceptsSignedPayload(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	data := map[string]string{
+		"id":         "42",
+		"first_name": "Alice",
+		"username":   "alice",
+		"photo_url":  "https://t.me/i/userpic/alice.jpg",
+		"auth_date":  strconv.FormatInt(now.Add(-time.Minute).Unix(), 10),
+	}
+	signTelegramPayload(data, testTelegramBotToken)
+
+	auth, err := verifyTelegramAuthData(data, testTelegramBotToken, 24*time.Hour, now)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if auth.ID != 42 || auth.Username != "alice" || auth.PhotoURL != data["photo_url"] {
+		t.Fatalf("unexpected auth data: %+v", auth)
+	}
+}
+
+func TestVerifyTelegramAuthDataRejectsTamperedPayload(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	data := map[string]string{
+		"id":        "42",
+		"username":  "alice",
+		"auth_date": strconv.FormatInt(now.Unix(), 10),
+	}
+	signTelegramPayload(data, testTelegramBotToken)
+	data["id"] = "43"
+
+	if _, err := verifyTelegramAuthData(data, testTelegramBotToken, 24*time.Hour, now); !errors.Is(err, ErrInvalidTelegramAuth) {
+		t.Fatalf("expected ErrInvalidTelegramAuth, got %v", err)
+	}
+
+	data["id"] = "42"
+	if _, err := verifyTelegramAuthData(data, "654321:other-token", 24*time.Hour, now); !errors.Is(err, ErrInvalidTelegramAuth) {
+		t.Fatalf("expected ErrInvalidTelegramAuth for another bot token, got %v", err)
+	}
+}
+
+func TestVerifyTelegramAuthDataRejectsStalePayload(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	data := map[string]string{
+		"id":        "42",
+		"auth_date": strconv.FormatInt(now.Add(-25*time.Hour).Unix(), 10),
+	}
+	signTelegramPayload(data, testTelegramBotToken)
+
+	if _, err := verifyTelegramAuthData(data, testTelegramBotToken, 24*time.Hour, now); !errors.Is(err, ErrTelegramAuthExpired) {
+		t.Fatalf("expected ErrTelegramAuthExpired, got %v", err)
+	}
+}
+
+type telegramAuthRepoStub struct {
+	*authRepoStub
+	twoFactorChallenges map[string]model.TwoFactorChallenge
+}
+
+func (s *telegramAuthRepoStub) GetUserByTelegramID(telegramID int64) (model.User, error) {
+	for _, user := range s.users {
+		if user.TelegramID != nil && *user.TelegramID == telegramID {
+			return user, nil
+		}
+	}
+	return model.User{}, pgx.ErrNoRows
+}
+
+func (s *telegramAuthRepoStub) SaveTwoFactorChallenge(tokenHash string, challenge model.TwoFactorChallenge, ttl time.Duration) error {
+	s.twoFactorChallenges[tokenHash] = challenge
+	return nil
+}
+
+func TestSignInWithTelegramRequiresSecondFactor(t *testing.T) {
+	telegramID := int64(42)
+	enabledAt := time.Now().Add(-time.Hour)
+	repo := &telegramAuthRepoStub{authRepoStub: newAuthRepoStub(), twoFactorChallenges: map[string]model.TwoFactorChallenge{}}
+	repo.users[1] = model.User{ID: 1, Username: "alice", TelegramID: &telegramID, TOTPSecret: "secret", TOTPEnabledAt: &enabledAt}
+
+	svc := NewAuthService(repo, nil, nil, nil, NewMemoryMailer())
+	svc.telegramBotToken = testTelegramBotToken
+
+	data := map[string]string{
+		"id":        "42",
+		"auth_date": strconv.FormatInt(time.Now().Unix(), 10),
+	}
+	signTelegramPayload(data, testTelegramBotToken)
+
+	result, err := svc.SignInWithTelegram(data, model.SessionMeta{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if result.AuthTokens != nil {
+		t.Fatalf("expected no tokens before the second factor, got %+v", result.AuthTokens)
+	}
+	if !result.TwoFactorRequired || result.ChallengeToken == "" {
+		t.Fatalf("expected a two-factor challenge, got %+v", result)
+	}
+	if challenge, ok := repo.twoFactorChallenges[hashToken(result.ChallengeToken)]; !ok || challenge.UserID != 1 {
+		t.Fatalf("expected a stored challenge for the user, got %+v", repo.twoFactorChallenges)
+	}
+}
@@ -0,0 +1,376 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled    = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled        = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorEnrollmentMissing = errors.New("two-factor enrollment was not started")
+	ErrInvalidTwoFactorCode       = errors.New("invalid two-factor code")
+	ErrTwoFactorChallengeNotFound = errors.New("two-factor challenge not found")
+	ErrTwoFactorRequired          = errors.New("two-factor authentication required")
+)
+
+const (
+	totpIssuer         = "Sovpalo"
+	totpPeriod         = 30 * time.Second
+	totpDigits         = 6
+	totpSecretSize     = 20
+	totpSkew           = 1
+	recoveryCodeCount  = 10
+	twoFactorTokenSize = 32
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// StartTOTPEnrollment generates a new secret for the user. It only becomes
+// active once ConfirmTOTPEnrollment receives a valid code for it.
+func (s *AuthService) StartTOTPEnrollment(userID int64, input model.TOTPEnrollInput, meta model.SessionMeta) (model.TOTPEnrollment, error) {
+	user, err := s.confirmIdentity(userID, input.Password, meta.AuthTime)
+	if err != nil {
+		return model.TOTPEnrollment{}, err
+	}
+	if user.TOTPEnabledAt != nil {
+		return model.TOTPEnrollment{}, ErrTwoFactorAlreadyEnabled
+	}
+
+	secretBytes := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return model.TOTPEnrollment{}, err
+	}
+	secret := totpEncoding.EncodeToString(secretBytes)
+
+	if err := s.repo.SetPendingTOTPSecret(userID, secret); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.TOTPEnrollment{}, ErrTwoFactorAlreadyEnabled
+		}
+		return model.TOTPEnrollment{}, err
+	}
+
+	return model.TOTPEnrollment{
+		Secret:     secret,
+		OTPAuthURI: totpURI(secret, defaultString(user.Email, user.Username)),
+	}, nil
+}
+
+// ConfirmTOTPEnrollment activates the pending secret and returns the
+// recovery codes. They are shown only once and stored hashed.
+func (s *AuthService) ConfirmTOTPEnrollment(userID int64, input model.TOTPConfirmInput, meta model.SessionMeta) (model.RecoveryCodes, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.RecoveryCodes{}, ErrUserNotFound
+		}
+		return model.RecoveryCodes{}, err
+	}
+	if user.TOTPEnabledAt != nil {
+		return model.RecoveryCodes{}, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return model.RecoveryCodes{}, ErrTwoFactorEnrollmentMissing
+	}
+
+	if _, ok := validateTOTP(user.TOTPSecret, input.Code, time.Now()); !ok {
+		s.audit(model.AuditEventTOTPEnable, userID, meta, ErrInvalidTwoFactorCode, "")
+		return model.RecoveryCodes{}, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return model.RecoveryCodes{}, err
+	}
+
+	if err := s.repo.EnableTOTP(userID, hashes); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.RecoveryCodes{}, ErrTwoFactorEnrollmentMissing
+		}
+		return model.RecoveryCodes{}, err
+	}
+	s.audit(model.AuditEventTOTPEnable, userID, meta, nil, "")
+
+	return model.RecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+// DisableTOTP turns two-factor authentication off. It requires both the
+// current password and a valid TOTP or recovery code.
+func (s *AuthService) DisableTOTP(userID int64, input model.TOTPDisableInput, meta model.SessionMeta) error {
+	user, err := s.confirmIdentity(userID, input.Password, meta.AuthTime)
+	if err != nil {
+		if errors.Is(err, ErrIncorrectCurrentPassword) {
+			s.audit(model.AuditEventTOTPDisable, userID, meta, err, "")
+		}
+		return err
+	}
+	if user.TOTPEnabledAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	ok, err := s.checkSecondFactor(user, input.Code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.audit(model.AuditEventTOTPDisable, userID, meta, ErrInvalidTwoFactorCode, "")
+		return ErrInvalidTwoFactorCode
+	}
+
+	if err := s.repo.DisableTOTP(userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	s.audit(model.AuditEventTOTPDisable, userID, meta, nil, "")
+	return nil
+}
+
+// VerifyTwoFactorSignIn finishes a sign-in started by SignIn for an account
+// with two-factor authentication. The attempt is counted before the code is
+// checked, so parallel guesses cannot get past the attempt limit.
+func (s *AuthService) VerifyTwoFactorSignIn(input model.TwoFactorSignInInput, meta model.SessionMeta) (model.AuthTokens, error) {
+	tokenHash := hashToken(input.ChallengeToken)
+	attempts, err := s.repo.IncrementTwoFactorChallengeAttempts(tokenHash)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return model.AuthTokens{}, ErrTwoFactorChallengeNotFound
+		}
+		return model.AuthTokens{}, err
+	}
+	if attempts > s.maxCodeAttempts {
+		_ = s.repo.DeleteTwoFactorChallenge(tokenHash)
+		return model.AuthTokens{}, ErrTooManyAttempts
+	}
+
+	challenge, err := s.repo.GetTwoFactorChallenge(tokenHash)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return model.AuthTokens{}, ErrTwoFactorChallengeNotFound
+		}
+		return model.AuthTokens{}, err
+	}
+
+	if time.Now().After(challenge.ExpiresAt) {
+		_ = s.repo.DeleteTwoFactorChallenge(tokenHash)
+		return model.AuthTokens{}, ErrTwoFactorChallengeNotFound
+	}
+
+	user, err := s.repo.GetUserByID(challenge.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.AuthTokens{}, ErrTwoFactorChallengeNotFound
+		}
+		return model.AuthTokens{}, err
+	}
+
+	ok, err := s.checkSecondFactor(user, input.Code)
+	if err != nil {
+		return model.AuthTokens{}, err
+	}
+	if !ok {
+		if attempts >= s.maxCodeAttempts {
+			_ = s.repo.DeleteTwoFactorChallenge(tokenHash)
+			s.audit(model.AuditEventSignIn, user.ID, meta, ErrTooManyAttempts, signInMethodTwoFactor)
+			return model.AuthTokens{}, ErrTooManyAttempts
+		}
+		s.audit(model.AuditEventSignIn, user.ID, meta, ErrInvalidTwoFactorCode, signInMethodTwoFactor)
+		return model.AuthTokens{}, ErrInvalidTwoFactorCode
+	}
+
+	if err := s.repo.DeleteTwoFactorChallenge(tokenHash); err != nil {
+		return model.AuthTokens{}, err
+	}
+
+	tokens, err := s.issueTokens(user.ID, meta)
+	if err != nil {
+		return model.AuthTokens{}, err
+	}
+	s.recordSignIn(user, signInMethodTwoFactor, meta)
+	return tokens, nil
+}
+
+// completeSignIn is called once the first factor, verified with method, has
+// been checked. It issues tokens right away or, if the user has TOTP enabled,
+// a two-factor challenge.
+func (s *AuthService) completeSignIn(user model.User, method string, meta model.SessionMeta) (model.SignInResult, error) {
+	if user.TOTPEnabledAt == nil {
+		tokens, err := s.issueTokens(user.ID, meta)
+		if err != nil {
+			return model.SignInResult{}, err
+		}
+		s.recordSignIn(user, method, meta)
+		return model.SignInResult{AuthTokens: &tokens}, nil
+	}
+
+	challengeToken, err := randomHex(twoFactorTokenSize)
+	if err != nil {
+		return model.SignInResult{}, err
+	}
+
+	if err := s.repo.SaveTwoFactorChallenge(hashToken(challengeToken), model.TwoFactorChallenge{
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(s.twoFactorTTL),
+	}, s.twoFactorTTL); err != nil {
+		return model.SignInResult{}, err
+	}
+	s.audit(model.AuditEventSignIn, user.ID, meta, ErrTwoFactorRequired, method)
+
+	return model.SignInResult{
+		TwoFactorRequired:     true,
+		ChallengeToken:        challengeToken,
+		ChallengeExpiresInSec: int(s.twoFactorTTL.Seconds()),
+	}, nil
+}
+
+// checkSecondFactor accepts either a current TOTP code or an unused recovery
+// code. A TOTP code is accepted only once within its validity window.
+func (s *AuthService) checkSecondFactor(user model.User, code string) (bool, error) {
+	if user.TOTPEnabledAt == nil || user.TOTPSecret == "" {
+		return false, nil
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totpDigits {
+		step, ok := validateTOTP(user.TOTPSecret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		return s.repo.AcquireAuthCooldown(fmt.Sprintf("totp:%d:%d", user.ID, step), time.Duration(2*totpSkew+1)*totpPeriod)
+	}
+
+	return s.repo.UseRecoveryCode(user.ID, hashToken(normalizeRecoveryCode(code)))
+}
+
+// confirmIdentity confirms a two-factor change with the user's password.
+// Accounts without a password, created with Telegram or OpenID Connect,
+// confirm it with a sign-in made within reauthMaxAge instead; authTime is
+// when the caller's access token was issued by that sign-in.
+func (s *AuthService) confirmIdentity(userID int64, password string, authTime time.Time) (model.User, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return model.User{}, err
+	}
+	if user.Password != "" {
+		if err := s.checkCurrentPassword(user, password); err != nil {
+			return model.User{}, err
+		}
+		return user, nil
+	}
+
+	if authTime.IsZero() || time.Since(authTime) > s.reauthMaxAge {
+		return model.User{}, ErrRecentSignInRequired
+	}
+	return user, nil
+}
+
+func (s *AuthService) reauthenticate(userID int64, password string) (model.User, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return model.User{}, err
+	}
+	if err := s.checkCurrentPassword(user, password); err != nil {
+		return model.User{}, err
+	}
+	return user, nil
+}
+
+func (s *AuthService) getUser(userID int64) (model.User, error) {
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.User{}, ErrUserNotFound
+		}
+		return model.User{}, err
+	}
+	return user, nil
+}
+
+func (s *AuthService) checkCurrentPassword(user model.User, password string) error {
+	ok, _, err := s.verifyPassword(password, user.Password)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrIncorrectCurrentPassword
+	}
+	return nil
+}
+
+// totpCode computes the RFC 6238 code (HMAC-SHA1, 6 digits) for a time step.
+func totpCode(secret []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// validateTOTP checks code against the steps around now and returns the
+// matching step.
+func validateTOTP(secret string, code string, now time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / int64(totpPeriod.Seconds())
+	for offset := int64(-totpSkew); offset <= totpSkew; offset++ {
+		step := current + offset
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func totpURI(secret, account string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", totpIssuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+
+	label := url.PathEscape(totpIssuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// generateRecoveryCodes returns the codes to show the user and their hashes to store.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := randomHex(5)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
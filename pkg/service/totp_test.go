@@ -0,0 +1,165 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Sovpalo/sovpalo-backend/pkg/model"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestTOTPCodeMatchesRFC6238Vectors(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, expected := range cases {
+		if got := totpCode(secret, unix/30); got != expected {
+			t.Fatalf("time %d: expected %s, got %s", unix, expected, got)
+		}
+	}
+}
+
+func TestValidateTOTPAllowsOneStepOfSkew(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111109, 0)
+
+	if _, ok := validateTOTP(secret, "081804", now); !ok {
+		t.Fatalf("expected current code to be accepted")
+	}
+	if _, ok := validateTOTP(secret, "081804", now.Add(30*time.Second)); !ok {
+		t.Fatalf("expected previous step code to be accepted")
+	}
+	if _, ok := validateTOTP(secret, "081804", now.Add(2*time.Minute)); ok {
+		t.Fatalf("expected stale code to be rejected")
+	}
+}
+
+func TestRecoveryCodesAreStoredHashed(t *testing.T) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("expected %d codes, got %d codes and %d hashes", recoveryCodeCount, len(codes), len(hashes))
+	}
+	if hashToken(normalizeRecoveryCode(" "+codes[0]+" ")) != hashes[0] {
+		t.Fatalf("expected normalized code to match its stored hash")
+	}
+	if codes[0] == hashes[0] {
+		t.Fatalf("expected recovery code not to be stored in plain text")
+	}
+}
+
+type twoFactorRepoStub struct {
+	*authRepoStub
+	challenges         map[string]model.TwoFactorChallenge
+	attempts           map[string]int
+	recoveryCodeChecks int
+}
+
+func (s *twoFactorRepoStub) GetTwoFactorChallenge(tokenHash string) (model.TwoFactorChallenge, error) {
+	challenge, ok := s.challenges[tokenHash]
+	if !ok {
+		return model.TwoFactorChallenge{}, redis.Nil
+	}
+	return challenge, nil
+}
+
+func (s *twoFactorRepoStub) DeleteTwoFactorChallenge(tokenHash string) error {
+	delete(s.challenges, tokenHash)
+	delete(s.attempts, tokenHash)
+	return nil
+}
+
+func (s *twoFactorRepoStub) IncrementTwoFactorChallengeAttempts(tokenHash string) (int, error) {
+	if _, ok := s.challenges[tokenHash]; !ok {
+		return 0, redis.Nil
+	}
+	s.attempts[tokenHash]++
+	return s.attempts[tokenHash], nil
+}
+
+func (s *twoFactorRepoStub) UseRecoveryCode(userID int64, codeHash string) (bool, error) {
+	s.recoveryCodeChecks++
+	return true, nil
+}
+
+func TestVerifyTwoFactorSignInCountsAttemptBeforeCheckingCode(t *testing.T) {
+	enabledAt := time.Now().Add(-time.Hour)
+	repo := &twoFactorRepoStub{
+		authRepoStub: newAuthRepoStub(),
+		challenges:   map[string]model.TwoFactorChallenge{},
+		attempts:     map[string]int{},
+	}
+	repo.users[1] = model.User{ID: 1, Username: "alice", TOTPSecret: "secret", TOTPEnabledAt: &enabledAt}
+
+	svc := NewAuthService(repo, nil, nil, nil, NewMemoryMailer())
+	tokenHash := hashToken("challenge")
+	// The stored record has not seen the guesses made in parallel, only the
+	// counter has.
+	repo.challenges[tokenHash] = model.TwoFactorChallenge{UserID: 1, ExpiresAt: time.Now().Add(time.Minute)}
+	repo.attempts[tokenHash] = svc.maxCodeAttempts
+
+	_, err := svc.VerifyTwoFactorSignIn(model.TwoFactorSignInInput{ChallengeToken: "challenge", Code: "recovery-code"}, model.SessionMeta{})
+	if !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("expected ErrTooManyAttempts, got %v", err)
+	}
+	if repo.recoveryCodeChecks != 0 {
+		t.Fatalf("expected the code not to be checked once the limit is reached, got %d checks", repo.recoveryCodeChecks)
+	}
+	if _, ok := repo.challenges[tokenHash]; ok {
+		t.Fatalf("expected the challenge to be deleted")
+	}
+}
+
+type enrollRepoStub struct {
+	*authRepoStub
+	pendingSecret string
+}
+
+func (s *enrollRepoStub) SetPendingTOTPSecret(userID int64, secret string) error {
+	s.pendingSecret = secret
+	return nil
+}
+
+func TestStartTOTPEnrollmentWithoutPasswordNeedsRecentSignIn(t *testing.T) {
+	repo := &enrollRepoStub{authRepoStub: newAuthRepoStub()}
+	repo.users[1] = model.User{ID: 1, Username: "alice"}
+	svc := NewAuthService(repo, nil, nil, nil, NewMemoryMailer())
+	keys, err := newKeyManager(nil, nil, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	svc.keys = keys
+
+	for name, authTime := range map[string]time.Time{
+		"refreshed token": {},
+		"old sign-in":     time.Now().Add(-svc.reauthMaxAge - time.Minute),
+	} {
+		if _, err := svc.StartTOTPEnrollment(1, model.TOTPEnrollInput{}, model.SessionMeta{AuthTime: authTime}); !errors.Is(err, ErrRecentSignInRequired) {
+			t.Fatalf("%s: expected ErrRecentSignInRequired, got %v", name, err)
+		}
+	}
+
+	token, err := svc.generateTokenForUser(1, time.Now())
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	identity, err := svc.ParseToken(token)
+	if err != nil || identity.AuthTime.IsZero() {
+		t.Fatalf("expected the sign-in time in the token, got %+v err=%v", identity, err)
+	}
+
+	if _, err := svc.StartTOTPEnrollment(1, model.TOTPEnrollInput{}, model.SessionMeta{AuthTime: identity.AuthTime}); err != nil {
+		t.Fatalf("expected enrollment after a recent sign-in, got %v", err)
+	}
+	if repo.pendingSecret == "" {
+		t.Fatalf("expected a pending TOTP secret")
+	}
+}